@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/configstore/vibeyaml"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/bootstrap"
+	"github.com/phamdaiminhquan/vibe-devops/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect or clear the AI response cache",
+	Long: `Responses to analysis prompts ('vibe diagnose --ai', 'vibe logs --analyze') are cached
+under ~/.vibe/cache. Use --no-cache on any command to bypass it.`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show cache size and entry count",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := bootstrap.NewCacheStore(loadConfigOrDefault())
+		st, err := store.Stats()
+		if err != nil {
+			return fmt.Errorf("failed to read cache: %w", err)
+		}
+
+		fmt.Printf("Cache directory: %s\n", st.Dir)
+		fmt.Printf("Entries:         %d (%d expired)\n", st.Entries, st.Expired)
+		fmt.Printf("Size:            %.2f MB\n", float64(st.Bytes)/(1<<20))
+		if st.Entries > 0 {
+			fmt.Printf("Oldest:          %s\n", st.Oldest.Format("2006-01-02 15:04"))
+			fmt.Printf("Newest:          %s\n", st.Newest.Format("2006-01-02 15:04"))
+		}
+		return nil
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached responses",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		removed, err := bootstrap.NewCacheStore(loadConfigOrDefault()).Clear()
+		if err != nil {
+			return fmt.Errorf("failed to clear cache: %w", err)
+		}
+		fmt.Printf("✅ Removed %d cached responses.\n", removed)
+		return nil
+	},
+}
+
+// loadConfigOrDefault reads .vibe.yaml if present; cache commands also work outside a project.
+func loadConfigOrDefault() *config.Config {
+	store := vibeyaml.New()
+	if cfg, err := store.Load("."); err == nil {
+		return cfg
+	}
+	return store.Default()
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/terminal"
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/ui"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/bootstrap"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/command"
+	appSession "github.com/phamdaiminhquan/vibe-devops/internal/app/session"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+	"github.com/spf13/cobra"
+)
+
+var chatAgentMaxSteps int
+var chatAgentMaxRepairs int
+var chatAgentToolWorkers int
+var chatAgentToolTimeout time.Duration
+var chatSelfHeal bool
+var chatSelfHealMaxAttempts int
+
+var chatSessionName string
+var chatSessionScope string
+var chatResumeSession bool
+var chatNoSession bool
+var chatContextBudget int
+var chatContextRecentLines int
+
+var chatCmd = &cobra.Command{
+	Use:   "chat",
+	Short: "Start an interactive chat with the agent",
+	Long: `Opens an interactive session that keeps one agent conversation across turns.
+Proposed commands go through the same safety checks and confirmations as 'vibe run'.
+
+Type /help inside the chat for slash commands such as /context, /tools, /model,
+/session, /undo, /diagnose and /exit.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         chatCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(chatCmd)
+	chatCmd.Flags().IntVar(&chatAgentMaxSteps, "agent-max-steps", 10, "Max tool steps per turn")
+	chatCmd.Flags().IntVar(&chatAgentToolWorkers, "agent-tool-workers", 4, "Max read-only tools the agent runs in parallel")
+	chatCmd.Flags().DurationVar(&chatAgentToolTimeout, "agent-tool-timeout", time.Minute, "Timeout for a single tool run")
+	chatCmd.Flags().IntVar(&chatAgentMaxRepairs, "agent-max-repairs", 2, "Max correction turns when the model returns a malformed action (0 disables)")
+	chatCmd.Flags().BoolVar(&chatSelfHeal, "self-heal", true, "After running a command, let the agent read its output and continue")
+	chatCmd.Flags().IntVar(&chatSelfHealMaxAttempts, "self-heal-max-attempts", 3, "Max commands the agent runs in a row for one message")
+
+	chatCmd.Flags().StringVar(&chatSessionName, "session", "default", "Session name for agent memory persistence")
+	chatCmd.Flags().StringVar(&chatSessionScope, "session-scope", "both", "Session scope: none|project|global|both")
+	chatCmd.Flags().BoolVar(&chatResumeSession, "resume", true, "Resume session memory. When false, starts fresh but still writes updates.")
+	chatCmd.Flags().BoolVar(&chatNoSession, "no-session", false, "Disable session persistence")
+	chatCmd.Flags().IntVar(&chatContextBudget, "context-budget", 8000, "Approx char budget for session context tail")
+	chatCmd.Flags().IntVar(&chatContextRecentLines, "context-recent-lines", 40, "Max recent transcript lines to keep in session memory")
+}
+
+func chatCommand(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	appCtx, err := bootstrap.Initialize(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = appCtx.Close() }()
+	appCtx.Usage.SetSession(chatSessionName)
+
+	sessionCfg := bootstrap.SessionConfig{
+		Name:      chatSessionName,
+		Scope:     chatSessionScope,
+		Resume:    chatResumeSession,
+		NoSession: chatNoSession,
+		Budget: appSession.Budget{
+			MaxRecentLines: chatContextRecentLines,
+			MaxRecentChars: chatContextBudget,
+		},
+	}
+	sessionSvc := bootstrap.InitializeSessionService(appCtx.ProviderFor(bootstrap.RoleSummarizer), sessionCfg)
+
+	flags := command.RunFlags{
+		AgentMode:           true,
+		AgentMaxSteps:       chatAgentMaxSteps,
+		AgentMaxRepairs:     chatAgentMaxRepairs,
+		AgentToolWorkers:    chatAgentToolWorkers,
+		AgentToolTimeout:    chatAgentToolTimeout,
+		SelfHeal:            chatSelfHeal,
+		SelfHealMaxAttempts: chatSelfHealMaxAttempts,
+	}
+	// Chat messages and confirmations share one reader of stdin.
+	home, _ := os.UserHomeDir()
+	in := terminal.NewLineReader(filepath.Join(home, ".vibe", "chat_history"))
+	console := ui.NewLineTerminal(in, os.Stdout)
+	trust := trustProject(console)
+	if err := appCtx.TrustToolPolicies(trust); err != nil {
+		console.Message(ports.MessageWarning, fmt.Sprintf("Tool policies: %v", err))
+	}
+	if err := appCtx.ConnectTools(ctx, rootCmd.Version, trust); err != nil {
+		console.Message(ports.MessageWarning, fmt.Sprintf("Skipping MCP tools: %v", err))
+	}
+	return command.NewChatHandler(appCtx, sessionSvc, console, in, flags, chatSessionName).Handle(ctx)
+}
@@ -1,15 +1,12 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
-	"os"
-	"strconv"
 	"strings"
 
 	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/configstore/vibeyaml"
 	appConfig "github.com/phamdaiminhquan/vibe-devops/internal/app/config"
-	"github.com/phamdaiminhquan/vibe-devops/pkg/ai"
+	"github.com/phamdaiminhquan/vibe-devops/pkg/config"
 	"github.com/spf13/cobra"
 )
 
@@ -23,7 +20,7 @@ var configCmd = &cobra.Command{
 var setProviderCmd = &cobra.Command{
 	Use:   "provider [name]",
 	Short: "Set the active AI provider",
-	Long:  `Sets the active AI provider (e.g., "gemini").`,
+	Long:  `Sets the active AI provider ("gemini", "openai" or "ollama").`,
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		providerName := strings.ToLower(args[0])
@@ -34,9 +31,8 @@ var setProviderCmd = &cobra.Command{
 			return fmt.Errorf("failed to load config: %w. Please run 'vibe init' first", err)
 		}
 
-		// For now, we only support gemini, but this is extensible
-		if providerName != "gemini" {
-			return fmt.Errorf("unsupported provider: '%s'. Only 'gemini' is currently supported", providerName)
+		if !config.IsSupportedProvider(providerName) {
+			return fmt.Errorf("unsupported provider: '%s'. Supported providers: %s", providerName, strings.Join(config.SupportedProviders, ", "))
 		}
 
 		if _, err := svc.SetProvider(".", providerName); err != nil {
@@ -63,45 +59,31 @@ var setApiKeyCmd = &cobra.Command{
 		}
 
 		switch cfg.AI.Provider {
-		case "gemini":
-			if _, err := svc.SetGeminiAPIKey(".", apiKey); err != nil {
+		case config.ProviderGemini, config.ProviderOpenAI:
+			updated, err := svc.SetAPIKey(".", apiKey)
+			if err != nil {
 				return fmt.Errorf("failed to write updated config: %w", err)
 			}
 
 			fmt.Println("Validating API key and fetching available models...")
-			models, err := ai.GetGeminiModels(apiKey)
+			models, err := fetchModels(cmd.Context(), updated, cfg.AI.Provider)
 			if err != nil {
 				return fmt.Errorf("failed to validate API key: %w", err)
 			}
 
-			if len(models) == 0 {
-				return fmt.Errorf("no models found for this API key")
-			}
-
 			fmt.Println("✅ API Key is valid.")
-			fmt.Println("Select a model to use:")
-			for i, m := range models {
-				displayName := strings.TrimPrefix(m, "models/")
-				fmt.Printf("[%d] %s\n", i+1, displayName)
-			}
-
-			reader := bufio.NewReader(os.Stdin)
-			fmt.Print("Enter the number of the model: ")
-			input, _ := reader.ReadString('\n')
-			input = strings.TrimSpace(input)
-
-			index, err := strconv.Atoi(input)
-			if err != nil || index < 1 || index > len(models) {
-				return fmt.Errorf("invalid selection")
+			model, err := selectModel(models)
+			if err != nil {
+				return err
 			}
-
-			selectedModel := models[index-1]
-			model := strings.TrimPrefix(selectedModel, "models/")
-			if _, err := svc.SetGeminiModel(".", model); err != nil {
+			if _, err := svc.SetModel(".", model); err != nil {
 				return fmt.Errorf("failed to write updated config: %w", err)
 			}
 			fmt.Printf("Selected model: %s\n", model)
 
+		case config.ProviderOllama:
+			return fmt.Errorf("ollama does not use an API key. Run 'vibe config host <url>' to point vibe at your Ollama server")
+
 		default:
 			return fmt.Errorf("no active provider set or provider '%s' is not supported for API key configuration", cfg.AI.Provider)
 		}
@@ -111,8 +93,30 @@ var setApiKeyCmd = &cobra.Command{
 	},
 }
 
+var setHostCmd = &cobra.Command{
+	Use:   "host [url]",
+	Short: "Set the Ollama server URL",
+	Long:  `Sets the URL of the Ollama server (default "http://localhost:11434").`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		svc := appConfig.NewService(vibeyaml.New())
+		if _, err := svc.Load("."); err != nil {
+			return fmt.Errorf("failed to load config: %w. Please run 'vibe init' first", err)
+		}
+
+		cfg, err := svc.SetOllamaHost(".", args[0])
+		if err != nil {
+			return fmt.Errorf("failed to write updated config: %w", err)
+		}
+
+		fmt.Printf("✅ Ollama host set to '%s'.\n", cfg.AI.Ollama.Host)
+		return nil
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(configCmd)
 	configCmd.AddCommand(setProviderCmd)
 	configCmd.AddCommand(setApiKeyCmd)
+	configCmd.AddCommand(setHostCmd)
 }
@@ -3,7 +3,6 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/phamdaiminhquan/vibe-devops/internal/app/bootstrap"
 	"github.com/phamdaiminhquan/vibe-devops/internal/app/diagnose"
@@ -49,42 +48,11 @@ func analyzeWithAI(ctx context.Context, result *diagnose.DiagnoseResult) {
 		fmt.Printf("⚠️ Cannot connect to AI: %v\n", err)
 		return
 	}
-	defer appCtx.Provider.Close()
-
-	// Build prompt with diagnostics data
-	var sb strings.Builder
-	sb.WriteString("Analyze the following system diagnostics and provide a summary:\n\n")
-
-	if len(result.Errors) > 0 {
-		sb.WriteString("CRITICAL ERRORS:\n")
-		for _, issue := range result.Errors {
-			sb.WriteString(fmt.Sprintf("- %s: %s\n", issue.Category, issue.Description))
-		}
-	}
-
-	if len(result.Warnings) > 0 {
-		sb.WriteString("\nWARNINGS:\n")
-		for _, issue := range result.Warnings {
-			sb.WriteString(fmt.Sprintf("- %s: %s\n", issue.Category, issue.Description))
-		}
-	}
-
-	if len(result.OK) > 0 {
-		sb.WriteString("\nHEALTHY CHECKS:\n")
-		for _, check := range result.OK {
-			sb.WriteString(fmt.Sprintf("- %s: %s\n", check.Description, check.Value))
-		}
-	}
-
-	if len(result.Errors) == 0 && len(result.Warnings) == 0 {
-		sb.WriteString("\nThe system appears healthy. Please confirm this assessment and provide any optimization recommendations.")
-	} else {
-		sb.WriteString("\nPlease analyze root causes and suggest specific remediation steps.")
-	}
+	defer appCtx.Close()
 
 	// Generate AI response
-	resp, err := appCtx.Provider.Generate(ctx, ports.GenerateRequest{
-		Prompt: sb.String(),
+	resp, err := appCtx.CachedProvider(bootstrap.RoleAnalyzer, !noCache).Generate(ctx, ports.GenerateRequest{
+		Prompt: diagnose.AnalysisPrompt(result),
 	})
 	if err != nil {
 		fmt.Printf("⚠️ AI Error: %v\n", err)
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/configstore/vibeyaml"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/bootstrap"
+	appConfig "github.com/phamdaiminhquan/vibe-devops/internal/app/config"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/doctor"
+	"github.com/spf13/cobra"
+)
+
+var doctorJSON bool
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check vibe's own setup",
+	Long: `Check that vibe itself is set up correctly: .vibe.yaml, every configured
+provider (with one tiny real generation each), session and cache directories,
+required tools, terminal fonts and git checkpoints.
+
+Exits with a non-zero status when a check fails.
+
+Examples:
+  vibe doctor
+  vibe doctor --json`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, cfgErr := appConfig.NewService(vibeyaml.New()).Load(".")
+		home, _ := os.UserHomeDir()
+
+		if !doctorJSON {
+			fmt.Println("🩺 Checking vibe setup...")
+		}
+		report := doctor.NewService(doctor.Options{
+			Config:      cfg,
+			ConfigErr:   cfgErr,
+			NewProvider: bootstrap.NewProvider,
+			Roles:       bootstrap.Roles,
+			WorkDir:     ".",
+			HomeDir:     home,
+		}).Run(cmd.Context())
+
+		if doctorJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(report); err != nil {
+				return err
+			}
+		} else {
+			printDoctorReport(report)
+		}
+
+		if report.Failed() {
+			return fmt.Errorf("%d of %d checks failed", report.Count(doctor.StatusFail), len(report.Checks))
+		}
+		return nil
+	},
+}
+
+func printDoctorReport(report doctor.Report) {
+	icons := map[doctor.Status]string{
+		doctor.StatusPass: "✅ pass",
+		doctor.StatusWarn: "⚠️  warn",
+		doctor.StatusFail: "❌ fail",
+		doctor.StatusSkip: "➖ skip",
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tCATEGORY\tCHECK\tDETAIL")
+	for _, c := range report.Checks {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", icons[c.Status], c.Category, c.Name, c.Detail)
+	}
+	w.Flush()
+
+	var hints []string
+	for _, c := range report.Checks {
+		if c.Hint != "" && (c.Status == doctor.StatusFail || c.Status == doctor.StatusWarn) {
+			hints = append(hints, fmt.Sprintf("  • %s: %s", c.Name, strings.ReplaceAll(c.Hint, "\n", "\n    ")))
+		}
+	}
+	if len(hints) > 0 {
+		fmt.Println("\nSuggestions:")
+		fmt.Println(strings.Join(hints, "\n"))
+	}
+
+	fmt.Printf("\n%d passed, %d warnings, %d failed\n",
+		report.Count(doctor.StatusPass), report.Count(doctor.StatusWarn), report.Count(doctor.StatusFail))
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "Print the report as JSON")
+}
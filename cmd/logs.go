@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"os"
@@ -44,7 +43,7 @@ Examples:
 		}
 
 		// Read file
-		lines, err := readLogFile(filePath, logsTail)
+		lines, err := logs.ReadLastLines(filePath, logsTail)
 		if err != nil {
 			fmt.Printf("❌ Error: %v\n", err)
 			return
@@ -77,29 +76,6 @@ Examples:
 	},
 }
 
-func readLogFile(path string, n int) ([]string, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, fmt.Errorf("cannot open file: %w", err)
-	}
-	defer f.Close()
-
-	var lines []string
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-
-	// Return last N lines
-	if n > 0 && len(lines) > n {
-		return lines[len(lines)-n:], nil
-	}
-	return lines, nil
-}
-
 func displayLogs(lines []string, format logs.LogFormat, useColor bool) {
 	// Analyze issues
 	issues := logs.AnalyzeLines(lines)
@@ -199,43 +175,11 @@ func analyzeLogsWithAI(ctx context.Context, lines []string, filePath string) {
 		fmt.Printf("⚠️  Cannot connect to AI: %v\n", err)
 		return
 	}
-	defer appCtx.Provider.Close()
-
-	// Build prompt
-	var sb strings.Builder
-	sb.WriteString("Analyze the following log file and provide insights:\n\n")
-	sb.WriteString(fmt.Sprintf("File: %s\n", filepath.Base(filePath)))
-	sb.WriteString(fmt.Sprintf("Lines: %d\n\n", len(lines)))
-
-	// Include issues
-	issues := logs.AnalyzeLines(lines)
-	if len(issues) > 0 {
-		sb.WriteString("Detected issues:\n")
-		for _, issue := range issues {
-			sb.WriteString(fmt.Sprintf("- Line %d: %s (%s)\n", issue.Line, issue.Description, issue.Category))
-		}
-		sb.WriteString("\n")
-	}
-
-	// Include sample of error lines (first 20)
-	errorLines := 0
-	sb.WriteString("Sample error lines:\n")
-	for _, line := range lines {
-		level := logs.DetectLevel(line)
-		if level == logs.LevelError || level == logs.LevelFatal {
-			sb.WriteString("  " + line + "\n")
-			errorLines++
-			if errorLines >= 20 {
-				break
-			}
-		}
-	}
-
-	sb.WriteString("\nPlease analyze root causes and suggest specific remediation steps.")
+	defer appCtx.Close()
 
 	// Generate AI response
-	resp, err := appCtx.Provider.Generate(ctx, ports.GenerateRequest{
-		Prompt: sb.String(),
+	resp, err := appCtx.CachedProvider(bootstrap.RoleAnalyzer, !noCache).Generate(ctx, ports.GenerateRequest{
+		Prompt: logs.AnalysisPrompt(filepath.Base(filePath), lines),
 	})
 	if err != nil {
 		fmt.Printf("⚠️  AI Error: %v\n", err)
@@ -2,13 +2,17 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/configstore/vibeyaml"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/bootstrap"
 	appConfig "github.com/phamdaiminhquan/vibe-devops/internal/app/config"
-	"github.com/phamdaiminhquan/vibe-devops/pkg/ai"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/tokens"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
 	"github.com/phamdaiminhquan/vibe-devops/pkg/config"
 	"github.com/spf13/cobra"
 )
@@ -27,69 +31,208 @@ var modelCmd = &cobra.Command{
 			return fmt.Errorf("failed to load config: %w. Please run 'vibe init' first", err)
 		}
 
-		switch strings.ToLower(strings.TrimSpace(cfg.AI.Provider)) {
-		case "gemini":
-			apiKey := strings.TrimSpace(cfg.AI.Gemini.APIKey)
-			if apiKey == "" || apiKey == config.DefaultAPIKeyPlaceholder {
-				return fmt.Errorf("Gemini API key is not configured. Run 'vibe config api-key " + "<your_api_key>" + "' first")
-			}
-
-			if len(args) == 1 {
-				model := strings.TrimSpace(args[0])
-				model = strings.TrimPrefix(model, "models/")
-				if _, err := svc.SetGeminiModel(".", model); err != nil {
-					return fmt.Errorf("failed to write updated config: %w", err)
-				}
-				fmt.Printf("✅ Model set to '%s'.\n", model)
-				return nil
-			}
+		provider := strings.ToLower(strings.TrimSpace(cfg.AI.Provider))
+		if !config.IsSupportedProvider(provider) {
+			return fmt.Errorf("unsupported provider '%s'", cfg.AI.Provider)
+		}
 
-			models, err := ai.GetGeminiModels(apiKey)
+		if len(args) == 1 {
+			updated, err := svc.SetModel(".", args[0])
 			if err != nil {
-				return fmt.Errorf("failed to fetch models: %w", err)
-			}
-			if len(models) == 0 {
-				return fmt.Errorf("no models found for this API key")
+				return fmt.Errorf("failed to write updated config: %w", err)
 			}
+			fmt.Printf("✅ Model set to '%s'.\n", updated.ActiveModel())
+			return nil
+		}
 
-			if modelListOnly {
-				for _, m := range models {
-					fmt.Println(strings.TrimPrefix(m, "models/"))
-				}
-				return nil
-			}
+		models, err := fetchModels(cmd.Context(), cfg, provider)
+		if err != nil {
+			return err
+		}
 
-			fmt.Println("Select a model to use:")
-			for i, m := range models {
-				displayName := strings.TrimPrefix(m, "models/")
-				fmt.Printf("[%d] %s\n", i+1, displayName)
-			}
+		if modelListOnly {
+			printModels(models, cfg.ActiveModel())
+			return nil
+		}
 
-			reader := bufio.NewReader(os.Stdin)
-			fmt.Print("Enter the number of the model: ")
-			input, _ := reader.ReadString('\n')
-			input = strings.TrimSpace(input)
+		selected, err := selectModel(models)
+		if err != nil {
+			return err
+		}
+		if _, err := svc.SetModel(".", selected); err != nil {
+			return fmt.Errorf("failed to write updated config: %w", err)
+		}
+		fmt.Printf("✅ Model set to '%s'.\n", selected)
+		return nil
+	},
+}
 
-			idx := -1
-			_, scanErr := fmt.Sscanf(input, "%d", &idx)
-			if scanErr != nil || idx < 1 || idx > len(models) {
-				return fmt.Errorf("invalid selection")
-			}
+var modelPullCmd = &cobra.Command{
+	Use:   "pull [model]",
+	Short: "Download a model into the local Ollama server",
+	Long:  "Pulls a model from the Ollama registry into the server configured under ai.ollama.host.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := appConfig.NewService(vibeyaml.New()).Load(".")
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w. Please run 'vibe init' first", err)
+		}
 
-			selectedModel := strings.TrimPrefix(models[idx-1], "models/")
-			if _, err := svc.SetGeminiModel(".", selectedModel); err != nil {
-				return fmt.Errorf("failed to write updated config: %w", err)
-			}
-			fmt.Printf("✅ Model set to '%s'.\n", selectedModel)
-			return nil
+		catalog, closeCatalog, err := bootstrap.NewModelCatalog(cfg, config.ProviderOllama)
+		if err != nil {
+			return err
+		}
+		defer closeCatalog()
+		puller, ok := catalog.(ports.ModelPuller)
+		if !ok {
+			return fmt.Errorf("ollama cannot pull models")
+		}
 
-		default:
-			return fmt.Errorf("unsupported provider '%s'", cfg.AI.Provider)
+		name := args[0]
+		status := ""
+		err = puller.PullModel(cmd.Context(), name, func(p ports.PullProgress) {
+			if p.Total > 0 {
+				fmt.Printf("\r%s %5.1f%% (%s / %s)   ", p.Status, float64(p.Completed)*100/float64(p.Total), formatSize(p.Completed), formatSize(p.Total))
+				status = p.Status
+				return
+			}
+			if p.Status != status {
+				if status != "" {
+					fmt.Println()
+				}
+				fmt.Print(p.Status)
+				status = p.Status
+			}
+		})
+		fmt.Println()
+		if err != nil {
+			return err
 		}
+		fmt.Printf("✅ Pulled '%s'. Run 'vibe model %s' to use it.\n", name, name)
+		return nil
 	},
 }
 
+// fetchModels lists the models of provider, filling in context windows the API
+// does not report from the built-in table.
+func fetchModels(ctx context.Context, cfg *config.Config, provider string) ([]ports.ModelInfo, error) {
+	catalog, closeCatalog, err := bootstrap.NewModelCatalog(cfg, provider)
+	if err != nil {
+		if provider != config.ProviderOllama {
+			return nil, fmt.Errorf("%w. Run 'vibe config api-key <your_api_key>' first", err)
+		}
+		return nil, err
+	}
+	defer closeCatalog()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	models, err := catalog.ListModels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch models: %w", err)
+	}
+	if len(models) == 0 {
+		if provider == config.ProviderOllama {
+			return nil, fmt.Errorf("no models installed. Run 'vibe model pull <name>' first")
+		}
+		return nil, fmt.Errorf("no models found for this API key")
+	}
+	for i := range models {
+		if models[i].ContextWindow == 0 {
+			models[i].ContextWindow = tokens.KnownWindow(models[i].Name)
+		}
+	}
+	return models, nil
+}
+
+func printModels(models []ports.ModelInfo, current string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  MODEL\tCONTEXT\tCAPABILITIES\tDETAILS")
+	for _, m := range models {
+		marker := " "
+		if m.Name == current {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "%s %s\t%s\t%s\t%s\n", marker, m.Name, formatWindow(m.ContextWindow), capabilities(m), modelDetails(m))
+	}
+	w.Flush()
+}
+
+// selectModel prompts for a model by number.
+func selectModel(models []ports.ModelInfo) (string, error) {
+	fmt.Println("Select a model to use:")
+	for i, m := range models {
+		hints := capabilities(m)
+		if window := formatWindow(m.ContextWindow); window != "-" {
+			hints = window + ", " + hints
+		}
+		fmt.Printf("[%d] %s (%s)\n", i+1, m.Name, hints)
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Print("Enter the number of the model: ")
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input)
+
+	idx := -1
+	_, scanErr := fmt.Sscanf(input, "%d", &idx)
+	if scanErr != nil || idx < 1 || idx > len(models) {
+		return "", fmt.Errorf("invalid selection")
+	}
+	return models[idx-1].Name, nil
+}
+
+func capabilities(m ports.ModelInfo) string {
+	var caps []string
+	if m.ToolCalling {
+		caps = append(caps, "tools")
+	}
+	if m.JSONMode {
+		caps = append(caps, "json")
+	}
+	if m.Vision {
+		caps = append(caps, "vision")
+	}
+	if len(caps) == 0 {
+		return "-"
+	}
+	return strings.Join(caps, ", ")
+}
+
+func modelDetails(m ports.ModelInfo) string {
+	details := m.Details
+	if m.Size > 0 {
+		details = strings.TrimSpace(details + " " + formatSize(m.Size))
+	}
+	if details == "" {
+		return "-"
+	}
+	return details
+}
+
+func formatWindow(n int) string {
+	switch {
+	case n <= 0:
+		return "-"
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.0fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%dk", n/1_000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+func formatSize(b int64) string {
+	const gb, mb = 1 << 30, 1 << 20
+	if b >= gb {
+		return fmt.Sprintf("%.1f GB", float64(b)/gb)
+	}
+	return fmt.Sprintf("%.0f MB", float64(b)/mb)
+}
+
 func init() {
 	rootCmd.AddCommand(modelCmd)
+	modelCmd.AddCommand(modelPullCmd)
 	modelCmd.Flags().BoolVar(&modelListOnly, "list", false, "List available models (does not change config)")
 }
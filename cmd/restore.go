@@ -1,20 +1,23 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/ui"
 	"github.com/phamdaiminhquan/vibe-devops/internal/app/safety"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
 	"github.com/spf13/cobra"
 )
 
 var (
 	restoreList    bool
 	restoreCleanup bool
+	restoreLast    bool
+	restoreYes     bool
 )
 
 var restoreCmd = &cobra.Command{
@@ -24,100 +27,128 @@ var restoreCmd = &cobra.Command{
 
 Examples:
   vibe restore              # Interactive: select a backup to restore
+  vibe restore --last       # Restore the most recent backup
+  vibe restore --last --yes # Same, without prompts (for scripts)
   vibe restore --list       # List all available backups
   vibe restore --cleanup    # Remove backups older than 7 days`,
 	Run: func(cmd *cobra.Command, args []string) {
+		console := ui.NewTerminal(os.Stdin, os.Stdout)
+
 		if restoreCleanup {
-			fmt.Println("Cleaning up old backups...")
+			console.Message(ports.MessageInfo, "Cleaning up old backups...")
 			safety.CleanupOldBackups()
-			fmt.Println("✅ Done!")
+			console.Message(ports.MessageSuccess, "Done!")
 			return
 		}
 
 		if restoreList {
-			listBackups()
+			listBackups(console)
 			return
 		}
 
-		interactiveRestore()
+		if restoreLast {
+			restoreLatest(console)
+			return
+		}
+
+		if restoreYes {
+			console.Message(ports.MessageError, "--yes needs --last: there is nobody to pick a backup")
+			return
+		}
+
+		interactiveRestore(console)
 	},
 }
 
-func listBackups() {
+func listBackups(console ports.UI) {
 	backups, err := safety.GetRecentBackups(10)
 	if err != nil {
-		fmt.Printf("❌ Error: %v\n", err)
+		console.Message(ports.MessageError, fmt.Sprintf("Error: %v", err))
 		return
 	}
 
 	if len(backups) == 0 {
-		fmt.Println("No safety backups found")
-		fmt.Println("   Backups are created when running dangerous commands")
+		console.Message(ports.MessageInfo, "No safety backups found\n   Backups are created when running dangerous commands")
 		return
 	}
 
-	fmt.Println("\nSafety Backups:")
-	fmt.Println("═══════════════════════════════════════")
-	for i, b := range backups {
-		relTime := b.Timestamp.Format("2006-01-02 15:04:05")
-		fmt.Printf("  %d. [%s]\n", i+1, relTime)
-		fmt.Printf("     Command: %s\n", truncate(b.Command, 60))
-		fmt.Printf("     Files: %d paths\n", len(b.BackupPaths))
+	var b strings.Builder
+	b.WriteString("\nSafety Backups:\n")
+	b.WriteString("═══════════════════════════════════════")
+	for i, backup := range backups {
+		relTime := backup.Timestamp.Format("2006-01-02 15:04:05")
+		fmt.Fprintf(&b, "\n  %d. [%s]", i+1, relTime)
+		fmt.Fprintf(&b, "\n     Command: %s", truncate(backup.Command, 60))
+		fmt.Fprintf(&b, "\n     Files: %d paths", len(backup.BackupPaths))
 	}
-	fmt.Println()
+	b.WriteString("\n")
+	console.Message(ports.MessageInfo, b.String())
 }
 
-func interactiveRestore() {
+func interactiveRestore(console ports.UI) {
 	backups, err := safety.GetRecentBackups(10)
 	if err != nil {
-		fmt.Printf("❌ Error: %v\n", err)
+		console.Message(ports.MessageError, fmt.Sprintf("Error: %v", err))
 		return
 	}
 
 	if len(backups) == 0 {
-		fmt.Println("No safety backups found")
+		console.Message(ports.MessageInfo, "No safety backups found")
 		return
 	}
 
-	fmt.Println("\nSafety Backups:")
-	fmt.Println("═══════════════════════════════════════")
-	for i, b := range backups {
-		relTime := b.Timestamp.Format("2006-01-02 15:04:05")
-		fmt.Printf("  %d. [%s]\n", i+1, relTime)
-		fmt.Printf("     Command: %s\n", truncate(b.Command, 60))
-		for orig := range b.BackupPaths {
-			fmt.Printf("     • %s\n", orig)
+	var b strings.Builder
+	b.WriteString("\nSafety Backups:\n")
+	b.WriteString("═══════════════════════════════════════")
+	for i, backup := range backups {
+		relTime := backup.Timestamp.Format("2006-01-02 15:04:05")
+		fmt.Fprintf(&b, "\n  %d. [%s]", i+1, relTime)
+		fmt.Fprintf(&b, "\n     Command: %s", truncate(backup.Command, 60))
+		for orig := range backup.BackupPaths {
+			fmt.Fprintf(&b, "\n     • %s", orig)
 		}
 	}
-	fmt.Print("\nSelect to restore (1-", len(backups), ", or 'q' to quit): ")
-
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(input)
+	console.Message(ports.MessageInfo, b.String())
 
+	input, _ := console.Input(fmt.Sprintf("\nSelect to restore (1-%d, or 'q' to quit): ", len(backups)))
 	if input == "q" || input == "" {
-		fmt.Println("Cancelled.")
+		console.Message(ports.MessageInfo, "Cancelled.")
 		return
 	}
 
 	idx, err := strconv.Atoi(input)
 	if err != nil || idx < 1 || idx > len(backups) {
-		fmt.Println("❌ Invalid selection")
+		console.Message(ports.MessageError, "Invalid selection")
 		return
 	}
 
-	selected := backups[idx-1]
+	restoreFrom(console, backups[idx-1])
+}
+
+func restoreLatest(console ports.UI) {
+	backups, err := safety.GetRecentBackups(1)
+	if err != nil {
+		console.Message(ports.MessageError, fmt.Sprintf("Error: %v", err))
+		return
+	}
+	if len(backups) == 0 {
+		console.Message(ports.MessageInfo, "No safety backups found")
+		return
+	}
+	restoreFrom(console, backups[0])
+}
 
+// restoreFrom restores the files of one backup after confirmation (skipped with --yes).
+func restoreFrom(console ports.UI, selected safety.BackupManifest) {
 	// Confirm restoration
-	fmt.Println("\n⚠️  This will overwrite current files with backed up versions:")
+	var b strings.Builder
+	b.WriteString("\nThis will overwrite current files with backed up versions:")
 	for orig := range selected.BackupPaths {
-		fmt.Printf("   • %s\n", orig)
+		fmt.Fprintf(&b, "\n   • %s", orig)
 	}
-	fmt.Print("\nContinue? (y/N) ")
-
-	confirm, _ := reader.ReadString('\n')
-	if strings.ToLower(strings.TrimSpace(confirm)) != "y" {
-		fmt.Println("Cancelled.")
+	console.Message(ports.MessageWarning, b.String())
+	if !restoreYes && !console.Confirm("\nContinue?") {
+		console.Message(ports.MessageInfo, "Cancelled.")
 		return
 	}
 
@@ -125,12 +156,12 @@ func interactiveRestore() {
 	homeDir, _ := os.UserHomeDir()
 	backupPath := filepath.Join(homeDir, safety.BackupDir, selected.Timestamp.Format("2006-01-02_15-04-05"))
 
-	fmt.Println("\nRestoring files...")
+	console.Message(ports.MessageInfo, "\nRestoring files...")
 	if err := safety.RestoreBackup(backupPath); err != nil {
-		fmt.Printf("❌ Error: %v\n", err)
+		console.Message(ports.MessageError, fmt.Sprintf("Error: %v", err))
 		return
 	}
-	fmt.Println("✅ Done! Files restored.")
+	console.Message(ports.MessageSuccess, "Done! Files restored.")
 }
 
 func truncate(s string, max int) string {
@@ -143,5 +174,7 @@ func truncate(s string, max int) string {
 func init() {
 	restoreCmd.Flags().BoolVar(&restoreList, "list", false, "List all available backups")
 	restoreCmd.Flags().BoolVar(&restoreCleanup, "cleanup", false, "Remove backups older than 7 days")
+	restoreCmd.Flags().BoolVar(&restoreLast, "last", false, "Restore the most recent backup")
+	restoreCmd.Flags().BoolVarP(&restoreYes, "yes", "y", false, "Don't ask for confirmation (use with --last)")
 	rootCmd.AddCommand(restoreCmd)
 }
@@ -88,7 +88,11 @@ func Execute() {
 	}
 }
 
+// noCache disables the on-disk response cache for analysis commands.
+var noCache bool
+
 func init() {
 	// Add global flags here if needed
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.vibe.yaml)")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Bypass the AI response cache (~/.vibe/cache)")
 }
@@ -2,16 +2,26 @@ package cmd
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"strings"
+	"time"
 
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/ui"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/approval"
 	"github.com/phamdaiminhquan/vibe-devops/internal/app/bootstrap"
 	"github.com/phamdaiminhquan/vibe-devops/internal/app/command"
 	appSession "github.com/phamdaiminhquan/vibe-devops/internal/app/session"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/usage"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
 	"github.com/spf13/cobra"
 )
 
 var runAgentMode bool
 var runAgentMaxSteps int
+var runAgentMaxRepairs int
+var runAgentToolWorkers int
+var runAgentToolTimeout time.Duration
 var runSelfHeal bool
 var runSelfHealMaxAttempts int
 
@@ -22,6 +32,13 @@ var runNoSession bool
 var runContextBudget int
 var runContextRecentLines int
 
+var runBudgetUSD float64
+var runBudgetTokens int
+
+var runYes bool
+var runPolicy string
+var runOutput string
+
 var runCmd = &cobra.Command{
 	Use:   "run [natural language request]",
 	Short: "Execute a command based on a natural language request",
@@ -36,6 +53,9 @@ func init() {
 	rootCmd.AddCommand(runCmd)
 	runCmd.Flags().BoolVar(&runAgentMode, "agent", true, "Enable agent mode (default: true). Use --agent=false for simple single-shot mode")
 	runCmd.Flags().IntVar(&runAgentMaxSteps, "agent-max-steps", 10, "Max tool steps in agent mode")
+	runCmd.Flags().IntVar(&runAgentToolWorkers, "agent-tool-workers", 4, "Max read-only tools the agent runs in parallel")
+	runCmd.Flags().DurationVar(&runAgentToolTimeout, "agent-tool-timeout", time.Minute, "Timeout for a single tool run in agent mode")
+	runCmd.Flags().IntVar(&runAgentMaxRepairs, "agent-max-repairs", 2, "Max correction turns when the model returns a malformed action (0 disables)")
 	runCmd.Flags().BoolVar(&runSelfHeal, "self-heal", true, "In agent mode, keep iterating after execution by reading command output and proposing next steps until an answer is reached (default: true)")
 	runCmd.Flags().IntVar(&runSelfHealMaxAttempts, "self-heal-max-attempts", 3, "Max execution/repair iterations in self-heal loop (agent mode only)")
 
@@ -45,17 +65,74 @@ func init() {
 	runCmd.Flags().BoolVar(&runNoSession, "no-session", false, "Disable session persistence (agent mode)")
 	runCmd.Flags().IntVar(&runContextBudget, "context-budget", 8000, "Approx char budget for session context tail")
 	runCmd.Flags().IntVar(&runContextRecentLines, "context-recent-lines", 40, "Max recent transcript lines to keep in session memory")
+	runCmd.Flags().Float64Var(&runBudgetUSD, "budget-usd", 0, "Stop the run once it has spent this many USD on model calls (0 = usage.budget from config)")
+	runCmd.Flags().IntVar(&runBudgetTokens, "budget-tokens", 0, "Stop the run once it has used this many tokens (0 = usage.budget from config)")
+
+	runCmd.Flags().BoolVarP(&runYes, "yes", "y", false, "Never prompt; approve proposed commands with --policy (default auto)")
+	runCmd.Flags().StringVar(&runPolicy, "policy", "", "Approval policy for non-interactive runs: strict|readonly|auto (implies no prompts)")
+	runCmd.Flags().StringVarP(&runOutput, "output", "o", "text", "Output format: text|json (json implies no prompts, with --policy readonly by default)")
+}
+
+// approvalPolicy resolves the approval policy from --yes, --policy and --output.
+// An empty policy means an interactive run.
+func approvalPolicy() (approval.Policy, error) {
+	switch {
+	case runPolicy != "":
+		return approval.ParsePolicy(runPolicy)
+	case runYes:
+		return approval.Auto, nil
+	case runOutput == "json":
+		return approval.ReadOnly, nil
+	}
+	return "", nil
+}
+
+// trustProject asks on console whether to trust settings from the project's
+// .vibe.yaml, such as its local MCP servers.
+func trustProject(console ports.UI) bootstrap.TrustFunc {
+	return func(warning string, lines []string) bool {
+		console.Message(ports.MessageWarning, warning+":\n  "+strings.Join(lines, "\n  ")+"\nOnly trust projects you know.")
+		return console.Confirm("Trust them for this project?")
+	}
 }
 
 func runCommand(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	if runOutput != "text" && runOutput != "json" {
+		return fmt.Errorf("invalid output format '%s' (use text or json)", runOutput)
+	}
+	policy, err := approvalPolicy()
+	if err != nil {
+		return err
+	}
+	// With JSON output, stdout carries only the result; progress goes to stderr.
+	console := ui.NewTerminal(os.Stdin, os.Stdout)
+	if runOutput == "json" {
+		console = ui.NewTerminal(os.Stdin, os.Stderr)
+	}
+
 	// 1. Bootstrap Application
 	appCtx, err := bootstrap.Initialize(ctx)
 	if err != nil {
 		return err
 	}
-	defer func() { _ = appCtx.Provider.Close() }()
+	defer func() { _ = appCtx.Close() }()
+
+	appCtx.Usage.SetSession(runSessionName)
+	if runBudgetUSD > 0 || runBudgetTokens > 0 {
+		budget := usage.Budget{
+			MaxCostUSD: appCtx.Config.Usage.Budget.MaxCostUSD,
+			MaxTokens:  appCtx.Config.Usage.Budget.MaxTokens,
+		}
+		if runBudgetUSD > 0 {
+			budget.MaxCostUSD = runBudgetUSD
+		}
+		if runBudgetTokens > 0 {
+			budget.MaxTokens = runBudgetTokens
+		}
+		appCtx.Usage.SetBudget(budget)
+	}
 
 	// 2. Setup Session Service
 	sessionCfg := bootstrap.SessionConfig{
@@ -68,18 +145,38 @@ func runCommand(cmd *cobra.Command, args []string) error {
 			MaxRecentChars: runContextBudget,
 		},
 	}
-	sessionSvc := bootstrap.InitializeSessionService(appCtx.Provider, sessionCfg)
+	sessionSvc := bootstrap.InitializeSessionService(appCtx.ProviderFor(bootstrap.RoleSummarizer), sessionCfg)
+
+	// 3. Connect to MCP servers; one that fails only loses its tools
+	var trust bootstrap.TrustFunc
+	if policy == "" {
+		trust = trustProject(console)
+	}
+	if err := appCtx.TrustToolPolicies(trust); err != nil {
+		console.Message(ports.MessageWarning, fmt.Sprintf("Tool policies: %v", err))
+	}
+	if runAgentMode {
+		if err := appCtx.ConnectTools(ctx, rootCmd.Version, trust); err != nil {
+			console.Message(ports.MessageWarning, fmt.Sprintf("Skipping MCP tools: %v", err))
+		}
+	}
 
-	// 3. Setup Command Handler
+	// 4. Setup Command Handler
 	flags := command.RunFlags{
 		AgentMode:           runAgentMode,
 		AgentMaxSteps:       runAgentMaxSteps,
+		AgentMaxRepairs:     runAgentMaxRepairs,
+		AgentToolWorkers:    runAgentToolWorkers,
+		AgentToolTimeout:    runAgentToolTimeout,
 		SelfHeal:            runSelfHeal,
 		SelfHealMaxAttempts: runSelfHealMaxAttempts,
+		Policy:              policy,
+		JSON:                runOutput == "json",
+		SessionName:         runSessionName,
 	}
-	handler := command.NewRunHandler(appCtx, sessionSvc, flags)
+	handler := command.NewRunHandler(appCtx, sessionSvc, console, flags)
 
-	// 4. Delegate to Handler
+	// 5. Delegate to Handler
 	userRequest := strings.Join(args, " ")
 	return handler.Handle(ctx, userRequest)
 }
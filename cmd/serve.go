@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/ui"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/bootstrap"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/command"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/server"
+	appSession "github.com/phamdaiminhquan/vibe-devops/internal/app/session"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var serveAddr string
+var serveToken string
+var serveAgentMaxSteps int
+var serveAgentMaxRepairs int
+var serveAgentToolWorkers int
+var serveAgentToolTimeout time.Duration
+var serveSelfHeal bool
+var serveSelfHealMaxAttempts int
+var serveSessionName string
+var serveSessionScope string
+var serveNoSession bool
+var serveLogDir string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the agent over a local HTTP API",
+	Long: `Starts a localhost HTTP/JSON API for dashboards and editor plugins.
+
+Clients authenticate with "Authorization: Bearer <token>" (or ?token= for
+EventSource). The token comes from --token, then $VIBE_SERVE_TOKEN; otherwise a
+random one is generated and printed at startup.
+
+Endpoints:
+  POST   /v1/runs                      Start a run: {"request": "...", "policy": "readonly"}
+  GET    /v1/runs                      List runs
+  GET    /v1/runs/{id}                 Run status, pending prompt and result
+  GET    /v1/runs/{id}/events          Stream progress, tokens and prompts (SSE)
+  POST   /v1/runs/{id}/prompts/{p}     Answer a prompt: {"approve": true} or {"answer": "a"}
+  DELETE /v1/runs/{id}                 Cancel a run
+  GET    /v1/sessions                  List saved sessions
+  POST   /v1/diagnose                  Run diagnostics: {"ai": true}
+  POST   /v1/logs/analyze              Analyze a log file under --log-dir: {"path": "app.log", "tail": 100, "ai": true}`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         serveCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:7777", "Address to listen on (loopback only)")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "API token (default $VIBE_SERVE_TOKEN, or a random one)")
+	serveCmd.Flags().IntVar(&serveAgentMaxSteps, "agent-max-steps", 10, "Default max tool steps per run")
+	serveCmd.Flags().IntVar(&serveAgentToolWorkers, "agent-tool-workers", 4, "Max read-only tools the agent runs in parallel")
+	serveCmd.Flags().DurationVar(&serveAgentToolTimeout, "agent-tool-timeout", time.Minute, "Timeout for a single tool run")
+	serveCmd.Flags().IntVar(&serveAgentMaxRepairs, "agent-max-repairs", 2, "Max correction turns when the model returns a malformed action (0 disables)")
+	serveCmd.Flags().BoolVar(&serveSelfHeal, "self-heal", true, "After running a command, let the agent read its output and continue")
+	serveCmd.Flags().IntVar(&serveSelfHealMaxAttempts, "self-heal-max-attempts", 3, "Max execution/repair iterations per run")
+
+	serveCmd.Flags().StringVar(&serveSessionName, "session", "default", "Default session name for runs")
+	serveCmd.Flags().StringVar(&serveSessionScope, "session-scope", "both", "Session scope: none|project|global|both")
+	serveCmd.Flags().BoolVar(&serveNoSession, "no-session", false, "Disable session persistence")
+	serveCmd.Flags().StringVar(&serveLogDir, "log-dir", "", "Directory /v1/logs/analyze may read from (default the working directory)")
+}
+
+func serveCommand(cmd *cobra.Command, args []string) error {
+	if err := checkLoopback(serveAddr); err != nil {
+		return err
+	}
+	token := serveToken
+	if token == "" {
+		token = os.Getenv("VIBE_SERVE_TOKEN")
+	}
+	generated := token == ""
+	if generated {
+		buf := make([]byte, 24)
+		if _, err := rand.Read(buf); err != nil {
+			return fmt.Errorf("failed to generate a token: %w", err)
+		}
+		token = hex.EncodeToString(buf)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	appCtx, err := bootstrap.Initialize(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = appCtx.Close() }()
+	// Each run tags its usage with its own session; this covers the rest.
+	appCtx.Usage.SetSession(serveSessionName)
+	// Only someone at the terminal can trust the project's MCP servers and
+	// tool policies.
+	var trust bootstrap.TrustFunc
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		trust = trustProject(ui.NewTerminal(os.Stdin, os.Stdout))
+	}
+	if err := appCtx.TrustToolPolicies(trust); err != nil {
+		fmt.Fprintf(os.Stderr, "[VIBE] Tool policies: %v\n", err)
+	}
+	if err := appCtx.ConnectTools(ctx, rootCmd.Version, trust); err != nil {
+		fmt.Fprintf(os.Stderr, "[VIBE] Skipping MCP tools: %v\n", err)
+	}
+
+	srv := server.New(appCtx, server.Config{
+		Token: token,
+		Flags: command.RunFlags{
+			AgentMode:           true,
+			AgentMaxSteps:       serveAgentMaxSteps,
+			AgentMaxRepairs:     serveAgentMaxRepairs,
+			AgentToolWorkers:    serveAgentToolWorkers,
+			AgentToolTimeout:    serveAgentToolTimeout,
+			SelfHeal:            serveSelfHeal,
+			SelfHealMaxAttempts: serveSelfHealMaxAttempts,
+		},
+		Session: bootstrap.SessionConfig{
+			Name:      serveSessionName,
+			Scope:     serveSessionScope,
+			Resume:    true,
+			NoSession: serveNoSession,
+			Budget:    appSession.Budget{},
+		},
+		NoCache: noCache,
+		LogDir:  serveLogDir,
+	})
+
+	listener, err := net.Listen("tcp", serveAddr)
+	if err != nil {
+		return err
+	}
+	httpSrv := &http.Server{Handler: srv.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	fmt.Printf("[VIBE] Serving on http://%s\n", listener.Addr())
+	if generated {
+		fmt.Printf("[VIBE] Token: %s\n", token)
+	}
+	fmt.Println("[VIBE] Press Ctrl+C to stop.")
+
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpSrv.Shutdown(shutdownCtx)
+	}()
+	if err := httpSrv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// checkLoopback refuses addresses other machines could reach: runs execute
+// commands on this host, so the API stays local.
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid address '%s': %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("refusing to listen on '%s': vibe serve only binds to loopback addresses such as 127.0.0.1", addr)
+}
@@ -1,19 +1,21 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/ui"
 	vibegit "github.com/phamdaiminhquan/vibe-devops/internal/app/git"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
 	"github.com/spf13/cobra"
 )
 
 var (
 	undoLast bool
 	undoList bool
+	undoYes  bool
 )
 
 var undoCmd = &cobra.Command{
@@ -24,104 +26,99 @@ var undoCmd = &cobra.Command{
 Examples:
   vibe undo              # Interactive: select a checkpoint to restore
   vibe undo --last       # Restore to the last checkpoint
+  vibe undo --last --yes # Same, without prompts (for scripts)
   vibe undo --list       # List all available checkpoints`,
 	Run: func(cmd *cobra.Command, args []string) {
 		workDir, _ := os.Getwd()
+		console := ui.NewTerminal(os.Stdin, os.Stdout)
 
 		// Check if git repo
 		if !vibegit.IsGitRepo(workDir) {
-			fmt.Println("Not a git repository")
+			console.Message(ports.MessageError, "Not a git repository")
 			return
 		}
 
 		if undoList {
-			listCheckpoints(workDir)
+			listCheckpoints(console, workDir)
 			return
 		}
 
 		if undoLast {
-			undoLastCheckpoint(workDir)
+			undoLastCheckpoint(console, workDir)
+			return
+		}
+
+		if undoYes {
+			console.Message(ports.MessageError, "--yes needs --last: there is nobody to pick a checkpoint")
 			return
 		}
 
 		// Interactive mode
-		interactiveUndo(workDir)
+		interactiveUndo(console, workDir)
 	},
 }
 
-func listCheckpoints(workDir string) {
+// recentCheckpoints loads and prints the checkpoints, or explains why there are none.
+func recentCheckpoints(console ports.UI, workDir string) []vibegit.CheckpointInfo {
 	checkpoints, err := vibegit.GetRecentCheckpoints(workDir, 10)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		return
+		console.Message(ports.MessageError, fmt.Sprintf("Error: %v", err))
+		return nil
 	}
 
 	if len(checkpoints) == 0 {
-		fmt.Println("No vibe checkpoints found")
-		fmt.Println("   Checkpoints are created automatically before AI sessions")
-		return
+		console.Message(ports.MessageInfo, "No vibe checkpoints found\n   Checkpoints are created automatically before AI sessions")
+		return nil
 	}
 
-	fmt.Println("\nRecent Vibe Checkpoints:")
-	fmt.Println("═══════════════════════════════════════")
+	var b strings.Builder
+	b.WriteString("\nRecent Vibe Checkpoints:\n")
+	b.WriteString("═══════════════════════════════════════")
 	for i, cp := range checkpoints {
-		fmt.Printf("  %d. [%s] %s - %s\n", i+1, cp.Hash, cp.Relative, cp.Message)
+		fmt.Fprintf(&b, "\n  %d. [%s] %s - %s", i+1, cp.Hash, cp.Relative, cp.Message)
 	}
-	fmt.Println()
+	console.Message(ports.MessageInfo, b.String())
+	return checkpoints
 }
 
-func undoLastCheckpoint(workDir string) {
+func listCheckpoints(console ports.UI, workDir string) {
+	if recentCheckpoints(console, workDir) != nil {
+		console.Message(ports.MessageInfo, "")
+	}
+}
+
+func undoLastCheckpoint(console ports.UI, workDir string) {
 	// Check for uncommitted changes
-	if vibegit.HasUncommittedChanges(workDir) {
-		fmt.Print("⚠️  You have uncommitted changes. Continue anyway? (y/N) ")
-		reader := bufio.NewReader(os.Stdin)
-		input, _ := reader.ReadString('\n')
-		if strings.ToLower(strings.TrimSpace(input)) != "y" {
-			fmt.Println("Cancelled.")
+	if vibegit.HasUncommittedChanges(workDir) && !undoYes {
+		if !console.Confirm("⚠️  You have uncommitted changes. Continue anyway?") {
+			console.Message(ports.MessageInfo, "Cancelled.")
 			return
 		}
 	}
 
-	fmt.Println("Restoring to last checkpoint...")
+	console.Message(ports.MessageInfo, "Restoring to last checkpoint...")
 	if err := vibegit.UndoLastCheckpoint(workDir); err != nil {
-		fmt.Printf("Error: %v\n", err)
+		console.Message(ports.MessageError, fmt.Sprintf("Error: %v", err))
 		return
 	}
-	fmt.Println("Done! Workspace restored to before the last AI session.")
+	console.Message(ports.MessageSuccess, "Done! Workspace restored to before the last AI session.")
 }
 
-func interactiveUndo(workDir string) {
-	checkpoints, err := vibegit.GetRecentCheckpoints(workDir, 10)
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		return
-	}
-
-	if len(checkpoints) == 0 {
-		fmt.Println("No vibe checkpoints found")
-		fmt.Println("   Checkpoints are created automatically before AI sessions")
+func interactiveUndo(console ports.UI, workDir string) {
+	checkpoints := recentCheckpoints(console, workDir)
+	if checkpoints == nil {
 		return
 	}
 
-	fmt.Println("\nRecent Vibe Checkpoints:")
-	fmt.Println("═══════════════════════════════════════")
-	for i, cp := range checkpoints {
-		fmt.Printf("  %d. [%s] %s - %s\n", i+1, cp.Hash, cp.Relative, cp.Message)
-	}
-	fmt.Print("\nSelect to restore (1-", len(checkpoints), ", or 'q' to quit): ")
-
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(input)
-
+	input, _ := console.Input(fmt.Sprintf("\nSelect to restore (1-%d, or 'q' to quit): ", len(checkpoints)))
 	if input == "q" || input == "" {
-		fmt.Println("Cancelled.")
+		console.Message(ports.MessageInfo, "Cancelled.")
 		return
 	}
 
 	idx, err := strconv.Atoi(input)
 	if err != nil || idx < 1 || idx > len(checkpoints) {
-		fmt.Println("Invalid selection")
+		console.Message(ports.MessageError, "Invalid selection")
 		return
 	}
 
@@ -129,24 +126,23 @@ func interactiveUndo(workDir string) {
 
 	// Check for uncommitted changes
 	if vibegit.HasUncommittedChanges(workDir) {
-		fmt.Print("You have uncommitted changes. Continue anyway? (y/N) ")
-		confirm, _ := reader.ReadString('\n')
-		if strings.ToLower(strings.TrimSpace(confirm)) != "y" {
-			fmt.Println("Cancelled.")
+		if !console.Confirm("You have uncommitted changes. Continue anyway?") {
+			console.Message(ports.MessageInfo, "Cancelled.")
 			return
 		}
 	}
 
-	fmt.Printf("Restoring to checkpoint %s...\n", selected.Hash)
+	console.Message(ports.MessageInfo, fmt.Sprintf("Restoring to checkpoint %s...", selected.Hash))
 	if err := vibegit.RestoreCheckpoint(workDir, selected.Hash); err != nil {
-		fmt.Printf("Error: %v\n", err)
+		console.Message(ports.MessageError, fmt.Sprintf("Error: %v", err))
 		return
 	}
-	fmt.Println("Done! Workspace restored.")
+	console.Message(ports.MessageSuccess, "Done! Workspace restored.")
 }
 
 func init() {
 	undoCmd.Flags().BoolVar(&undoLast, "last", false, "Undo the last AI session")
 	undoCmd.Flags().BoolVar(&undoList, "list", false, "List all available checkpoints")
+	undoCmd.Flags().BoolVarP(&undoYes, "yes", "y", false, "Don't ask for confirmation (use with --last)")
 	rootCmd.AddCommand(undoCmd)
 }
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/bootstrap"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/usage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usageDays    int
+	usageBy      string
+	usageProject bool
+	usageSession string
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show token usage and estimated cost",
+	Long: `Report tokens and estimated cost of model calls, grouped by day, session and model.
+
+Usage is recorded in ~/.vibe/usage.jsonl. Costs come from a built-in price table
+that can be overridden with 'usage.prices' in .vibe.yaml.
+
+Examples:
+  vibe usage                    # Last 30 days, all projects
+  vibe usage --days 7 --by model
+  vibe usage --project          # Only the current project
+  vibe usage --session default`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		groups := []usage.GroupBy{usage.ByDay, usage.BySession, usage.ByModel}
+		if usageBy != "" {
+			g := usage.GroupBy(usageBy)
+			if g != usage.ByDay && g != usage.BySession && g != usage.ByModel {
+				return fmt.Errorf("invalid --by value '%s' (use day, session or model)", usageBy)
+			}
+			groups = []usage.GroupBy{g}
+		}
+
+		filter := usage.Filter{Session: usageSession}
+		if usageProject {
+			project, err := filepath.Abs(".")
+			if err != nil {
+				return err
+			}
+			filter.Project = project
+		}
+
+		since := time.Now().AddDate(0, 0, -usageDays)
+		records, err := bootstrap.NewUsageStore().List(since)
+		if err != nil {
+			return fmt.Errorf("failed to read usage: %w", err)
+		}
+
+		var total usage.Totals
+		for _, g := range groups {
+			var rows []usage.Row
+			rows, total = usage.Summarize(records, g, filter)
+			if total.Calls == 0 {
+				fmt.Printf("No usage recorded in the last %d days.\n", usageDays)
+				return nil
+			}
+			printUsageRows(g, rows)
+		}
+
+		fmt.Printf("Total (last %d days): %d calls, %d tokens, $%.4f\n", usageDays, total.Calls, total.TotalTokens(), total.CostUSD)
+		if total.UnpricedCalls > 0 {
+			fmt.Printf("Note: %d calls used models without a known price; add them under 'usage.prices' in .vibe.yaml.\n", total.UnpricedCalls)
+		}
+		return nil
+	},
+}
+
+func printUsageRows(by usage.GroupBy, rows []usage.Row) {
+	fmt.Printf("By %s:\n", by)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  "+strings.ToUpper(string(by))+"\tCALLS\tPROMPT\tCOMPLETION\tCOST (USD)")
+	for _, r := range rows {
+		fmt.Fprintf(w, "  %s\t%d\t%d\t%d\t%.4f\n", r.Key, r.Calls, r.PromptTokens, r.CompletionTokens, r.CostUSD)
+	}
+	_ = w.Flush()
+	fmt.Println()
+}
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+	usageCmd.Flags().IntVar(&usageDays, "days", 30, "Number of days to include")
+	usageCmd.Flags().StringVar(&usageBy, "by", "", "Group by a single dimension: day, session or model (default: all)")
+	usageCmd.Flags().BoolVar(&usageProject, "project", false, "Only include usage from the current project")
+	usageCmd.Flags().StringVar(&usageSession, "session", "", "Only include usage from this session")
+}
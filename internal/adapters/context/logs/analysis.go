@@ -0,0 +1,54 @@
+package logs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AnalysisPrompt asks a model to find root causes in lines read from fileName.
+// It includes the detected issues and up to 20 error lines.
+func AnalysisPrompt(fileName string, lines []string) string {
+	var sb strings.Builder
+	sb.WriteString("Analyze the following log file and provide insights:\n\n")
+	sb.WriteString(fmt.Sprintf("File: %s\n", fileName))
+	sb.WriteString(fmt.Sprintf("Lines: %d\n\n", len(lines)))
+
+	// Include issues
+	issues := AnalyzeLines(lines)
+	if len(issues) > 0 {
+		sb.WriteString("Detected issues:\n")
+		for _, issue := range issues {
+			sb.WriteString(fmt.Sprintf("- Line %d: %s (%s)\n", issue.Line, issue.Description, issue.Category))
+		}
+		sb.WriteString("\n")
+	}
+
+	// Include sample of error lines (first 20)
+	errorLines := 0
+	sb.WriteString("Sample error lines:\n")
+	for _, line := range lines {
+		level := DetectLevel(line)
+		if level == LevelError || level == LevelFatal {
+			sb.WriteString("  " + line + "\n")
+			errorLines++
+			if errorLines >= 20 {
+				break
+			}
+		}
+	}
+
+	sb.WriteString("\nPlease analyze root causes and suggest specific remediation steps.")
+	return sb.String()
+}
+
+// FormatName returns the lowercase name of a log format, e.g. "logfmt".
+func FormatName(format LogFormat) string {
+	switch format {
+	case FormatJSON:
+		return "json"
+	case FormatLogfmt:
+		return "logfmt"
+	default:
+		return "plain"
+	}
+}
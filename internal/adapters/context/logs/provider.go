@@ -74,7 +74,7 @@ func (p *Provider) GetContextItems(ctx context.Context, query string, extras por
 	}
 
 	// Read log file
-	lines, err := readLastNLines(absPath, lastN)
+	lines, err := ReadLastLines(absPath, lastN)
 	if err != nil {
 		return nil, err
 	}
@@ -190,7 +190,8 @@ func parseLineLimit(s string) int {
 	return n
 }
 
-func readLastNLines(filePath string, n int) ([]string, error) {
+// ReadLastLines returns the last n lines of a file, or all of them when n <= 0.
+func ReadLastLines(filePath string, n int) ([]string, error) {
 	f, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("cannot open log file: %w", err)
@@ -208,7 +209,7 @@ func readLastNLines(filePath string, n int) ([]string, error) {
 	}
 
 	// Return last N lines
-	if len(lines) <= n {
+	if n <= 0 || len(lines) <= n {
 		return lines, nil
 	}
 	return lines[len(lines)-n:], nil
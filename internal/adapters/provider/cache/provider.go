@@ -0,0 +1,130 @@
+// Package cache decorates a provider with an on-disk response cache.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/provider/stream"
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// Provider serves repeated requests from a Store. Only successful, complete
+// responses are cached; cache hits report zero usage because nothing was spent.
+type Provider struct {
+	ports.Provider
+	store *Store
+	// model is the provider's configured default, used when a request doesn't override it.
+	model  string
+	logger *slog.Logger
+}
+
+// New wraps inner. model is the default model of inner, so that changing it in config invalidates the cache.
+func New(inner ports.Provider, store *Store, model string, logger *slog.Logger) *Provider {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Provider{Provider: inner, store: store, model: model, logger: logger}
+}
+
+func (p *Provider) SupportsToolCalling() bool { return ports.SupportsToolCalling(p.Provider) }
+
+func (p *Provider) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
+	key, model := p.key(req)
+	if e, ok := p.store.get(key); ok {
+		p.logger.DebugContext(ctx, "cache hit", "key", key[:12])
+		return ports.GenerateResponse{Text: e.Text, ToolCalls: e.ToolCalls}, nil
+	}
+
+	resp, err := p.Provider.Generate(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	p.save(ctx, key, model, resp.Text, resp.ToolCalls)
+	return resp, nil
+}
+
+func (p *Provider) StreamGenerate(ctx context.Context, req ports.GenerateRequest) (<-chan ports.StreamChunk, error) {
+	key, model := p.key(req)
+	if e, ok := p.store.get(key); ok {
+		p.logger.DebugContext(ctx, "cache hit (stream)", "key", key[:12])
+		return replay(ctx, e), nil
+	}
+
+	in, err := p.Provider.StreamGenerate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan ports.StreamChunk)
+	go func() {
+		defer close(out)
+		var text strings.Builder
+		var calls []chat.ToolCall
+		failed := false
+		for chunk := range in {
+			if chunk.Error != nil {
+				failed = true
+			}
+			text.WriteString(chunk.Content)
+			calls = append(calls, chunk.ToolCalls...)
+			out <- chunk
+		}
+		if !failed && ctx.Err() == nil {
+			p.save(ctx, key, model, text.String(), calls)
+		}
+	}()
+	return out, nil
+}
+
+func (p *Provider) save(ctx context.Context, key, model, text string, calls []chat.ToolCall) {
+	if text == "" && len(calls) == 0 {
+		return
+	}
+	e := &entry{
+		CreatedAt: p.store.now(),
+		Provider:  p.Name(),
+		Model:     model,
+		Text:      text,
+		ToolCalls: calls,
+	}
+	if err := p.store.put(key, e); err != nil {
+		p.logger.WarnContext(ctx, "cache write failed", "error", err)
+	}
+}
+
+// key hashes everything that changes the model's answer.
+func (p *Provider) key(req ports.GenerateRequest) (string, string) {
+	model := req.Model
+	if model == "" {
+		model = p.model
+	}
+	payload, _ := json.Marshal(struct {
+		Provider string
+		Model    string
+		Prompt   string
+		Messages []chat.Message
+		Sampling ports.Sampling
+		Tools    []ports.ToolSpec
+		Output   *ports.OutputFormat
+	}{p.Name(), model, req.Prompt, req.Messages, req.Sampling, req.Tools, req.Output})
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), model
+}
+
+// replay streams a cached response in small chunks, ending with its tool calls.
+func replay(ctx context.Context, e *entry) <-chan ports.StreamChunk {
+	var last *ports.StreamChunk
+	if len(e.ToolCalls) > 0 {
+		last = &ports.StreamChunk{IsLast: true, ToolCalls: e.ToolCalls}
+	}
+	return stream.Text(ctx, e.Text, last)
+}
+
+var _ ports.Provider = (*Provider)(nil)
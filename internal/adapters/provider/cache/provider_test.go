@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+type countingProvider struct {
+	calls int
+	text  string
+}
+
+func (c *countingProvider) Name() string                           { return "stub" }
+func (c *countingProvider) IsConfigured(ctx context.Context) error { return nil }
+func (c *countingProvider) Close() error                           { return nil }
+
+func (c *countingProvider) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
+	c.calls++
+	return ports.GenerateResponse{Text: c.text, Usage: ports.Usage{PromptTokens: 10, CompletionTokens: 5}}, nil
+}
+
+func (c *countingProvider) StreamGenerate(ctx context.Context, req ports.GenerateRequest) (<-chan ports.StreamChunk, error) {
+	c.calls++
+	ch := make(chan ports.StreamChunk, 2)
+	ch <- ports.StreamChunk{Content: c.text[:3]}
+	ch <- ports.StreamChunk{Content: c.text[3:]}
+	close(ch)
+	return ch, nil
+}
+
+func newTestCache(t *testing.T, inner ports.Provider, ttl time.Duration, maxBytes int64) (*Provider, *Store) {
+	t.Helper()
+	store := NewStore(t.TempDir(), ttl, maxBytes)
+	return New(inner, store, "model-a", slog.New(slog.NewTextHandler(io.Discard, nil))), store
+}
+
+func collect(t *testing.T, ch <-chan ports.StreamChunk) string {
+	t.Helper()
+	var b strings.Builder
+	for chunk := range ch {
+		if chunk.Error != nil {
+			t.Fatalf("unexpected chunk error: %v", chunk.Error)
+		}
+		b.WriteString(chunk.Content)
+	}
+	return b.String()
+}
+
+func TestGenerate_CachesByRequest(t *testing.T) {
+	inner := &countingProvider{text: "analysis"}
+	p, _ := newTestCache(t, inner, time.Hour, 0)
+	ctx := context.Background()
+
+	first, err := p.Generate(ctx, ports.GenerateRequest{Prompt: "diagnose"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := p.Generate(ctx, ports.GenerateRequest{Prompt: "diagnose"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inner.calls != 1 || second.Text != first.Text {
+		t.Fatalf("expected a cache hit, inner calls %d, text %q", inner.calls, second.Text)
+	}
+	if second.Usage.TotalTokens() != 0 {
+		t.Errorf("cache hits must not report usage, got %+v", second.Usage)
+	}
+
+	temp := float32(0.2)
+	for _, req := range []ports.GenerateRequest{
+		{Prompt: "diagnose", Model: "model-b"},
+		{Prompt: "diagnose", Sampling: ports.Sampling{Temperature: &temp}},
+		{Prompt: "other"},
+	} {
+		if _, err := p.Generate(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if inner.calls != 4 {
+		t.Errorf("expected model, sampling and prompt to change the key, inner calls %d", inner.calls)
+	}
+}
+
+func TestGenerate_ExpiresAfterTTL(t *testing.T) {
+	inner := &countingProvider{text: "analysis"}
+	p, store := newTestCache(t, inner, time.Minute, 0)
+	ctx := context.Background()
+
+	_, _ = p.Generate(ctx, ports.GenerateRequest{Prompt: "x"})
+	store.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	_, _ = p.Generate(ctx, ports.GenerateRequest{Prompt: "x"})
+
+	if inner.calls != 2 {
+		t.Errorf("expected expired entry to be refreshed, inner calls %d", inner.calls)
+	}
+}
+
+func TestStreamGenerate_ReplaysCachedText(t *testing.T) {
+	inner := &countingProvider{text: strings.Repeat("streamed text ", 5)}
+	p, _ := newTestCache(t, inner, time.Hour, 0)
+	ctx := context.Background()
+
+	ch, err := p.StreamGenerate(ctx, ports.GenerateRequest{Prompt: "logs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := collect(t, ch)
+
+	ch, err = p.StreamGenerate(ctx, ports.GenerateRequest{Prompt: "logs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chunks int
+	var b strings.Builder
+	for chunk := range ch {
+		chunks++
+		b.WriteString(chunk.Content)
+	}
+
+	if inner.calls != 1 || b.String() != first {
+		t.Fatalf("expected replay of %q, got %q after %d inner calls", first, b.String(), inner.calls)
+	}
+	if chunks < 2 {
+		t.Errorf("expected cached text to be replayed as several chunks, got %d", chunks)
+	}
+}
+
+func TestStore_EvictsOldestOverSizeLimit(t *testing.T) {
+	inner := &countingProvider{text: strings.Repeat("x", 400)}
+	p, store := newTestCache(t, inner, 0, 1000)
+	ctx := context.Background()
+
+	for _, prompt := range []string{"a", "b", "c", "d"} {
+		_, _ = p.Generate(ctx, ports.GenerateRequest{Prompt: prompt})
+	}
+
+	st, err := store.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Bytes > 1000 || st.Entries == 0 {
+		t.Errorf("expected cache to stay under its size limit, got %+v", st)
+	}
+
+	removed, err := store.Clear()
+	if err != nil || removed != st.Entries {
+		t.Errorf("clear removed %d of %d entries (err %v)", removed, st.Entries, err)
+	}
+}
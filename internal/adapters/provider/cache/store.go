@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
+)
+
+// entry is the on-disk representation of one cached response.
+type entry struct {
+	CreatedAt time.Time       `json:"created_at"`
+	Provider  string          `json:"provider"`
+	Model     string          `json:"model"`
+	Text      string          `json:"text"`
+	ToolCalls []chat.ToolCall `json:"tool_calls,omitempty"`
+}
+
+// Store keeps cached responses as <dir>/<key[:2]>/<key>.json.
+type Store struct {
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+	now      func() time.Time
+}
+
+// NewStore creates a store. A zero ttl never expires entries; a zero maxBytes is unbounded.
+func NewStore(dir string, ttl time.Duration, maxBytes int64) *Store {
+	return &Store{dir: dir, ttl: ttl, maxBytes: maxBytes, now: time.Now}
+}
+
+// Stats describes the cache contents.
+type Stats struct {
+	Dir     string
+	Entries int
+	Expired int
+	Bytes   int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+func (s *Store) path(key string) string {
+	return filepath.Join(s.dir, key[:2], key+".json")
+}
+
+func (s *Store) get(key string) (*entry, bool) {
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		_ = os.Remove(s.path(key))
+		return nil, false
+	}
+	if s.expired(e.CreatedAt) {
+		_ = os.Remove(s.path(key))
+		return nil, false
+	}
+	return &e, true
+}
+
+func (s *Store) put(key string, e *entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return s.prune()
+}
+
+func (s *Store) expired(created time.Time) bool {
+	return s.ttl > 0 && s.now().Sub(created) > s.ttl
+}
+
+type fileInfo struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func (s *Store) files() ([]fileInfo, error) {
+	var out []fileInfo
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		out = append(out, fileInfo{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	return out, err
+}
+
+// prune drops expired entries, then the oldest ones until the cache fits maxBytes.
+func (s *Store) prune() error {
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	live := files[:0]
+	for _, f := range files {
+		if s.expired(f.modTime) {
+			_ = os.Remove(f.path)
+			continue
+		}
+		total += f.size
+		live = append(live, f)
+	}
+	if s.maxBytes <= 0 || total <= s.maxBytes {
+		return nil
+	}
+
+	sort.Slice(live, func(i, j int) bool { return live[i].modTime.Before(live[j].modTime) })
+	for _, f := range live {
+		if total <= s.maxBytes {
+			break
+		}
+		if err := os.Remove(f.path); err == nil {
+			total -= f.size
+		}
+	}
+	return nil
+}
+
+// Stats walks the cache directory.
+func (s *Store) Stats() (Stats, error) {
+	st := Stats{Dir: s.dir}
+	files, err := s.files()
+	if err != nil {
+		return st, err
+	}
+	for _, f := range files {
+		st.Entries++
+		st.Bytes += f.size
+		if s.expired(f.modTime) {
+			st.Expired++
+		}
+		if st.Oldest.IsZero() || f.modTime.Before(st.Oldest) {
+			st.Oldest = f.modTime
+		}
+		if f.modTime.After(st.Newest) {
+			st.Newest = f.modTime
+		}
+	}
+	return st, nil
+}
+
+// Clear removes every cached response and returns how many were removed.
+func (s *Store) Clear() (int, error) {
+	files, err := s.files()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, f := range files {
+		if err := os.Remove(f.path); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,266 @@
+// Package failover decorates an ordered list of providers with retry and failover.
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// Policy controls how often a single provider is retried before failing over to the next one.
+type Policy struct {
+	// MaxAttempts is the number of calls per provider, including the first one.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultPolicy returns the retry policy used when `ai.retry` is not configured.
+func DefaultPolicy() Policy {
+	return Policy{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: 30 * time.Second}
+}
+
+// Provider tries each wrapped provider in order. Rate limits, server errors and
+// network failures are retried with exponential backoff and jitter (honouring
+// Retry-After); any other failure moves on to the next provider immediately.
+type Provider struct {
+	providers []ports.Provider
+	policy    Policy
+	logger    *slog.Logger
+
+	sleep  func(ctx context.Context, d time.Duration) error
+	jitter func() float64
+}
+
+// New wraps providers (primary first). Zero policy fields fall back to DefaultPolicy.
+func New(providers []ports.Provider, policy Policy, logger *slog.Logger) *Provider {
+	def := DefaultPolicy()
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = def.MaxAttempts
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = def.InitialBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = def.MaxBackoff
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Provider{
+		providers: providers,
+		policy:    policy,
+		logger:    logger,
+		sleep:     sleepContext,
+		jitter:    rand.Float64,
+	}
+}
+
+// Name returns the primary provider's name.
+func (p *Provider) Name() string {
+	if len(p.providers) == 0 {
+		return "failover"
+	}
+	return p.providers[0].Name()
+}
+
+// IsConfigured succeeds when at least one provider in the chain is usable.
+func (p *Provider) IsConfigured(ctx context.Context) error {
+	var errs []error
+	for _, prov := range p.providers {
+		err := prov.IsConfigured(ctx)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", prov.Name(), err))
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("no providers configured")
+	}
+	return errors.Join(errs...)
+}
+
+// SupportsToolCalling is true only if every provider in the chain supports it,
+// so a failover never silently drops the tools the agent relies on.
+func (p *Provider) SupportsToolCalling() bool {
+	if len(p.providers) == 0 {
+		return false
+	}
+	for _, prov := range p.providers {
+		if !ports.SupportsToolCalling(prov) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Provider) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
+	var resp ports.GenerateResponse
+	err := p.do(ctx, req, func(prov ports.Provider, req ports.GenerateRequest) error {
+		var err error
+		resp, err = prov.Generate(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// StreamGenerate retries only until the first chunk has been delivered;
+// errors after that are passed through because the caller already saw partial output.
+func (p *Provider) StreamGenerate(ctx context.Context, req ports.GenerateRequest) (<-chan ports.StreamChunk, error) {
+	out := make(chan ports.StreamChunk)
+
+	go func() {
+		defer close(out)
+
+		err := p.do(ctx, req, func(prov ports.Provider, req ports.GenerateRequest) error {
+			ch, err := prov.StreamGenerate(ctx, req)
+			if err != nil {
+				return err
+			}
+			first, ok := <-ch
+			if !ok {
+				return nil
+			}
+			if first.Error != nil {
+				drain(ch)
+				return first.Error
+			}
+			if !send(ctx, out, first) {
+				go drain(ch)
+				return ctx.Err()
+			}
+			for chunk := range ch {
+				if !send(ctx, out, chunk) {
+					go drain(ch)
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			send(ctx, out, ports.StreamChunk{Error: err})
+		}
+	}()
+
+	return out, nil
+}
+
+// send delivers chunk unless ctx is cancelled first, so a consumer that stops
+// reading doesn't block the stream forever.
+func send(ctx context.Context, out chan<- ports.StreamChunk, chunk ports.StreamChunk) bool {
+	select {
+	case out <- chunk:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// drain discards the rest of an upstream stream so its producer can finish.
+func drain(ch <-chan ports.StreamChunk) {
+	for range ch {
+	}
+}
+
+func (p *Provider) Close() error {
+	var errs []error
+	for _, prov := range p.providers {
+		if err := prov.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// do runs call against each provider in order until one succeeds.
+func (p *Provider) do(ctx context.Context, req ports.GenerateRequest, call func(ports.Provider, ports.GenerateRequest) error) error {
+	if len(p.providers) == 0 {
+		return fmt.Errorf("no providers configured")
+	}
+
+	var errs []error
+	for i, prov := range p.providers {
+		provReq := req
+		if i > 0 {
+			// A model override names a model of the primary provider.
+			provReq.Model = ""
+		}
+		hasNext := i+1 < len(p.providers)
+
+		for attempt := 1; ; attempt++ {
+			err := call(prov, provReq)
+			if err == nil {
+				p.logger.InfoContext(ctx, "provider answered", "provider", prov.Name(), "attempt", attempt, "fallback", i > 0)
+				return nil
+			}
+			if ctx.Err() != nil || errors.Is(err, ports.ErrToolsUnsupported) {
+				// Cancellation and capability errors are for the caller to handle.
+				return err
+			}
+
+			delay, retry := p.backoff(err, attempt, hasNext)
+			if !retry {
+				errs = append(errs, fmt.Errorf("%s: %w", prov.Name(), err))
+				break
+			}
+			p.logger.WarnContext(ctx, "provider call failed, retrying", "provider", prov.Name(), "attempt", attempt, "delay", delay, "error", err)
+			if err := p.sleep(ctx, delay); err != nil {
+				return err
+			}
+		}
+
+		if hasNext {
+			p.logger.WarnContext(ctx, "provider failed, failing over", "provider", prov.Name(), "next", p.providers[i+1].Name())
+		}
+	}
+
+	if len(errs) == 1 {
+		return errors.Unwrap(errs[0])
+	}
+	return fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+}
+
+// backoff decides whether to retry the same provider and how long to wait first.
+func (p *Provider) backoff(err error, attempt int, hasNext bool) (time.Duration, bool) {
+	var pe *ports.ProviderError
+	if !errors.As(err, &pe) || !pe.Retryable() || attempt >= p.policy.MaxAttempts {
+		return 0, false
+	}
+
+	if pe.RetryAfter > 0 {
+		if pe.RetryAfter > p.policy.MaxBackoff && hasNext {
+			// Waiting longer than we are willing to back off; the next provider is a better bet.
+			return 0, false
+		}
+		return pe.RetryAfter, true
+	}
+
+	d := p.policy.InitialBackoff
+	for i := 1; i < attempt && d < p.policy.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.policy.MaxBackoff {
+		d = p.policy.MaxBackoff
+	}
+	// Equal jitter: keep at least half the delay so throttled APIs get real relief.
+	half := d / 2
+	return half + time.Duration(p.jitter()*float64(half)), true
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+var _ ports.Provider = (*Provider)(nil)
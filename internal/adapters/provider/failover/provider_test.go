@@ -0,0 +1,218 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+type stubProvider struct {
+	name  string
+	errs  []error
+	calls int
+	text  string
+}
+
+func (s *stubProvider) Name() string                           { return s.name }
+func (s *stubProvider) IsConfigured(ctx context.Context) error { return nil }
+func (s *stubProvider) Close() error                           { return nil }
+
+func (s *stubProvider) next() error {
+	s.calls++
+	if s.calls <= len(s.errs) {
+		return s.errs[s.calls-1]
+	}
+	return nil
+}
+
+func (s *stubProvider) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
+	if err := s.next(); err != nil {
+		return ports.GenerateResponse{}, err
+	}
+	return ports.GenerateResponse{Text: s.text}, nil
+}
+
+func (s *stubProvider) StreamGenerate(ctx context.Context, req ports.GenerateRequest) (<-chan ports.StreamChunk, error) {
+	err := s.next()
+	ch := make(chan ports.StreamChunk, 2)
+	if err != nil {
+		ch <- ports.StreamChunk{Error: err}
+	} else {
+		ch <- ports.StreamChunk{Content: s.text}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func status(code int, retryAfter time.Duration) error {
+	return &ports.ProviderError{Provider: "stub", StatusCode: code, RetryAfter: retryAfter, Err: fmt.Errorf("status %d", code)}
+}
+
+func newTestProvider(providers ...ports.Provider) (*Provider, *[]time.Duration) {
+	p := New(providers, Policy{MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	var slept []time.Duration
+	p.sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	p.jitter = func() float64 { return 1 }
+	return p, &slept
+}
+
+func TestGenerate_RetriesWithBackoff(t *testing.T) {
+	primary := &stubProvider{name: "gemini", text: "ok", errs: []error{status(503, 0), status(429, 0)}}
+	p, slept := newTestProvider(primary)
+
+	resp, err := p.Generate(context.Background(), ports.GenerateRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Text != "ok" || primary.calls != 3 {
+		t.Fatalf("unexpected result %q after %d calls", resp.Text, primary.calls)
+	}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}
+	if fmt.Sprint(*slept) != fmt.Sprint(want) {
+		t.Errorf("unexpected backoff: %v, want %v", *slept, want)
+	}
+}
+
+func TestGenerate_HonoursRetryAfter(t *testing.T) {
+	primary := &stubProvider{name: "gemini", text: "ok", errs: []error{status(429, 700*time.Millisecond)}}
+	p, slept := newTestProvider(primary)
+
+	if _, err := p.Generate(context.Background(), ports.GenerateRequest{Prompt: "hi"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*slept) != 1 || (*slept)[0] != 700*time.Millisecond {
+		t.Errorf("expected Retry-After delay, got %v", *slept)
+	}
+}
+
+func TestGenerate_FailsOverAfterRetries(t *testing.T) {
+	primary := &stubProvider{name: "gemini", errs: []error{status(503, 0), status(503, 0), status(503, 0)}}
+	fallback := &stubProvider{name: "ollama", text: "local"}
+	p, _ := newTestProvider(primary, fallback)
+
+	resp, err := p.Generate(context.Background(), ports.GenerateRequest{Prompt: "hi", Model: "gemini-1.5-pro"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Text != "local" || primary.calls != 3 || fallback.calls != 1 {
+		t.Errorf("unexpected result %q (primary %d calls, fallback %d)", resp.Text, primary.calls, fallback.calls)
+	}
+}
+
+func TestGenerate_LongRetryAfterFailsOverImmediately(t *testing.T) {
+	primary := &stubProvider{name: "gemini", errs: []error{status(429, time.Minute)}}
+	fallback := &stubProvider{name: "openai", text: "ok"}
+	p, slept := newTestProvider(primary, fallback)
+
+	if _, err := p.Generate(context.Background(), ports.GenerateRequest{Prompt: "hi"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if primary.calls != 1 || len(*slept) != 0 {
+		t.Errorf("expected immediate failover, primary calls %d, slept %v", primary.calls, *slept)
+	}
+}
+
+func TestGenerate_NonRetryableSkipsRetries(t *testing.T) {
+	primary := &stubProvider{name: "openai", errs: []error{status(401, 0)}}
+	p, _ := newTestProvider(primary)
+
+	_, err := p.Generate(context.Background(), ports.GenerateRequest{Prompt: "hi"})
+	var pe *ports.ProviderError
+	if !errors.As(err, &pe) || pe.StatusCode != 401 {
+		t.Fatalf("expected original provider error, got %v", err)
+	}
+	if primary.calls != 1 {
+		t.Errorf("expected no retries for 401, got %d calls", primary.calls)
+	}
+}
+
+func TestGenerate_AllProvidersFail(t *testing.T) {
+	p, _ := newTestProvider(
+		&stubProvider{name: "gemini", errs: []error{status(400, 0)}},
+		&stubProvider{name: "ollama", errs: []error{errors.New("connection refused")}},
+	)
+
+	_, err := p.Generate(context.Background(), ports.GenerateRequest{Prompt: "hi"})
+	if err == nil || !strings.Contains(err.Error(), "all providers failed") || !strings.Contains(err.Error(), "ollama: connection refused") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestStreamGenerate_FailsOverBeforeFirstChunk(t *testing.T) {
+	primary := &stubProvider{name: "gemini", errs: []error{status(500, 0), status(500, 0), status(500, 0)}}
+	fallback := &stubProvider{name: "openai", text: "streamed"}
+	p, _ := newTestProvider(primary, fallback)
+
+	ch, err := p.StreamGenerate(context.Background(), ports.GenerateRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got strings.Builder
+	for chunk := range ch {
+		if chunk.Error != nil {
+			t.Fatalf("unexpected chunk error: %v", chunk.Error)
+		}
+		got.WriteString(chunk.Content)
+	}
+	if got.String() != "streamed" {
+		t.Errorf("unexpected stream content: %q", got.String())
+	}
+}
+
+// endlessProvider streams chunks until its context is cancelled.
+type endlessProvider struct {
+	stubProvider
+	stopped chan struct{}
+}
+
+func (e *endlessProvider) StreamGenerate(ctx context.Context, req ports.GenerateRequest) (<-chan ports.StreamChunk, error) {
+	ch := make(chan ports.StreamChunk)
+	go func() {
+		defer close(e.stopped)
+		defer close(ch)
+		for {
+			select {
+			case ch <- ports.StreamChunk{Content: "x"}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func TestStreamGenerate_StopsWhenConsumerCancels(t *testing.T) {
+	upstream := &endlessProvider{stubProvider: stubProvider{name: "gemini"}, stopped: make(chan struct{})}
+	p, _ := newTestProvider(upstream)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := p.StreamGenerate(ctx, ports.GenerateRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ch
+	cancel() // stop reading without draining
+
+	select {
+	case <-upstream.stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("upstream stream kept running after cancellation")
+	}
+	// The chain's goroutine must give up its pending send rather than wait for
+	// a reader; reading now finds the stream closed.
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := <-ch; ok {
+		t.Error("expected the stream to be closed after cancellation")
+	}
+}
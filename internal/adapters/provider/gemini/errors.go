@@ -0,0 +1,36 @@
+package gemini
+
+import (
+	"errors"
+	"time"
+
+	"github.com/googleapis/gax-go/v2/apierror"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+	"google.golang.org/api/googleapi"
+)
+
+// wrapError classifies Google API errors as ports.ProviderError.
+// Gemini reports throttling delays either as a Retry-After header or as RetryInfo details.
+func (p *Provider) wrapError(err error) error {
+	pe := &ports.ProviderError{Provider: p.Name(), Err: err}
+
+	var gErr *googleapi.Error
+	if errors.As(err, &gErr) {
+		pe.StatusCode = gErr.Code
+		pe.RetryAfter = ports.ParseRetryAfter(gErr.Header.Get("Retry-After"), time.Now())
+	}
+
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) {
+		if pe.StatusCode == 0 {
+			pe.StatusCode = apiErr.HTTPCode()
+		}
+		if info := apiErr.Details().RetryInfo; info != nil && pe.RetryAfter == 0 {
+			pe.RetryAfter = info.GetRetryDelay().AsDuration()
+		}
+	}
+	if pe.StatusCode < 0 {
+		pe.StatusCode = 0
+	}
+	return pe
+}
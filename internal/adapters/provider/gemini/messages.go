@@ -0,0 +1,75 @@
+package gemini
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// toContents maps a request onto Gemini's conversation model. System messages
+// become the system instruction, assistant turns use the "model" role, and
+// tool results are sent as FunctionResponse parts of a user turn. Consecutive
+// turns with the same role are merged, since Gemini expects them to alternate.
+func toContents(req ports.GenerateRequest) (*genai.Content, []*genai.Content) {
+	if len(req.Messages) == 0 {
+		prompt := strings.TrimSpace(req.Prompt)
+		if prompt == "" {
+			return nil, nil
+		}
+		return nil, []*genai.Content{genai.NewUserContent(genai.Text(prompt))}
+	}
+
+	var system []string
+	var contents []*genai.Content
+	add := func(role string, parts ...genai.Part) {
+		if len(parts) == 0 {
+			return
+		}
+		if n := len(contents); n > 0 && contents[n-1].Role == role {
+			contents[n-1].Parts = append(contents[n-1].Parts, parts...)
+			return
+		}
+		contents = append(contents, &genai.Content{Role: role, Parts: parts})
+	}
+
+	for _, m := range req.Messages {
+		switch m.Role {
+		case chat.RoleSystem:
+			if s := strings.TrimSpace(m.Content); s != "" {
+				system = append(system, s)
+			}
+		case chat.RoleAssistant:
+			var parts []genai.Part
+			if strings.TrimSpace(m.Content) != "" {
+				parts = append(parts, genai.Text(m.Content))
+			}
+			for _, c := range m.ToolCalls {
+				parts = append(parts, genai.FunctionCall{Name: c.Name, Args: argsMap(c.Arguments)})
+			}
+			add("model", parts...)
+		case chat.RoleTool:
+			add("user", genai.FunctionResponse{Name: m.Name, Response: map[string]any{"content": m.Content}})
+		default:
+			if strings.TrimSpace(m.Content) != "" {
+				add("user", genai.Text(m.Content))
+			}
+		}
+	}
+
+	var instruction *genai.Content
+	if len(system) > 0 {
+		instruction = &genai.Content{Parts: []genai.Part{genai.Text(strings.Join(system, "\n\n"))}}
+	}
+	return instruction, contents
+}
+
+func argsMap(raw json.RawMessage) map[string]any {
+	args := map[string]any{}
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &args)
+	}
+	return args
+}
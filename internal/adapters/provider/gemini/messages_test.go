@@ -0,0 +1,54 @@
+package gemini
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+func TestToContents_MultiTurn(t *testing.T) {
+	system, contents := toContents(ports.GenerateRequest{Messages: []chat.Message{
+		{Role: chat.RoleSystem, Content: "You are Vibe."},
+		{Role: chat.RoleUser, Content: "Task: list files"},
+		{Role: chat.RoleAssistant, ToolCalls: []chat.ToolCall{{ID: "call_0", Name: "list_dir", Arguments: json.RawMessage(`{"path":"."}`)}}},
+		{Role: chat.RoleTool, ToolCallID: "call_0", Name: "list_dir", Content: "- [file] go.mod"},
+		{Role: chat.RoleUser, Content: "EXEC_RESULT: exit_code=0"},
+	}})
+
+	if system == nil || system.Parts[0] != genai.Text("You are Vibe.") {
+		t.Fatalf("expected the system message as system instruction, got %+v", system)
+	}
+	if len(contents) != 3 {
+		t.Fatalf("expected user, model, user turns, got %d", len(contents))
+	}
+	if contents[0].Role != "user" || contents[1].Role != "model" || contents[2].Role != "user" {
+		t.Errorf("unexpected roles: %s, %s, %s", contents[0].Role, contents[1].Role, contents[2].Role)
+	}
+
+	call, ok := contents[1].Parts[0].(genai.FunctionCall)
+	if !ok || call.Name != "list_dir" || call.Args["path"] != "." {
+		t.Errorf("unexpected function call part: %#v", contents[1].Parts[0])
+	}
+
+	// The tool result and the following user text share one user turn.
+	if len(contents[2].Parts) != 2 {
+		t.Fatalf("expected merged user turn, got %d parts", len(contents[2].Parts))
+	}
+	resp, ok := contents[2].Parts[0].(genai.FunctionResponse)
+	if !ok || resp.Name != "list_dir" || resp.Response["content"] != "- [file] go.mod" {
+		t.Errorf("unexpected function response part: %#v", contents[2].Parts[0])
+	}
+}
+
+func TestToContents_Prompt(t *testing.T) {
+	system, contents := toContents(ports.GenerateRequest{Prompt: "  hello "})
+	if system != nil || len(contents) != 1 || contents[0].Parts[0] != genai.Text("hello") {
+		t.Errorf("unexpected conversion: %+v %+v", system, contents)
+	}
+	if _, contents := toContents(ports.GenerateRequest{}); len(contents) != 0 {
+		t.Errorf("expected no contents for an empty request")
+	}
+}
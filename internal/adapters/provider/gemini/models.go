@@ -0,0 +1,49 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+	"google.golang.org/api/iterator"
+)
+
+// ListModels lists the models that support generateContent, with their input
+// token limits. All of them take function declarations; JSON mode and image
+// input are available from Gemini 1.5 on.
+func (p *Provider) ListModels(ctx context.Context) ([]ports.ModelInfo, error) {
+	var models []ports.ModelInfo
+	it := p.client.ListModels(ctx)
+	for {
+		m, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list gemini models: %w", p.wrapError(err))
+		}
+		if !slices.Contains(m.SupportedGenerationMethods, "generateContent") {
+			continue
+		}
+		models = append(models, modelInfo(m))
+	}
+	return models, nil
+}
+
+func modelInfo(m *genai.ModelInfo) ports.ModelInfo {
+	name := strings.TrimPrefix(m.Name, "models/")
+	legacy := strings.HasPrefix(name, "gemini-1.0") || strings.HasPrefix(name, "gemini-pro")
+	return ports.ModelInfo{
+		Name:          name,
+		ContextWindow: int(m.InputTokenLimit),
+		ToolCalling:   strings.HasPrefix(name, "gemini"),
+		JSONMode:      strings.HasPrefix(name, "gemini") && !legacy,
+		Vision:        strings.HasPrefix(name, "gemini") && (!legacy || strings.Contains(name, "vision")),
+		Details:       m.DisplayName,
+	}
+}
+
+var _ ports.ModelLister = (*Provider)(nil)
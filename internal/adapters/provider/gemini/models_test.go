@@ -0,0 +1,19 @@
+package gemini
+
+import (
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+func TestModelInfo(t *testing.T) {
+	flash := modelInfo(&genai.ModelInfo{Name: "models/gemini-1.5-flash", DisplayName: "Gemini 1.5 Flash", InputTokenLimit: 1048576})
+	if flash.Name != "gemini-1.5-flash" || flash.ContextWindow != 1048576 || !flash.ToolCalling || !flash.JSONMode || !flash.Vision {
+		t.Errorf("unexpected info: %+v", flash)
+	}
+
+	legacy := modelInfo(&genai.ModelInfo{Name: "models/gemini-pro", InputTokenLimit: 30720})
+	if !legacy.ToolCalling || legacy.JSONMode || legacy.Vision {
+		t.Errorf("unexpected info for gemini-pro: %+v", legacy)
+	}
+}
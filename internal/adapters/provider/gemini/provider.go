@@ -2,10 +2,13 @@ package gemini
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/google/generative-ai-go/genai"
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
 	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
@@ -18,10 +21,10 @@ type Provider struct {
 	model  string
 
 	client *genai.Client
-	gm     *genai.GenerativeModel
 }
 
-func New(apiKey, model string) (*Provider, error) {
+// New creates a Gemini provider; a nil httpClient uses the SDK's default transport.
+func New(apiKey, model string, httpClient *http.Client) (*Provider, error) {
 	p := &Provider{apiKey: apiKey, model: model}
 	if err := p.IsConfigured(context.Background()); err != nil {
 		return nil, err
@@ -30,13 +33,20 @@ func New(apiKey, model string) (*Provider, error) {
 		return nil, fmt.Errorf("gemini model is not configured")
 	}
 
-	client, err := genai.NewClient(context.Background(), option.WithAPIKey(apiKey))
+	opts := []option.ClientOption{option.WithAPIKey(apiKey)}
+	if httpClient != nil {
+		// A custom client replaces the SDK's transport, API key included.
+		opts = append(opts, option.WithHTTPClient(&http.Client{
+			Transport: &apiKeyTransport{base: httpClient.Transport, apiKey: apiKey},
+			Timeout:   httpClient.Timeout,
+		}))
+	}
+	client, err := genai.NewClient(context.Background(), opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create genai client: %w", err)
 	}
 
 	p.client = client
-	p.gm = client.GenerativeModel(model)
 	return p, nil
 }
 
@@ -49,77 +59,68 @@ func (p *Provider) IsConfigured(ctx context.Context) error {
 	return nil
 }
 
-func (p *Provider) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
-	prompt := strings.TrimSpace(req.Prompt)
-	if prompt == "" && len(req.Messages) > 0 {
-		var b strings.Builder
-		for _, m := range req.Messages {
-			b.WriteString(string(m.Role))
-			b.WriteString(": ")
-			b.WriteString(m.Content)
-			b.WriteString("\n")
-		}
-		prompt = strings.TrimSpace(b.String())
-	}
+func (p *Provider) SupportsToolCalling() bool { return true }
 
-	if prompt == "" {
-		return ports.GenerateResponse{}, fmt.Errorf("empty prompt")
+func (p *Provider) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
+	cs, last, err := p.chatFor(req)
+	if err != nil {
+		return ports.GenerateResponse{}, err
 	}
 
-	// support overriding model/temperature if needed, but for now stick to defaults or configured
-
-	resp, err := p.gm.GenerateContent(ctx, genai.Text(prompt))
+	resp, err := cs.SendMessage(ctx, last...)
 	if err != nil {
-		return ports.GenerateResponse{}, fmt.Errorf("failed to get completion from gemini: %w", err)
+		return ports.GenerateResponse{}, fmt.Errorf("failed to get completion from gemini: %w", p.wrapError(err))
 	}
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
 		return ports.GenerateResponse{}, fmt.Errorf("gemini returned no content")
 	}
 
-	if txt, ok := resp.Candidates[0].Content.Parts[0].(genai.Text); ok {
-		return ports.GenerateResponse{Text: string(txt)}, nil
+	text, calls := splitParts(resp.Candidates[0].Content.Parts, 0)
+	if text == "" && len(calls) == 0 {
+		return ports.GenerateResponse{}, fmt.Errorf("gemini response was not text")
 	}
-
-	return ports.GenerateResponse{}, fmt.Errorf("gemini response was not text")
+	return ports.GenerateResponse{Text: text, ToolCalls: calls, Usage: p.usage(p.modelName(req), resp.UsageMetadata)}, nil
 }
 
 func (p *Provider) StreamGenerate(ctx context.Context, req ports.GenerateRequest) (<-chan ports.StreamChunk, error) {
-	prompt := strings.TrimSpace(req.Prompt)
-	if prompt == "" && len(req.Messages) > 0 {
-		var b strings.Builder
-		for _, m := range req.Messages {
-			b.WriteString(string(m.Role))
-			b.WriteString(": ")
-			b.WriteString(m.Content)
-			b.WriteString("\n")
-		}
-		prompt = strings.TrimSpace(b.String())
-	}
-
-	if prompt == "" {
-		return nil, fmt.Errorf("empty prompt")
+	cs, last, err := p.chatFor(req)
+	if err != nil {
+		return nil, err
 	}
 
 	ch := make(chan ports.StreamChunk)
 
-	iter := p.gm.GenerateContentStream(ctx, genai.Text(prompt))
+	iter := cs.SendMessageStream(ctx, last...)
 
 	go func() {
 		defer close(ch)
+		var calls []chat.ToolCall
+		var usage *ports.Usage
 		for {
 			resp, err := iter.Next()
 			if err != nil {
 				if err == iterator.Done {
+					if len(calls) > 0 || usage != nil {
+						ch <- ports.StreamChunk{IsLast: true, ToolCalls: calls, Usage: usage}
+					}
 					return
 				}
-				ch <- ports.StreamChunk{Error: err}
+				ch <- ports.StreamChunk{Error: p.wrapError(err)}
 				return
 			}
 
-			if len(resp.Candidates) > 0 && len(resp.Candidates[0].Content.Parts) > 0 {
-				if txt, ok := resp.Candidates[0].Content.Parts[0].(genai.Text); ok {
-					ch <- ports.StreamChunk{Content: string(txt)}
+			// Usage metadata is cumulative; the last chunk carries the totals.
+			if resp.UsageMetadata != nil {
+				u := p.usage(p.modelName(req), resp.UsageMetadata)
+				usage = &u
+			}
+
+			if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
+				text, more := splitParts(resp.Candidates[0].Content.Parts, len(calls))
+				calls = append(calls, more...)
+				if text != "" {
+					ch <- ports.StreamChunk{Content: text}
 				}
 			}
 		}
@@ -135,4 +136,123 @@ func (p *Provider) Close() error {
 	return p.client.Close()
 }
 
+// modelFor builds a per-request model handle so that tools and overrides
+// never leak between concurrent calls.
+func (p *Provider) modelFor(req ports.GenerateRequest) (*genai.GenerativeModel, error) {
+	gm := p.client.GenerativeModel(p.modelName(req))
+	gm.Temperature = req.Temperature
+	gm.TopP = req.TopP
+	if req.MaxTokens > 0 {
+		gm.SetMaxOutputTokens(int32(req.MaxTokens))
+	}
+	gm.StopSequences = req.Stop // Gemini has no seed parameter.
+
+	if len(req.Tools) > 0 {
+		decls := make([]*genai.FunctionDeclaration, 0, len(req.Tools))
+		for _, t := range req.Tools {
+			params, err := toGenaiSchema(t.Parameters)
+			if err != nil {
+				return nil, fmt.Errorf("tool %s: %w", t.Name, err)
+			}
+			if params != nil && params.Type == genai.TypeObject && len(params.Properties) == 0 {
+				params = nil // Gemini rejects empty OBJECT schemas; omit parameters instead.
+			}
+			decls = append(decls, &genai.FunctionDeclaration{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  params,
+			})
+		}
+		gm.Tools = []*genai.Tool{{FunctionDeclarations: decls}}
+	}
+
+	// Gemini can't combine function calling with a JSON response type.
+	if req.Output != nil && len(req.Tools) == 0 {
+		gm.ResponseMIMEType = "application/json"
+		schema, err := toGenaiSchema(req.Output.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("output schema: %w", err)
+		}
+		// Free-form objects can't be expressed; fall back to plain JSON mode.
+		if !hasOpenObject(schema) {
+			gm.ResponseSchema = schema
+		}
+	}
+
+	return gm, nil
+}
+
+// chatFor starts a chat session holding every turn but the last, which the caller sends.
+func (p *Provider) chatFor(req ports.GenerateRequest) (*genai.ChatSession, []genai.Part, error) {
+	system, contents := toContents(req)
+	if len(contents) == 0 {
+		return nil, nil, fmt.Errorf("empty prompt")
+	}
+
+	gm, err := p.modelFor(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	gm.SystemInstruction = system
+
+	cs := gm.StartChat()
+	cs.History = contents[:len(contents)-1]
+	return cs, contents[len(contents)-1].Parts, nil
+}
+
+func (p *Provider) modelName(req ports.GenerateRequest) string {
+	if req.Model != "" {
+		return req.Model
+	}
+	return p.model
+}
+
+func (p *Provider) usage(model string, md *genai.UsageMetadata) ports.Usage {
+	u := ports.Usage{Provider: p.Name(), Model: strings.TrimPrefix(model, "models/")}
+	if md != nil {
+		u.PromptTokens = int(md.PromptTokenCount)
+		u.CompletionTokens = int(md.CandidatesTokenCount)
+	}
+	return u
+}
+
+// splitParts separates text from function calls; offset keeps synthesised IDs unique across stream chunks.
+func splitParts(parts []genai.Part, offset int) (string, []chat.ToolCall) {
+	var text strings.Builder
+	var calls []chat.ToolCall
+	for _, part := range parts {
+		switch v := part.(type) {
+		case genai.Text:
+			text.WriteString(string(v))
+		case genai.FunctionCall:
+			args, err := json.Marshal(v.Args)
+			if err != nil || v.Args == nil {
+				args = []byte(`{}`)
+			}
+			calls = append(calls, chat.ToolCall{
+				ID:        fmt.Sprintf("call_%d", offset+len(calls)),
+				Name:      v.Name,
+				Arguments: args,
+			})
+		}
+	}
+	return text.String(), calls
+}
+
+// apiKeyTransport authenticates requests made through a custom HTTP client.
+type apiKeyTransport struct {
+	base   http.RoundTripper
+	apiKey string
+}
+
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("x-goog-api-key", t.apiKey)
+	return base.RoundTrip(req)
+}
+
 var _ ports.Provider = (*Provider)(nil)
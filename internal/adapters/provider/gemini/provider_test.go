@@ -0,0 +1,25 @@
+package gemini
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIKeyTransport(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("x-goog-api-key")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &apiKeyTransport{apiKey: "secret"}}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got != "secret" {
+		t.Errorf("expected the API key header, got %q", got)
+	}
+}
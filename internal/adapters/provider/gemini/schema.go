@@ -0,0 +1,130 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// jsonSchema is the subset of JSON Schema that Gemini's OpenAPI-style Schema understands.
+type jsonSchema struct {
+	Type        any                    `json:"type"`
+	Description string                 `json:"description"`
+	Format      string                 `json:"format"`
+	Enum        []any                  `json:"enum"`
+	Items       *jsonSchema            `json:"items"`
+	Properties  map[string]*jsonSchema `json:"properties"`
+	Required    []string               `json:"required"`
+	Nullable    bool                   `json:"nullable"`
+}
+
+// toGenaiSchema converts a JSON Schema document into a genai.Schema.
+// Unsupported keywords are dropped; the result is only as strict as Gemini allows.
+func toGenaiSchema(raw json.RawMessage) (*genai.Schema, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var js jsonSchema
+	if err := json.Unmarshal(raw, &js); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	return convertSchema(&js)
+}
+
+func convertSchema(js *jsonSchema) (*genai.Schema, error) {
+	if js == nil {
+		return nil, nil
+	}
+
+	typeName, nullable := schemaType(js.Type)
+	out := &genai.Schema{
+		Description: js.Description,
+		Format:      js.Format,
+		Nullable:    js.Nullable || nullable,
+		Required:    js.Required,
+	}
+
+	switch typeName {
+	case "string":
+		out.Type = genai.TypeString
+	case "number":
+		out.Type = genai.TypeNumber
+	case "integer":
+		out.Type = genai.TypeInteger
+	case "boolean":
+		out.Type = genai.TypeBoolean
+	case "array":
+		out.Type = genai.TypeArray
+	case "object", "":
+		out.Type = genai.TypeObject
+	default:
+		return nil, fmt.Errorf("unsupported schema type: %s", typeName)
+	}
+
+	for _, e := range js.Enum {
+		out.Enum = append(out.Enum, fmt.Sprint(e))
+	}
+
+	if js.Items != nil {
+		items, err := convertSchema(js.Items)
+		if err != nil {
+			return nil, err
+		}
+		out.Items = items
+	}
+
+	if len(js.Properties) > 0 {
+		out.Properties = make(map[string]*genai.Schema, len(js.Properties))
+		for name, prop := range js.Properties {
+			ps, err := convertSchema(prop)
+			if err != nil {
+				return nil, fmt.Errorf("property %s: %w", name, err)
+			}
+			out.Properties[name] = ps
+		}
+	}
+
+	return out, nil
+}
+
+// hasOpenObject reports whether s contains an OBJECT without properties, which
+// Gemini accepts for function parameters but rejects in a response schema.
+func hasOpenObject(s *genai.Schema) bool {
+	if s == nil {
+		return false
+	}
+	if s.Type == genai.TypeObject && len(s.Properties) == 0 {
+		return true
+	}
+	if hasOpenObject(s.Items) {
+		return true
+	}
+	for _, p := range s.Properties {
+		if hasOpenObject(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaType normalises `"type": "x"` and `"type": ["x", "null"]`.
+func schemaType(v any) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, false
+	case []any:
+		name, nullable := "", false
+		for _, item := range t {
+			s, _ := item.(string)
+			if s == "null" {
+				nullable = true
+			} else if name == "" {
+				name = s
+			}
+		}
+		return name, nullable
+	default:
+		return "", false
+	}
+}
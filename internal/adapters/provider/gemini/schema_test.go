@@ -0,0 +1,61 @@
+package gemini
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+func TestToGenaiSchema(t *testing.T) {
+	raw := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {"type": "string", "description": "File path"},
+			"lines": {"type": ["integer", "null"]},
+			"mode": {"type": "string", "enum": ["head", "tail"]},
+			"globs": {"type": "array", "items": {"type": "string"}}
+		},
+		"required": ["path"]
+	}`)
+
+	s, err := toGenaiSchema(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Type != genai.TypeObject || len(s.Required) != 1 || s.Required[0] != "path" {
+		t.Fatalf("unexpected root schema: %+v", s)
+	}
+	if p := s.Properties["path"]; p.Type != genai.TypeString || p.Description != "File path" {
+		t.Errorf("unexpected path schema: %+v", p)
+	}
+	if p := s.Properties["lines"]; p.Type != genai.TypeInteger || !p.Nullable {
+		t.Errorf("unexpected lines schema: %+v", p)
+	}
+	if p := s.Properties["mode"]; len(p.Enum) != 2 || p.Enum[1] != "tail" {
+		t.Errorf("unexpected mode schema: %+v", p)
+	}
+	if p := s.Properties["globs"]; p.Type != genai.TypeArray || p.Items == nil || p.Items.Type != genai.TypeString {
+		t.Errorf("unexpected globs schema: %+v", p)
+	}
+}
+
+func TestToGenaiSchema_Invalid(t *testing.T) {
+	if _, err := toGenaiSchema(json.RawMessage(`{"type":"tuple"}`)); err == nil {
+		t.Error("expected error for unsupported type")
+	}
+	if s, err := toGenaiSchema(nil); err != nil || s != nil {
+		t.Errorf("expected nil schema for empty input, got %+v, %v", s, err)
+	}
+}
+
+func TestHasOpenObject(t *testing.T) {
+	closed, _ := toGenaiSchema(json.RawMessage(`{"type":"object","properties":{"a":{"type":"string"}}}`))
+	open, _ := toGenaiSchema(json.RawMessage(`{"type":"object","properties":{"input":{"type":"object"}}}`))
+	if hasOpenObject(closed) {
+		t.Error("expected a fully specified schema to be usable as response schema")
+	}
+	if !hasOpenObject(open) {
+		t.Error("expected a nested free-form object to be detected")
+	}
+}
@@ -0,0 +1,122 @@
+// Package httpclient builds the HTTP client shared by the provider adapters:
+// proxy, extra CAs, client certificates and timeouts.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Options configures the client. Zero values keep Go's defaults.
+type Options struct {
+	// Proxy is used for all requests; empty falls back to HTTPS_PROXY / HTTP_PROXY / NO_PROXY.
+	Proxy string
+	// NoProxy lists hosts, domains (".corp.example") and CIDRs that bypass Proxy.
+	NoProxy []string
+	// CABundle is a PEM file of CAs trusted in addition to the system pool.
+	CABundle string
+	// ClientCert and ClientKey are PEM files presented for mutual TLS.
+	ClientCert string
+	ClientKey  string
+	// ConnectTimeout bounds TCP connect plus TLS handshake.
+	ConnectTimeout time.Duration
+	// ReadTimeout fails a request when the server sends nothing for this long,
+	// while waiting for headers or between body reads, so streams stay usable.
+	ReadTimeout time.Duration
+}
+
+// IsZero reports whether no option is set.
+func (o Options) IsZero() bool {
+	return o.Proxy == "" && len(o.NoProxy) == 0 && o.CABundle == "" && o.ClientCert == "" &&
+		o.ClientKey == "" && o.ConnectTimeout == 0 && o.ReadTimeout == 0
+}
+
+// New returns a client for opts. It returns nil when opts is zero, so that
+// each adapter keeps its SDK's default client.
+func New(opts Options) (*http.Client, error) {
+	if opts.IsZero() {
+		return nil, nil
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.Proxy != "" || len(opts.NoProxy) > 0 {
+		var proxyConfig *httpproxy.Config
+		if opts.Proxy != "" {
+			if _, err := url.Parse(opts.Proxy); err != nil {
+				return nil, fmt.Errorf("invalid proxy URL: %w", err)
+			}
+			proxyConfig = &httpproxy.Config{
+				HTTPProxy:  opts.Proxy,
+				HTTPSProxy: opts.Proxy,
+				NoProxy:    strings.Join(opts.NoProxy, ","),
+			}
+		} else {
+			// The proxy comes from the environment; NoProxy extends its NO_PROXY.
+			proxyConfig = httpproxy.FromEnvironment()
+			proxyConfig.NoProxy = strings.Join(append([]string{proxyConfig.NoProxy}, opts.NoProxy...), ",")
+		}
+		proxy := proxyConfig.ProxyFunc()
+		t.Proxy = func(req *http.Request) (*url.URL, error) { return proxy(req.URL) }
+	}
+
+	if opts.CABundle != "" || opts.ClientCert != "" || opts.ClientKey != "" {
+		tlsConfig, err := tlsConfig(opts)
+		if err != nil {
+			return nil, err
+		}
+		t.TLSClientConfig = tlsConfig
+	}
+
+	if opts.ConnectTimeout > 0 {
+		dialer := &net.Dialer{Timeout: opts.ConnectTimeout, KeepAlive: 30 * time.Second}
+		t.DialContext = dialer.DialContext
+		t.TLSHandshakeTimeout = opts.ConnectTimeout
+	}
+
+	var rt http.RoundTripper = t
+	if opts.ReadTimeout > 0 {
+		rt = &idleTimeoutTransport{base: t, timeout: opts.ReadTimeout}
+	}
+	return &http.Client{Transport: rt}, nil
+}
+
+func tlsConfig(opts Options) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if opts.CABundle != "" {
+		pem, err := os.ReadFile(opts.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", opts.CABundle)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if opts.ClientCert != "" || opts.ClientKey != "" {
+		if opts.ClientCert == "" || opts.ClientKey == "" {
+			return nil, fmt.Errorf("client certificate and key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,159 @@
+package httpclient
+
+import (
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew_ZeroOptionsKeepsDefaults(t *testing.T) {
+	c, err := New(Options{})
+	if err != nil || c != nil {
+		t.Errorf("expected nil client for zero options, got %v, %v", c, err)
+	}
+}
+
+func TestNew_ProxyAndNoProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.Host)
+		_, _ = io.WriteString(w, "via proxy")
+	}))
+	defer proxy.Close()
+
+	c, err := New(Options{Proxy: proxy.URL, NoProxy: []string{"internal.example"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get("http://api.example/v1/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "via proxy" || len(proxied) != 1 || proxied[0] != "api.example" {
+		t.Errorf("expected the request to go through the proxy, got %q via %v", body, proxied)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://internal.example/", nil)
+	if u, err := c.Transport.(*http.Transport).Proxy(req); err != nil || u != nil {
+		t.Errorf("expected internal.example to bypass the proxy, got %v, %v", u, err)
+	}
+}
+
+func TestNew_NoProxyWithEnvironmentProxy(t *testing.T) {
+	for _, key := range []string{"HTTPS_PROXY", "https_proxy", "http_proxy", "no_proxy"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("HTTP_PROXY", "http://proxy.corp:3128")
+	t.Setenv("NO_PROXY", "env.example")
+
+	c, err := New(Options{NoProxy: []string{"internal.example"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyFor := func(rawURL string) *url.URL {
+		req, _ := http.NewRequest(http.MethodGet, rawURL, nil)
+		u, err := c.Transport.(*http.Transport).Proxy(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+	if u := proxyFor("http://api.example/v1"); u == nil || u.Host != "proxy.corp:3128" {
+		t.Errorf("expected the environment proxy, got %v", u)
+	}
+	if u := proxyFor("http://internal.example/"); u != nil {
+		t.Errorf("expected internal.example to bypass the proxy, got %v", u)
+	}
+	if u := proxyFor("http://env.example/"); u != nil {
+		t.Errorf("expected NO_PROXY from the environment to still apply, got %v", u)
+	}
+}
+
+func TestNew_CABundle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(bundle, cert, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := http.Get(srv.URL); err == nil {
+		t.Fatal("expected the default client to reject the private CA")
+	}
+	c, err := New(Options{CABundle: bundle})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("expected the CA bundle to be trusted: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestNew_InvalidTLSFiles(t *testing.T) {
+	if _, err := New(Options{CABundle: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("expected an error for a missing CA bundle")
+	}
+	if _, err := New(Options{ClientCert: "cert.pem"}); err == nil || !strings.Contains(err.Error(), "together") {
+		t.Errorf("expected an error for a certificate without key, got %v", err)
+	}
+}
+
+func TestNew_ReadTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		for i := 0; i < 3; i++ {
+			_, _ = io.WriteString(w, "chunk\n")
+			flusher.Flush()
+			time.Sleep(60 * time.Millisecond)
+		}
+		if r.URL.Path == "/stall" {
+			time.Sleep(500 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := New(Options{ReadTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A slow but steady stream outlives the timeout.
+	resp, err := c.Get(srv.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || strings.Count(string(body), "chunk") != 3 {
+		t.Errorf("expected the full stream, got %q, %v", body, err)
+	}
+
+	resp, err = c.Get(srv.URL + "/stall")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	var timeout *TimeoutError
+	var netErr net.Error
+	if !errors.As(err, &timeout) || !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("expected a read timeout, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// TimeoutError is returned when the server stays silent for longer than the read timeout.
+// It is a net.Error with Timeout() true, so callers treat it as a retryable network failure.
+type TimeoutError struct {
+	After time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("no response from server for %s (read timeout)", e.After)
+}
+
+func (e *TimeoutError) Timeout() bool   { return true }
+func (e *TimeoutError) Temporary() bool { return true }
+
+// idleTimeoutTransport cancels a request once no data has arrived for timeout.
+type idleTimeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *idleTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	fired := new(atomic.Bool)
+	timer := time.AfterFunc(t.timeout, func() {
+		fired.Store(true)
+		cancel()
+	})
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		timer.Stop()
+		cancel()
+		if fired.Load() {
+			return nil, &TimeoutError{After: t.timeout}
+		}
+		return nil, err
+	}
+	timer.Reset(t.timeout)
+	resp.Body = &idleBody{ReadCloser: resp.Body, timer: timer, timeout: t.timeout, fired: fired, cancel: cancel}
+	return resp, nil
+}
+
+// idleBody pushes the deadline back on every read.
+type idleBody struct {
+	io.ReadCloser
+	timer   *time.Timer
+	timeout time.Duration
+	fired   *atomic.Bool
+	cancel  context.CancelFunc
+}
+
+func (b *idleBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && b.fired.Load() {
+		return n, &TimeoutError{After: b.timeout}
+	}
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	return n, err
+}
+
+func (b *idleBody) Close() error {
+	b.timer.Stop()
+	b.cancel()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,86 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// ListModels lists the locally installed models (/api/tags). Capabilities and
+// the trained context length come from /api/show; every Ollama model accepts
+// the JSON format option.
+func (p *Provider) ListModels(ctx context.Context) ([]ports.ModelInfo, error) {
+	list, err := p.client.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ollama models: %w", p.wrapError(err))
+	}
+
+	models := make([]ports.ModelInfo, 0, len(list.Models))
+	for _, m := range list.Models {
+		info := ports.ModelInfo{Name: m.Name, JSONMode: true, Size: m.Size, Details: modelDetails(m.Details)}
+		show, err := p.client.Show(ctx, &api.ShowRequest{Model: m.Name})
+		if err != nil {
+			// Listing still works without the details.
+			models = append(models, info)
+			continue
+		}
+		var caps []string
+		for _, c := range show.Capabilities {
+			caps = append(caps, string(c))
+		}
+		if len(caps) > 0 && !slices.Contains(caps, "completion") {
+			continue // embedding-only models cannot chat
+		}
+		info.ToolCalling = slices.Contains(caps, "tools")
+		info.Vision = slices.Contains(caps, "vision")
+		info.ContextWindow = contextLength(show.ModelInfo)
+		models = append(models, info)
+	}
+	return models, nil
+}
+
+// PullModel downloads a model from the Ollama registry, reporting progress as it goes.
+func (p *Provider) PullModel(ctx context.Context, name string, progress func(ports.PullProgress)) error {
+	err := p.client.Pull(ctx, &api.PullRequest{Model: name}, func(r api.ProgressResponse) error {
+		if progress != nil {
+			progress(ports.PullProgress{Status: r.Status, Total: r.Total, Completed: r.Completed})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to pull %s: %w", name, p.wrapError(err))
+	}
+	return nil
+}
+
+// contextLength reads "<architecture>.context_length" from /api/show model info.
+func contextLength(info map[string]any) int {
+	for k, v := range info {
+		if !strings.HasSuffix(k, ".context_length") {
+			continue
+		}
+		if n, ok := v.(float64); ok {
+			return int(n)
+		}
+	}
+	return 0
+}
+
+func modelDetails(d api.ModelDetails) string {
+	var parts []string
+	for _, s := range []string{d.Family, d.ParameterSize, d.QuantizationLevel} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+var (
+	_ ports.ModelLister = (*Provider)(nil)
+	_ ports.ModelPuller = (*Provider)(nil)
+)
@@ -0,0 +1,83 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+func TestProvider_ListModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/tags":
+			_ = json.NewEncoder(w).Encode(map[string]any{"models": []map[string]any{
+				{"name": "llama3.2:3b", "size": 2 << 30, "details": map[string]any{"family": "llama", "parameter_size": "3.2B"}},
+				{"name": "nomic-embed-text:latest", "size": 1 << 28},
+			}})
+		case "/api/show":
+			var req struct{ Model string }
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if req.Model == "llama3.2:3b" {
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"capabilities": []string{"completion", "tools"},
+					"model_info":   map[string]any{"general.architecture": "llama", "llama.context_length": 131072},
+				})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"capabilities": []string{"embedding"}})
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	p, err := New(srv.URL, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	models, err := p.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(models) != 1 {
+		t.Fatalf("expected the embedding model to be skipped, got %+v", models)
+	}
+	m := models[0]
+	if m.Name != "llama3.2:3b" || m.ContextWindow != 131072 || !m.ToolCalling || !m.JSONMode || m.Vision {
+		t.Errorf("unexpected model info: %+v", m)
+	}
+	if m.Details != "llama 3.2B" || m.Size != 2<<30 {
+		t.Errorf("unexpected details: %q, %d", m.Details, m.Size)
+	}
+}
+
+func TestProvider_PullModel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/pull" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		_ = enc.Encode(map[string]any{"status": "pulling manifest"})
+		_ = enc.Encode(map[string]any{"status": "downloading", "total": 100, "completed": 40})
+		_ = enc.Encode(map[string]any{"status": "success"})
+	}))
+	defer srv.Close()
+
+	p, err := New(srv.URL, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []ports.PullProgress
+	if err := p.PullModel(context.Background(), "llama3.2:3b", func(pp ports.PullProgress) { events = append(events, pp) }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 3 || events[1].Completed != 40 || events[2].Status != "success" {
+		t.Errorf("unexpected progress: %+v", events)
+	}
+}
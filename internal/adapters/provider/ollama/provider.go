@@ -2,23 +2,28 @@ package ollama
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
 
 	"github.com/ollama/ollama/api"
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
 	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
 )
 
 type Provider struct {
 	client *api.Client
 	model  string
+	// numCtx is sent as num_ctx when set; Ollama otherwise truncates prompts to its default window.
+	numCtx int
 }
 
 // New creates a new Ollama provider
-// host example: "http://localhost:11434"
-func New(host, model string) (*Provider, error) {
+// host example: "http://localhost:11434"; a nil httpClient uses http.DefaultClient.
+func New(host, model string, httpClient *http.Client) (*Provider, error) {
 	if host == "" {
 		host = "http://localhost:11434"
 	}
@@ -31,13 +36,22 @@ func New(host, model string) (*Provider, error) {
 		return nil, fmt.Errorf("invalid ollama host: %w", err)
 	}
 
-	client := api.NewClient(u, http.DefaultClient)
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	client := api.NewClient(u, httpClient)
 	return &Provider{
 		client: client,
 		model:  model,
 	}, nil
 }
 
+// WithContextWindow sets the context window (num_ctx) requested for every call.
+func (p *Provider) WithContextWindow(tokens int) *Provider {
+	p.numCtx = tokens
+	return p
+}
+
 func (p *Provider) Name() string {
 	return "ollama"
 }
@@ -59,27 +73,44 @@ func (p *Provider) Generate(ctx context.Context, req ports.GenerateRequest) (por
 
 	messages := p.convertToOllamaMessages(req)
 
+	tools, err := toOllamaTools(req.Tools)
+	if err != nil {
+		return ports.GenerateResponse{}, err
+	}
+
 	var responseText strings.Builder
+	var toolCalls []chat.ToolCall
+	usage := ports.Usage{Provider: p.Name(), Model: model}
 
 	// Use Chat API (supports messages)
 	reqChat := &api.ChatRequest{
 		Model:    model,
 		Messages: messages,
+		Tools:    tools,
+		Format:   outputFormat(req.Output),
+		Options:  p.options(req.Sampling),
 		Stream:   new(bool), // false
 	}
 	*reqChat.Stream = false
 
-	err := p.client.Chat(ctx, reqChat, func(resp api.ChatResponse) error {
+	err = p.client.Chat(ctx, reqChat, func(resp api.ChatResponse) error {
 		responseText.WriteString(resp.Message.Content)
+		toolCalls = append(toolCalls, fromOllamaToolCalls(resp.Message.ToolCalls, len(toolCalls))...)
+		if resp.Done {
+			usage.PromptTokens = resp.PromptEvalCount
+			usage.CompletionTokens = resp.EvalCount
+		}
 		return nil
 	})
 
 	if err != nil {
-		return ports.GenerateResponse{}, fmt.Errorf("ollama generate error: %w", err)
+		return ports.GenerateResponse{}, fmt.Errorf("ollama generate error: %w", p.wrapError(err))
 	}
 
 	return ports.GenerateResponse{
-		Text: responseText.String(),
+		Text:      responseText.String(),
+		ToolCalls: toolCalls,
+		Usage:     usage,
 	}, nil
 }
 
@@ -91,11 +122,19 @@ func (p *Provider) StreamGenerate(ctx context.Context, req ports.GenerateRequest
 
 	messages := p.convertToOllamaMessages(req)
 
+	tools, err := toOllamaTools(req.Tools)
+	if err != nil {
+		return nil, err
+	}
+
 	ch := make(chan ports.StreamChunk)
 
 	reqChat := &api.ChatRequest{
 		Model:    model,
 		Messages: messages,
+		Tools:    tools,
+		Format:   outputFormat(req.Output),
+		Options:  p.options(req.Sampling),
 		Stream:   new(bool), // true
 	}
 	*reqChat.Stream = true
@@ -103,40 +142,55 @@ func (p *Provider) StreamGenerate(ctx context.Context, req ports.GenerateRequest
 	go func() {
 		defer close(ch)
 
+		var toolCalls []chat.ToolCall
 		err := p.client.Chat(ctx, reqChat, func(resp api.ChatResponse) error {
 			if resp.Message.Content != "" {
 				ch <- ports.StreamChunk{Content: resp.Message.Content}
 			}
+			toolCalls = append(toolCalls, fromOllamaToolCalls(resp.Message.ToolCalls, len(toolCalls))...)
 			if resp.Done {
-				// isLast handled by channel close
+				ch <- ports.StreamChunk{
+					IsLast:    true,
+					ToolCalls: toolCalls,
+					Usage: &ports.Usage{
+						Provider:         p.Name(),
+						Model:            model,
+						PromptTokens:     resp.PromptEvalCount,
+						CompletionTokens: resp.EvalCount,
+					},
+				}
 			}
 			return nil
 		})
 
 		if err != nil {
-			ch <- ports.StreamChunk{Error: err}
+			ch <- ports.StreamChunk{Error: p.wrapError(err)}
 		}
 	}()
 
 	return ch, nil
 }
 
+func (p *Provider) SupportsToolCalling() bool { return true }
+
 func (p *Provider) convertToOllamaMessages(req ports.GenerateRequest) []api.Message {
 	var messages []api.Message
 
 	if len(req.Messages) > 0 {
 		for _, m := range req.Messages {
-			role := "user"
+			msg := api.Message{Role: "user", Content: m.Content}
 			switch m.Role {
-			case "system":
-				role = "system"
-			case "assistant":
-				role = "assistant"
+			case chat.RoleSystem:
+				msg.Role = "system"
+			case chat.RoleAssistant:
+				msg.Role = "assistant"
+				msg.ToolCalls = toOllamaToolCalls(m.ToolCalls)
+			case chat.RoleTool:
+				msg.Role = "tool"
+				msg.ToolName = m.Name
+				msg.ToolCallID = m.ToolCallID
 			}
-			messages = append(messages, api.Message{
-				Role:    role,
-				Content: m.Content,
-			})
+			messages = append(messages, msg)
 		}
 	} else if req.Prompt != "" {
 		messages = append(messages, api.Message{
@@ -151,4 +205,118 @@ func (p *Provider) Close() error {
 	return nil
 }
 
+func toOllamaTools(specs []ports.ToolSpec) (api.Tools, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	tools := make(api.Tools, 0, len(specs))
+	for _, spec := range specs {
+		var params api.ToolFunctionParameters
+		if len(spec.Parameters) > 0 {
+			if err := json.Unmarshal(spec.Parameters, &params); err != nil {
+				return nil, fmt.Errorf("tool %s: invalid parameters schema: %w", spec.Name, err)
+			}
+		}
+		if params.Type == "" {
+			params.Type = "object"
+		}
+		tools = append(tools, api.Tool{
+			Type: "function",
+			Function: api.ToolFunction{
+				Name:        spec.Name,
+				Description: spec.Description,
+				Parameters:  params,
+			},
+		})
+	}
+	return tools, nil
+}
+
+func toOllamaToolCalls(calls []chat.ToolCall) []api.ToolCall {
+	var out []api.ToolCall
+	for i, c := range calls {
+		args := api.NewToolCallFunctionArguments()
+		if len(c.Arguments) > 0 {
+			_ = json.Unmarshal(c.Arguments, &args)
+		}
+		out = append(out, api.ToolCall{
+			ID:       c.ID,
+			Function: api.ToolCallFunction{Index: i, Name: c.Name, Arguments: args},
+		})
+	}
+	return out
+}
+
+// options maps sampling parameters and the context window to Ollama model options.
+func (p *Provider) options(s ports.Sampling) map[string]any {
+	if s.IsZero() && p.numCtx == 0 {
+		return nil
+	}
+	opts := map[string]any{}
+	if p.numCtx > 0 {
+		opts["num_ctx"] = p.numCtx
+	}
+	if s.Temperature != nil {
+		opts["temperature"] = *s.Temperature
+	}
+	if s.TopP != nil {
+		opts["top_p"] = *s.TopP
+	}
+	if s.MaxTokens > 0 {
+		opts["num_predict"] = s.MaxTokens
+	}
+	if len(s.Stop) > 0 {
+		opts["stop"] = s.Stop
+	}
+	if s.Seed != nil {
+		opts["seed"] = *s.Seed
+	}
+	return opts
+}
+
+// outputFormat maps structured output to Ollama's `format`: a JSON schema, or "json" without one.
+func outputFormat(f *ports.OutputFormat) json.RawMessage {
+	switch {
+	case f == nil:
+		return nil
+	case len(f.Schema) > 0:
+		return f.Schema
+	default:
+		return json.RawMessage(`"json"`)
+	}
+}
+
+// fromOllamaToolCalls converts tool calls; offset keeps synthesised IDs unique across stream chunks.
+func fromOllamaToolCalls(calls []api.ToolCall, offset int) []chat.ToolCall {
+	var out []chat.ToolCall
+	for _, tc := range calls {
+		if tc.Function.Name == "" {
+			continue
+		}
+		id := tc.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", offset+len(out))
+		}
+		args, err := json.Marshal(tc.Function.Arguments)
+		if err != nil {
+			args = []byte("{}")
+		}
+		out = append(out, chat.ToolCall{ID: id, Name: tc.Function.Name, Arguments: args})
+	}
+	return out
+}
+
+// wrapError classifies Ollama errors as ports.ProviderError and maps the
+// "model does not support tools" rejection to ports.ErrToolsUnsupported.
+func (p *Provider) wrapError(err error) error {
+	var se api.StatusError
+	if !errors.As(err, &se) {
+		return &ports.ProviderError{Provider: p.Name(), Err: err}
+	}
+	if strings.Contains(strings.ToLower(se.ErrorMessage), "does not support tools") {
+		err = fmt.Errorf("%w: %s", ports.ErrToolsUnsupported, se.ErrorMessage)
+	}
+	return &ports.ProviderError{Provider: p.Name(), StatusCode: se.StatusCode, Err: err}
+}
+
 var _ ports.Provider = (*Provider)(nil)
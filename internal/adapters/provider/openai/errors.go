@@ -0,0 +1,53 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+	gopenai "github.com/sashabaranov/go-openai"
+)
+
+type retryAfterKey struct{}
+
+// withRetryAfterRecorder returns a context whose requests record the server's
+// Retry-After delay, since go-openai does not expose response headers on errors.
+func withRetryAfterRecorder(ctx context.Context) (context.Context, *time.Duration) {
+	rec := new(time.Duration)
+	return context.WithValue(ctx, retryAfterKey{}, rec), rec
+}
+
+// retryAfterTransport stores Retry-After into the recorder carried by the request context.
+type retryAfterTransport struct {
+	base http.RoundTripper
+}
+
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if rec, ok := req.Context().Value(retryAfterKey{}).(*time.Duration); ok {
+		if d := ports.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); d > 0 {
+			*rec = d
+		}
+	}
+	return resp, nil
+}
+
+// wrapError classifies go-openai errors as ports.ProviderError.
+func (p *Provider) wrapError(err error, retryAfter time.Duration) error {
+	pe := &ports.ProviderError{Provider: p.Name(), RetryAfter: retryAfter, Err: err}
+
+	var apiErr *gopenai.APIError
+	var reqErr *gopenai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		pe.StatusCode = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		pe.StatusCode = reqErr.HTTPStatusCode
+	}
+	return pe
+}
@@ -0,0 +1,55 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// nonChatPrefixes are OpenAI model families that cannot serve chat completions.
+var nonChatPrefixes = []string{"text-embedding", "whisper", "tts", "dall-e", "omni-moderation", "text-moderation", "davinci", "babbage", "gpt-image", "sora"}
+
+// ListModels lists the chat models served by /v1/models. OpenAI only reports
+// model IDs, so capabilities are inferred from well-known model families;
+// models of OpenAI-compatible servers are listed without hints.
+func (p *Provider) ListModels(ctx context.Context) ([]ports.ModelInfo, error) {
+	ctx, retryAfter := withRetryAfterRecorder(ctx)
+	list, err := p.client.ListModels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list openai models: %w", p.wrapError(err, *retryAfter))
+	}
+
+	var models []ports.ModelInfo
+	for _, m := range list.Models {
+		id := strings.ToLower(m.ID)
+		if hasAnyPrefix(id, nonChatPrefixes...) || strings.Contains(id, "realtime") || strings.Contains(id, "transcribe") {
+			continue
+		}
+		info := ports.ModelInfo{Name: m.ID, Details: m.OwnedBy}
+		switch {
+		case hasAnyPrefix(id, "o1-mini", "o3-mini"):
+			info.ToolCalling, info.JSONMode = true, true
+		case hasAnyPrefix(id, "gpt-4o", "gpt-4.1", "gpt-4-turbo", "gpt-5", "o1", "o3", "o4"):
+			info.ToolCalling, info.JSONMode, info.Vision = true, true, true
+		case hasAnyPrefix(id, "gpt-4", "gpt-3.5-turbo"):
+			info.ToolCalling, info.JSONMode = true, true
+		}
+		models = append(models, info)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+	return models, nil
+}
+
+func hasAnyPrefix(s string, prefixes ...string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+var _ ports.ModelLister = (*Provider)(nil)
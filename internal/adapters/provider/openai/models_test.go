@@ -0,0 +1,57 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProvider_ListModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"object": "list",
+			"data": []map[string]any{
+				{"id": "gpt-4o-mini", "object": "model", "owned_by": "system"},
+				{"id": "text-embedding-3-small", "object": "model", "owned_by": "system"},
+				{"id": "o3-mini", "object": "model", "owned_by": "system"},
+				{"id": "qwen2.5-coder", "object": "model", "owned_by": "vllm"},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	models, err := New("sk-test", "", Options{BaseURL: srv.URL + "/v1"}).ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(models) != 3 {
+		t.Fatalf("expected embeddings to be filtered out, got %+v", models)
+	}
+	byName := map[string]bool{}
+	for _, m := range models {
+		byName[m.Name] = true
+		switch m.Name {
+		case "gpt-4o-mini":
+			if !m.ToolCalling || !m.JSONMode || !m.Vision {
+				t.Errorf("expected full capabilities for gpt-4o-mini, got %+v", m)
+			}
+		case "o3-mini":
+			if m.Vision {
+				t.Errorf("o3-mini does not take images: %+v", m)
+			}
+		case "qwen2.5-coder":
+			if m.ToolCalling || m.Details != "vllm" {
+				t.Errorf("expected no hints for an unknown model, got %+v", m)
+			}
+		}
+	}
+	if !byName["gpt-4o-mini"] || !byName["o3-mini"] || !byName["qwen2.5-coder"] {
+		t.Errorf("unexpected models: %+v", models)
+	}
+}
@@ -2,30 +2,78 @@ package openai
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"net/http"
+	"strings"
 
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
 	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
 	gopenai "github.com/sashabaranov/go-openai"
 )
 
+const defaultPlaceholderKey = "YOUR_OPENAI_API_KEY_HERE"
+
 // Provider implements ports.Provider for OpenAI
 type Provider struct {
-	client *gopenai.Client
-	model  string
+	client     *gopenai.Client
+	apiKey     string
+	model      string
+	baseURL    string
+	apiVersion string
+}
+
+// Options customises the client for OpenAI-compatible endpoints.
+type Options struct {
+	// BaseURL overrides https://api.openai.com/v1 (e.g. "http://localhost:8000/v1" for vLLM).
+	BaseURL string
+	// Organization is sent as the OpenAI-Organization header.
+	Organization string
+	// APIVersion enables Azure-style routing; BaseURL is then the resource endpoint.
+	APIVersion string
+	// Headers are added to every request (gateway auth, tenant routing, ...).
+	Headers map[string]string
+	// HTTPClient supplies the base transport (proxy, TLS, timeouts); nil uses http.DefaultTransport.
+	HTTPClient *http.Client
 }
 
 // New creates a new OpenAI provider
-func New(apiKey, model string) *Provider {
+func New(apiKey, model string, opts Options) *Provider {
 	if model == "" {
 		model = gopenai.GPT4o
 	}
-	config := gopenai.DefaultConfig(apiKey)
+
+	var config gopenai.ClientConfig
+	if opts.APIVersion != "" {
+		config = gopenai.DefaultAzureConfig(apiKey, opts.BaseURL)
+		config.APIVersion = opts.APIVersion
+	} else {
+		config = gopenai.DefaultConfig(apiKey)
+		if opts.BaseURL != "" {
+			config.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+		}
+	}
+	config.OrgID = opts.Organization
+
+	var transport http.RoundTripper = http.DefaultTransport
+	if opts.HTTPClient != nil && opts.HTTPClient.Transport != nil {
+		transport = opts.HTTPClient.Transport
+	}
+	if len(opts.Headers) > 0 {
+		transport = &headerTransport{base: transport, headers: opts.Headers}
+	}
+	config.HTTPClient = &http.Client{Transport: &retryAfterTransport{base: transport}}
+
 	client := gopenai.NewClientWithConfig(config)
 	return &Provider{
-		client: client,
-		model:  model,
+		client:     client,
+		apiKey:     apiKey,
+		model:      model,
+		baseURL:    opts.BaseURL,
+		apiVersion: opts.APIVersion,
 	}
 }
 
@@ -34,60 +82,53 @@ func (p *Provider) Name() string {
 }
 
 func (p *Provider) IsConfigured(ctx context.Context) error {
-	// Simple check: do we have a client?
 	// Proper check would be making a simple API call, but that might be expensive/slow
 	if p.client == nil {
 		return fmt.Errorf("openai client not initialized")
 	}
+	// Self-hosted OpenAI-compatible servers often run without authentication;
+	// Azure always needs a key.
+	if p.baseURL != "" && p.apiVersion == "" {
+		return nil
+	}
+	if strings.TrimSpace(p.apiKey) == "" || p.apiKey == defaultPlaceholderKey {
+		return fmt.Errorf("openai API key is not configured")
+	}
 	return nil
 }
 
-func (p *Provider) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
-	messages := p.convertToOpenAIMessages(req)
-
-	model := p.model
-	if req.Model != "" {
-		model = req.Model
-	}
-
-	resp, err := p.client.CreateChatCompletion(
-		ctx,
-		gopenai.ChatCompletionRequest{
-			Model:    model,
-			Messages: messages,
-		},
-	)
+func (p *Provider) SupportsToolCalling() bool { return true }
 
+func (p *Provider) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
+	ctx, retryAfter := withRetryAfterRecorder(ctx)
+	chatReq := p.buildRequest(req)
+	resp, err := p.client.CreateChatCompletion(ctx, chatReq)
 	if err != nil {
-		return ports.GenerateResponse{}, fmt.Errorf("openai generate error: %w", err)
+		return ports.GenerateResponse{}, fmt.Errorf("openai generate error: %w", p.wrapError(err, *retryAfter))
 	}
 
 	if len(resp.Choices) == 0 {
 		return ports.GenerateResponse{}, fmt.Errorf("openai returned no choices")
 	}
 
+	msg := resp.Choices[0].Message
 	return ports.GenerateResponse{
-		Text: resp.Choices[0].Message.Content,
+		Text:      msg.Content,
+		ToolCalls: fromOpenAIToolCalls(msg.ToolCalls),
+		Usage:     p.usage(resp.Model, chatReq.Model, resp.Usage),
 	}, nil
 }
 
 func (p *Provider) StreamGenerate(ctx context.Context, req ports.GenerateRequest) (<-chan ports.StreamChunk, error) {
-	messages := p.convertToOpenAIMessages(req)
-
-	model := p.model
-	if req.Model != "" {
-		model = req.Model
+	ctx, retryAfter := withRetryAfterRecorder(ctx)
+	chatReq := p.buildRequest(req)
+	if p.apiVersion == "" {
+		// Azure-style deployments reject stream_options on older API versions.
+		chatReq.StreamOptions = &gopenai.StreamOptions{IncludeUsage: true}
 	}
-
-	stream, err := p.client.CreateChatCompletionStream(
-		ctx,
-		gopenai.ChatCompletionRequest{
-			Model:    model,
-			Messages: messages,
-		},
-	)
+	stream, err := p.client.CreateChatCompletionStream(ctx, chatReq)
 	if err != nil {
-		return nil, fmt.Errorf("openai stream creation error: %w", err)
+		return nil, fmt.Errorf("openai stream creation error: %w", p.wrapError(err, *retryAfter))
 	}
 
 	ch := make(chan ports.StreamChunk)
@@ -96,20 +137,36 @@ func (p *Provider) StreamGenerate(ctx context.Context, req ports.GenerateRequest
 		defer stream.Close()
 		defer close(ch)
 
+		// Tool call arguments arrive as fragments keyed by index.
+		var calls []gopenai.ToolCall
+		var usage *ports.Usage
+
 		for {
 			response, err := stream.Recv()
 			if errors.Is(err, io.EOF) {
+				if len(calls) > 0 || usage != nil {
+					ch <- ports.StreamChunk{IsLast: true, ToolCalls: fromOpenAIToolCalls(calls), Usage: usage}
+				}
 				return
 			}
 			if err != nil {
-				ch <- ports.StreamChunk{Error: err}
+				ch <- ports.StreamChunk{Error: p.wrapError(err, 0)}
 				return
 			}
 
+			// With include_usage the final chunk has no choices, only usage.
+			if response.Usage != nil {
+				u := p.usage(response.Model, chatReq.Model, *response.Usage)
+				usage = &u
+			}
+
 			if len(response.Choices) > 0 {
-				content := response.Choices[0].Delta.Content
-				if content != "" {
-					ch <- ports.StreamChunk{Content: content}
+				delta := response.Choices[0].Delta
+				for _, tc := range delta.ToolCalls {
+					calls = mergeToolCallDelta(calls, tc)
+				}
+				if delta.Content != "" {
+					ch <- ports.StreamChunk{Content: delta.Content}
 				}
 			}
 		}
@@ -118,23 +175,100 @@ func (p *Provider) StreamGenerate(ctx context.Context, req ports.GenerateRequest
 	return ch, nil
 }
 
+func (p *Provider) buildRequest(req ports.GenerateRequest) gopenai.ChatCompletionRequest {
+	model := p.model
+	if req.Model != "" {
+		model = req.Model
+	}
+
+	out := gopenai.ChatCompletionRequest{
+		Model:    model,
+		Messages: p.convertToOpenAIMessages(req),
+	}
+
+	if t := req.Temperature; t != nil {
+		out.Temperature = *t
+		if *t == 0 {
+			// go-openai omits a zero temperature, which the API reads as 1.
+			out.Temperature = math.SmallestNonzeroFloat32
+		}
+	}
+	if req.TopP != nil {
+		out.TopP = *req.TopP
+	}
+	out.MaxTokens = req.MaxTokens
+	out.Stop = req.Stop
+	out.Seed = req.Seed
+
+	if req.Output != nil {
+		out.ResponseFormat = responseFormat(req.Output)
+	}
+
+	for _, t := range req.Tools {
+		out.Tools = append(out.Tools, gopenai.Tool{
+			Type: gopenai.ToolTypeFunction,
+			Function: &gopenai.FunctionDefinition{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  t.Parameters,
+			},
+		})
+	}
+
+	return out
+}
+
+// responseFormat uses json_schema when a schema is given and plain JSON mode otherwise.
+// The schema is not strict: strict mode requires every property to be required.
+func responseFormat(f *ports.OutputFormat) *gopenai.ChatCompletionResponseFormat {
+	if len(f.Schema) == 0 {
+		return &gopenai.ChatCompletionResponseFormat{Type: gopenai.ChatCompletionResponseFormatTypeJSONObject}
+	}
+	name := f.Name
+	if name == "" {
+		name = "response"
+	}
+	return &gopenai.ChatCompletionResponseFormat{
+		Type: gopenai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &gopenai.ChatCompletionResponseFormatJSONSchema{
+			Name:   name,
+			Schema: f.Schema,
+		},
+	}
+}
+
+func (p *Provider) usage(respModel, reqModel string, u gopenai.Usage) ports.Usage {
+	model := respModel
+	if model == "" {
+		model = reqModel
+	}
+	return ports.Usage{
+		Provider:         p.Name(),
+		Model:            model,
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+	}
+}
+
 func (p *Provider) convertToOpenAIMessages(req ports.GenerateRequest) []gopenai.ChatCompletionMessage {
 	var messages []gopenai.ChatCompletionMessage
 
 	// If Messages are provided, use them
 	if len(req.Messages) > 0 {
 		for _, m := range req.Messages {
-			role := gopenai.ChatMessageRoleUser
+			msg := gopenai.ChatCompletionMessage{Role: gopenai.ChatMessageRoleUser, Content: m.Content}
 			switch m.Role {
-			case "system":
-				role = gopenai.ChatMessageRoleSystem
-			case "assistant":
-				role = gopenai.ChatMessageRoleAssistant
+			case chat.RoleSystem:
+				msg.Role = gopenai.ChatMessageRoleSystem
+			case chat.RoleAssistant:
+				msg.Role = gopenai.ChatMessageRoleAssistant
+				msg.ToolCalls = toOpenAIToolCalls(m.ToolCalls)
+			case chat.RoleTool:
+				msg.Role = gopenai.ChatMessageRoleTool
+				msg.ToolCallID = m.ToolCallID
+				msg.Name = m.Name
 			}
-			messages = append(messages, gopenai.ChatCompletionMessage{
-				Role:    role,
-				Content: m.Content,
-			})
+			messages = append(messages, msg)
 		}
 	} else if req.Prompt != "" {
 		// Fallback to single prompt
@@ -151,4 +285,75 @@ func (p *Provider) Close() error {
 	return nil
 }
 
+func mergeToolCallDelta(calls []gopenai.ToolCall, delta gopenai.ToolCall) []gopenai.ToolCall {
+	idx := len(calls)
+	if delta.Index != nil {
+		idx = *delta.Index
+	}
+	for len(calls) <= idx {
+		calls = append(calls, gopenai.ToolCall{Type: gopenai.ToolTypeFunction})
+	}
+	if delta.ID != "" {
+		calls[idx].ID = delta.ID
+	}
+	calls[idx].Function.Name += delta.Function.Name
+	calls[idx].Function.Arguments += delta.Function.Arguments
+	return calls
+}
+
+func toOpenAIToolCalls(calls []chat.ToolCall) []gopenai.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]gopenai.ToolCall, 0, len(calls))
+	for _, c := range calls {
+		args := string(c.Arguments)
+		if args == "" {
+			args = "{}"
+		}
+		out = append(out, gopenai.ToolCall{
+			ID:       c.ID,
+			Type:     gopenai.ToolTypeFunction,
+			Function: gopenai.FunctionCall{Name: c.Name, Arguments: args},
+		})
+	}
+	return out
+}
+
+func fromOpenAIToolCalls(calls []gopenai.ToolCall) []chat.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]chat.ToolCall, 0, len(calls))
+	for i, tc := range calls {
+		if tc.Function.Name == "" {
+			continue
+		}
+		id := tc.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", i)
+		}
+		args := strings.TrimSpace(tc.Function.Arguments)
+		if args == "" {
+			args = "{}"
+		}
+		out = append(out, chat.ToolCall{ID: id, Name: tc.Function.Name, Arguments: json.RawMessage(args)})
+	}
+	return out
+}
+
+// headerTransport injects static headers into every outgoing request.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	return t.base.RoundTrip(req)
+}
+
 var _ ports.Provider = (*Provider)(nil)
@@ -0,0 +1,245 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+func chatCompletionHandler(t *testing.T, check func(r *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		check(r)
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":     "chatcmpl-test",
+			"object": "chat.completion",
+			"model":  body["model"],
+			"choices": []map[string]any{{
+				"index":         0,
+				"finish_reason": "stop",
+				"message":       map[string]any{"role": "assistant", "content": "hello from stub"},
+			}},
+			"usage": map[string]any{"prompt_tokens": 12, "completion_tokens": 3, "total_tokens": 15},
+		})
+	}
+}
+
+func TestProvider_Generate_CompatibleServer(t *testing.T) {
+	srv := httptest.NewServer(chatCompletionHandler(t, func(r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("unexpected Authorization header: %q", got)
+		}
+		if got := r.Header.Get("OpenAI-Organization"); got != "org-123" {
+			t.Errorf("unexpected OpenAI-Organization header: %q", got)
+		}
+		if got := r.Header.Get("X-Tenant"); got != "devops" {
+			t.Errorf("unexpected X-Tenant header: %q", got)
+		}
+	}))
+	defer srv.Close()
+
+	p := New("sk-test", "local-model", Options{
+		BaseURL:      srv.URL + "/v1/",
+		Organization: "org-123",
+		Headers:      map[string]string{"X-Tenant": "devops"},
+	})
+
+	resp, err := p.Generate(context.Background(), ports.GenerateRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Text != "hello from stub" {
+		t.Errorf("unexpected text: %q", resp.Text)
+	}
+	if resp.Usage.Model != "local-model" || resp.Usage.PromptTokens != 12 || resp.Usage.CompletionTokens != 3 {
+		t.Errorf("unexpected usage: %+v", resp.Usage)
+	}
+}
+
+func TestProvider_Generate_AzureStyle(t *testing.T) {
+	srv := httptest.NewServer(chatCompletionHandler(t, func(r *http.Request) {
+		if r.URL.Path != "/openai/deployments/gpt-4o/chat/completions" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("api-version"); got != "2024-06-01" {
+			t.Errorf("unexpected api-version: %q", got)
+		}
+		if got := r.Header.Get("api-key"); got != "azure-key" {
+			t.Errorf("unexpected api-key header: %q", got)
+		}
+	}))
+	defer srv.Close()
+
+	p := New("azure-key", "gpt-4o", Options{BaseURL: srv.URL, APIVersion: "2024-06-01"})
+
+	if _, err := p.Generate(context.Background(), ports.GenerateRequest{Prompt: "hi"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestProvider_IsConfigured(t *testing.T) {
+	if err := New("", "", Options{}).IsConfigured(context.Background()); err == nil {
+		t.Error("expected error for missing API key")
+	}
+	if err := New(defaultPlaceholderKey, "", Options{}).IsConfigured(context.Background()); err == nil {
+		t.Error("expected error for placeholder API key")
+	}
+	if err := New("", "", Options{BaseURL: "http://localhost:8000/v1"}).IsConfigured(context.Background()); err != nil {
+		t.Errorf("expected keyless custom endpoint to be configured, got %v", err)
+	}
+	azure := Options{BaseURL: "https://example.openai.azure.com", APIVersion: "2024-06-01"}
+	if err := New("", "", azure).IsConfigured(context.Background()); err == nil {
+		t.Error("expected error for Azure without an API key")
+	}
+	if err := New("azure-key", "", azure).IsConfigured(context.Background()); err != nil {
+		t.Errorf("expected Azure with a key to be configured, got %v", err)
+	}
+}
+
+func TestProvider_Generate_RateLimitError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"message":"rate limited","type":"requests"}}`))
+	}))
+	defer srv.Close()
+
+	p := New("sk-test", "gpt-4o", Options{BaseURL: srv.URL})
+
+	_, err := p.Generate(context.Background(), ports.GenerateRequest{Prompt: "hi"})
+	var pe *ports.ProviderError
+	if !errors.As(err, &pe) {
+		t.Fatalf("expected ports.ProviderError, got %v", err)
+	}
+	if pe.StatusCode != http.StatusTooManyRequests || pe.RetryAfter != 7*time.Second || !pe.Retryable() {
+		t.Errorf("unexpected provider error: %+v", pe)
+	}
+}
+
+func TestProvider_Generate_ToolTurns(t *testing.T) {
+	var messages []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []map[string]any `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		messages = body.Messages
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer srv.Close()
+
+	p := New("sk-test", "gpt-4o", Options{BaseURL: srv.URL})
+	_, err := p.Generate(context.Background(), ports.GenerateRequest{Messages: []chat.Message{
+		{Role: chat.RoleSystem, Content: "rules"},
+		{Role: chat.RoleUser, Content: "task"},
+		{Role: chat.RoleAssistant, ToolCalls: []chat.ToolCall{{ID: "call_0", Name: "read_file", Arguments: json.RawMessage(`{"path":"go.mod"}`)}}},
+		{Role: chat.RoleTool, ToolCallID: "call_0", Name: "read_file", Content: "module x"},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(messages) != 4 {
+		t.Fatalf("expected 4 messages, got %d", len(messages))
+	}
+	if messages[0]["role"] != "system" || messages[1]["role"] != "user" {
+		t.Errorf("unexpected leading roles: %v, %v", messages[0]["role"], messages[1]["role"])
+	}
+	calls, _ := messages[2]["tool_calls"].([]any)
+	if messages[2]["role"] != "assistant" || len(calls) != 1 {
+		t.Fatalf("expected an assistant turn with one tool call, got %v", messages[2])
+	}
+	fn := calls[0].(map[string]any)["function"].(map[string]any)
+	if fn["name"] != "read_file" || fn["arguments"] != `{"path":"go.mod"}` {
+		t.Errorf("unexpected tool call: %v", fn)
+	}
+	if messages[3]["role"] != "tool" || messages[3]["tool_call_id"] != "call_0" || messages[3]["content"] != "module x" {
+		t.Errorf("unexpected tool turn: %v", messages[3])
+	}
+}
+
+func TestProvider_Generate_StructuredOutput(t *testing.T) {
+	var format map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ResponseFormat map[string]any `json:"response_format"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		format = body.ResponseFormat
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"{}"}}]}`))
+	}))
+	defer srv.Close()
+	p := New("sk-test", "gpt-4o", Options{BaseURL: srv.URL})
+	ctx := context.Background()
+
+	schema := json.RawMessage(`{"type":"object","properties":{"type":{"type":"string"}}}`)
+	if _, err := p.Generate(ctx, ports.GenerateRequest{Prompt: "hi", Output: &ports.OutputFormat{Name: "action", Schema: schema}}); err != nil {
+		t.Fatal(err)
+	}
+	js, _ := format["json_schema"].(map[string]any)
+	if format["type"] != "json_schema" || js["name"] != "action" || js["schema"] == nil {
+		t.Errorf("unexpected response_format: %v", format)
+	}
+
+	if _, err := p.Generate(ctx, ports.GenerateRequest{Prompt: "hi", Output: &ports.OutputFormat{}}); err != nil {
+		t.Fatal(err)
+	}
+	if format["type"] != "json_object" {
+		t.Errorf("expected JSON mode without a schema, got %v", format)
+	}
+
+	if _, err := p.Generate(ctx, ports.GenerateRequest{Prompt: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if format != nil {
+		t.Errorf("expected no response_format by default, got %v", format)
+	}
+}
+
+func TestProvider_Generate_Sampling(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer srv.Close()
+	p := New("sk-test", "gpt-4o", Options{BaseURL: srv.URL})
+
+	zero, topP, seed := float32(0), float32(0.9), 42
+	_, err := p.Generate(context.Background(), ports.GenerateRequest{Prompt: "hi", Sampling: ports.Sampling{
+		Temperature: &zero, TopP: &topP, MaxTokens: 128, Stop: []string{"END"}, Seed: &seed,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := body["temperature"]; !ok {
+		t.Error("expected a zero temperature to be sent, not omitted")
+	}
+	if body["max_tokens"] != float64(128) || body["seed"] != float64(42) || body["top_p"] == nil {
+		t.Errorf("unexpected sampling fields: %v", body)
+	}
+	if stop, _ := body["stop"].([]any); len(stop) != 1 || stop[0] != "END" {
+		t.Errorf("unexpected stop sequences: %v", body["stop"])
+	}
+}
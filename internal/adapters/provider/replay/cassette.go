@@ -0,0 +1,150 @@
+// Package replay records provider traffic to cassette files and plays it back,
+// so agent runs can be reproduced in tests without network access.
+package replay
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// cassetteVersion is bumped when the file format changes incompatibly.
+const cassetteVersion = 1
+
+// Mode selects whether a cassette is written or served.
+type Mode string
+
+const (
+	ModeRecord Mode = "record"
+	ModeReplay Mode = "replay"
+)
+
+// ParseMode parses a mode name; empty means replay.
+func ParseMode(s string) (Mode, error) {
+	switch Mode(strings.ToLower(strings.TrimSpace(s))) {
+	case "", ModeReplay:
+		return ModeReplay, nil
+	case ModeRecord:
+		return ModeRecord, nil
+	default:
+		return "", fmt.Errorf("invalid cassette mode '%s' (use record or replay)", s)
+	}
+}
+
+// Cassette is the on-disk recording of one run.
+type Cassette struct {
+	Version int `json:"version"`
+	// Provider is the name of the recorded provider, reported again on replay.
+	Provider string `json:"provider"`
+	// ToolCalling records whether the provider offered native tool calling,
+	// so the agent takes the same code path on replay.
+	ToolCalling  bool          `json:"tool_calling"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one request/response pair.
+type Interaction struct {
+	// Key hashes Request and is used to match calls on replay.
+	Key      string   `json:"key"`
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the recorded part of a ports.GenerateRequest. Tools are stored
+// by name only; their schemas are part of the code under test.
+type Request struct {
+	Model    string         `json:"model,omitempty"`
+	Prompt   string         `json:"prompt,omitempty"`
+	Messages []chat.Message `json:"messages,omitempty"`
+	ports.Sampling
+	Tools []string `json:"tools,omitempty"`
+	// Output is the name of the requested structured-output format, if any.
+	Output string `json:"output,omitempty"`
+	Stream bool   `json:"stream,omitempty"`
+}
+
+// Response is what the provider answered. Error is set instead when the call failed.
+type Response struct {
+	Text      string          `json:"text,omitempty"`
+	ToolCalls []chat.ToolCall `json:"tool_calls,omitempty"`
+	Usage     *ports.Usage    `json:"usage,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+func newRequest(req ports.GenerateRequest, stream bool) Request {
+	r := Request{
+		Model:    req.Model,
+		Prompt:   req.Prompt,
+		Messages: req.Messages,
+		Sampling: req.Sampling,
+		Stream:   stream,
+	}
+	for _, t := range req.Tools {
+		r.Tools = append(r.Tools, t.Name)
+	}
+	if req.Output != nil {
+		r.Output = req.Output.Name
+		if r.Output == "" {
+			r.Output = "json"
+		}
+	}
+	return r
+}
+
+// key hashes everything that changes the answer. Streaming and non-streaming
+// calls of the same request share a key.
+func (r Request) key() string {
+	r.Stream = false
+	payload, _ := json.Marshal(r)
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadCassette reads a cassette file.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	if c.Version != cassetteVersion {
+		return nil, fmt.Errorf("cassette %s has version %d, expected %d; re-record it", path, c.Version, cassetteVersion)
+	}
+	// Save indents tool arguments along with everything else; undo that.
+	for i := range c.Interactions {
+		for j, call := range c.Interactions[i].Response.ToolCalls {
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, call.Arguments); err == nil {
+				c.Interactions[i].Response.ToolCalls[j].Arguments = buf.Bytes()
+			}
+		}
+	}
+	return &c, nil
+}
+
+// Save writes the cassette atomically, indented so diffs stay reviewable.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
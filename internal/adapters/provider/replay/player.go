@@ -0,0 +1,129 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/provider/stream"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// ErrNoInteraction is returned when a cassette has no recorded answer left for a request.
+var ErrNoInteraction = errors.New("no recorded interaction matches the request")
+
+// Player serves responses from a cassette without touching the network.
+//
+// Each interaction is served once. A request is matched to the first unused
+// interaction with the same key; when none matches and the player is not
+// strict, the next unused interaction in recording order is served instead,
+// so cassettes survive harmless prompt changes (dates, OS, wording).
+type Player struct {
+	cassette *Cassette
+	path     string
+	strict   bool
+
+	mu   sync.Mutex
+	used []bool
+}
+
+// NewPlayer loads the cassette at path.
+func NewPlayer(path string, strict bool) (*Player, error) {
+	c, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Player{cassette: c, path: path, strict: strict, used: make([]bool, len(c.Interactions))}, nil
+}
+
+func (p *Player) Name() string {
+	if p.cassette.Provider == "" {
+		return "replay"
+	}
+	return p.cassette.Provider
+}
+
+func (p *Player) IsConfigured(ctx context.Context) error { return nil }
+
+func (p *Player) Close() error { return nil }
+
+func (p *Player) SupportsToolCalling() bool { return p.cassette.ToolCalling }
+
+// Remaining returns how many recorded interactions have not been served yet.
+func (p *Player) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.used) - p.servedLocked()
+}
+
+func (p *Player) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return ports.GenerateResponse{}, err
+	}
+	resp, err := p.next(newRequest(req, false))
+	if err != nil {
+		return ports.GenerateResponse{}, err
+	}
+	out := ports.GenerateResponse{Text: resp.Text, ToolCalls: resp.ToolCalls}
+	if resp.Usage != nil {
+		out.Usage = *resp.Usage
+	}
+	if resp.Error != "" {
+		return out, errors.New(resp.Error)
+	}
+	return out, nil
+}
+
+func (p *Player) StreamGenerate(ctx context.Context, req ports.GenerateRequest) (<-chan ports.StreamChunk, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	resp, err := p.next(newRequest(req, true))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" && resp.Text == "" && len(resp.ToolCalls) == 0 {
+		return nil, errors.New(resp.Error)
+	}
+
+	last := ports.StreamChunk{IsLast: true, ToolCalls: resp.ToolCalls, Usage: resp.Usage}
+	if resp.Error != "" {
+		last.Error = errors.New(resp.Error)
+	}
+	return stream.Text(ctx, resp.Text, &last), nil
+}
+
+func (p *Player) next(req Request) (Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := req.key()
+	for i, it := range p.cassette.Interactions {
+		if !p.used[i] && it.Key == key {
+			p.used[i] = true
+			return it.Response, nil
+		}
+	}
+	if !p.strict {
+		for i, it := range p.cassette.Interactions {
+			if !p.used[i] {
+				p.used[i] = true
+				return it.Response, nil
+			}
+		}
+	}
+	return Response{}, fmt.Errorf("%w in %s (%d of %d served)", ErrNoInteraction, p.path, p.servedLocked(), len(p.used))
+}
+
+func (p *Player) servedLocked() int {
+	n := 0
+	for _, u := range p.used {
+		if u {
+			n++
+		}
+	}
+	return n
+}
+
+var _ ports.Provider = (*Player)(nil)
@@ -0,0 +1,112 @@
+package replay
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// Recorder passes calls through to a real provider and appends every
+// request/response pair to a cassette. The file is rewritten after each call,
+// so an interrupted run still leaves a usable recording.
+type Recorder struct {
+	ports.Provider
+	tape *tape
+}
+
+// tape is the cassette shared by a recorder and the recorders derived from it with Wrap.
+type tape struct {
+	path   string
+	logger *slog.Logger
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder wraps inner. Any existing cassette at path is replaced.
+func NewRecorder(inner ports.Provider, path string, logger *slog.Logger) *Recorder {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Recorder{
+		Provider: inner,
+		tape: &tape{
+			path:   path,
+			logger: logger,
+			cassette: Cassette{
+				Version:     cassetteVersion,
+				Provider:    inner.Name(),
+				ToolCalling: ports.SupportsToolCalling(inner),
+			},
+		},
+	}
+}
+
+// Wrap returns a recorder for another provider that appends to the same cassette,
+// so calls routed to different models end up in one recording.
+func (r *Recorder) Wrap(inner ports.Provider) *Recorder {
+	return &Recorder{Provider: inner, tape: r.tape}
+}
+
+func (r *Recorder) SupportsToolCalling() bool { return ports.SupportsToolCalling(r.Provider) }
+
+func (r *Recorder) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
+	resp, err := r.Provider.Generate(ctx, req)
+	rec := Response{Text: resp.Text, ToolCalls: resp.ToolCalls}
+	if resp.Usage.TotalTokens() > 0 {
+		u := resp.Usage
+		rec.Usage = &u
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	r.append(ctx, newRequest(req, false), rec)
+	return resp, err
+}
+
+func (r *Recorder) StreamGenerate(ctx context.Context, req ports.GenerateRequest) (<-chan ports.StreamChunk, error) {
+	in, err := r.Provider.StreamGenerate(ctx, req)
+	if err != nil {
+		r.append(ctx, newRequest(req, true), Response{Error: err.Error()})
+		return nil, err
+	}
+
+	out := make(chan ports.StreamChunk)
+	go func() {
+		defer close(out)
+		var rec Response
+		var text strings.Builder
+		var calls []chat.ToolCall
+		for chunk := range in {
+			text.WriteString(chunk.Content)
+			calls = append(calls, chunk.ToolCalls...)
+			if chunk.Usage != nil {
+				rec.Usage = chunk.Usage
+			}
+			if chunk.Error != nil && rec.Error == "" {
+				rec.Error = chunk.Error.Error()
+			}
+			out <- chunk
+		}
+		rec.Text = text.String()
+		rec.ToolCalls = calls
+		r.append(ctx, newRequest(req, true), rec)
+	}()
+	return out, nil
+}
+
+func (r *Recorder) append(ctx context.Context, req Request, resp Response) {
+	t := r.tape
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cassette.Interactions = append(t.cassette.Interactions, Interaction{Key: req.key(), Request: req, Response: resp})
+	if err := t.cassette.Save(t.path); err != nil {
+		t.logger.WarnContext(ctx, "cassette write failed", "path", t.path, "error", err)
+	}
+}
+
+var _ ports.Provider = (*Recorder)(nil)
@@ -0,0 +1,186 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+type stubProvider struct {
+	texts []string
+	calls int
+}
+
+func (s *stubProvider) Name() string                           { return "stub" }
+func (s *stubProvider) IsConfigured(ctx context.Context) error { return nil }
+func (s *stubProvider) Close() error                           { return nil }
+func (s *stubProvider) SupportsToolCalling() bool              { return true }
+
+func (s *stubProvider) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
+	text := s.texts[s.calls]
+	s.calls++
+	if text == "fail" {
+		return ports.GenerateResponse{}, errors.New("upstream exploded")
+	}
+	return ports.GenerateResponse{
+		Text:      text,
+		ToolCalls: []chat.ToolCall{{ID: "call_0", Name: "read_file", Arguments: json.RawMessage(`{"path":"go.mod"}`)}},
+		Usage:     ports.Usage{Provider: "stub", Model: "m", PromptTokens: 7, CompletionTokens: 3},
+	}, nil
+}
+
+func (s *stubProvider) StreamGenerate(ctx context.Context, req ports.GenerateRequest) (<-chan ports.StreamChunk, error) {
+	text := s.texts[s.calls]
+	s.calls++
+	ch := make(chan ports.StreamChunk, 2)
+	ch <- ports.StreamChunk{Content: text[:2]}
+	ch <- ports.StreamChunk{Content: text[2:], IsLast: true, Usage: &ports.Usage{PromptTokens: 1, CompletionTokens: 2}}
+	close(ch)
+	return ch, nil
+}
+
+func drain(t *testing.T, ch <-chan ports.StreamChunk) (string, *ports.Usage) {
+	t.Helper()
+	var b strings.Builder
+	var usage *ports.Usage
+	for chunk := range ch {
+		if chunk.Error != nil {
+			t.Fatalf("unexpected chunk error: %v", chunk.Error)
+		}
+		b.WriteString(chunk.Content)
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+	}
+	return b.String(), usage
+}
+
+func record(t *testing.T, path string) {
+	t.Helper()
+	inner := &stubProvider{texts: []string{"first", "fail", "streamed answer"}}
+	rec := NewRecorder(inner, path, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ctx := context.Background()
+
+	tools := []ports.ToolSpec{{Name: "read_file"}}
+	if _, err := rec.Generate(ctx, ports.GenerateRequest{Prompt: "one", Tools: tools}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rec.Generate(ctx, ports.GenerateRequest{Prompt: "two"}); err == nil {
+		t.Fatal("expected the upstream error to pass through")
+	}
+	ch, err := rec.StreamGenerate(ctx, ports.GenerateRequest{Prompt: "three"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	drain(t, ch)
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	record(t, path)
+
+	player, err := NewPlayer(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if player.Name() != "stub" || !player.SupportsToolCalling() {
+		t.Errorf("expected provider traits to be recorded, got %q / %v", player.Name(), player.SupportsToolCalling())
+	}
+	ctx := context.Background()
+
+	// Replay out of order: matching is by request, not position.
+	ch, err := player.StreamGenerate(ctx, ports.GenerateRequest{Prompt: "three"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, usage := drain(t, ch)
+	if text != "streamed answer" || usage == nil || usage.CompletionTokens != 2 {
+		t.Errorf("unexpected stream replay: %q, %+v", text, usage)
+	}
+
+	resp, err := player.Generate(ctx, ports.GenerateRequest{Prompt: "one", Tools: []ports.ToolSpec{{Name: "read_file"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text != "first" || len(resp.ToolCalls) != 1 || string(resp.ToolCalls[0].Arguments) != `{"path":"go.mod"}` {
+		t.Errorf("unexpected replay: %+v", resp)
+	}
+	if resp.Usage.TotalTokens() != 10 {
+		t.Errorf("expected recorded usage, got %+v", resp.Usage)
+	}
+
+	if _, err := player.Generate(ctx, ports.GenerateRequest{Prompt: "two"}); err == nil || err.Error() != "upstream exploded" {
+		t.Errorf("expected the recorded error, got %v", err)
+	}
+	if player.Remaining() != 0 {
+		t.Errorf("expected every interaction to be served, %d left", player.Remaining())
+	}
+	if _, err := player.Generate(ctx, ports.GenerateRequest{Prompt: "one"}); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("expected ErrNoInteraction once the cassette is exhausted, got %v", err)
+	}
+}
+
+func TestReplay_StrictRejectsChangedRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	record(t, path)
+	ctx := context.Background()
+
+	strict, err := NewPlayer(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := strict.Generate(ctx, ports.GenerateRequest{Prompt: "one, reworded"}); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("expected strict replay to reject a changed prompt, got %v", err)
+	}
+
+	lenient, err := NewPlayer(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := lenient.Generate(ctx, ports.GenerateRequest{Prompt: "one, reworded"})
+	if err != nil || resp.Text != "first" {
+		t.Errorf("expected lenient replay to serve the next interaction in order, got %q (%v)", resp.Text, err)
+	}
+}
+
+func TestRecorder_WrapSharesCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	rec := NewRecorder(&stubProvider{texts: []string{"agent"}}, path, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	summarizer := rec.Wrap(&stubProvider{texts: []string{"summary"}})
+	ctx := context.Background()
+
+	if _, err := rec.Generate(ctx, ports.GenerateRequest{Prompt: "one"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := summarizer.Generate(ctx, ports.GenerateRequest{Prompt: "two"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Interactions) != 2 || c.Interactions[1].Response.Text != "summary" {
+		t.Errorf("expected both providers in one cassette, got %+v", c.Interactions)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for in, want := range map[string]Mode{"": ModeReplay, "Replay": ModeReplay, "record": ModeRecord} {
+		got, err := ParseMode(in)
+		if err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseMode("rewind"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
@@ -0,0 +1,38 @@
+// Package sampling decorates a provider with default sampling parameters.
+package sampling
+
+import (
+	"context"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// Provider fills the sampling parameters a request leaves unset. Values set by
+// the caller always win, so decorators can be stacked from the most specific
+// (use case) to the least specific (provider defaults).
+type Provider struct {
+	ports.Provider
+	defaults ports.Sampling
+}
+
+// Wrap returns inner unchanged when defaults is empty.
+func Wrap(inner ports.Provider, defaults ports.Sampling) ports.Provider {
+	if defaults.IsZero() {
+		return inner
+	}
+	return &Provider{Provider: inner, defaults: defaults}
+}
+
+func (p *Provider) SupportsToolCalling() bool { return ports.SupportsToolCalling(p.Provider) }
+
+func (p *Provider) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
+	req.Sampling = req.Sampling.WithDefaults(p.defaults)
+	return p.Provider.Generate(ctx, req)
+}
+
+func (p *Provider) StreamGenerate(ctx context.Context, req ports.GenerateRequest) (<-chan ports.StreamChunk, error) {
+	req.Sampling = req.Sampling.WithDefaults(p.defaults)
+	return p.Provider.StreamGenerate(ctx, req)
+}
+
+var _ ports.Provider = (*Provider)(nil)
@@ -0,0 +1,67 @@
+package sampling
+
+import (
+	"context"
+	"testing"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+type recordingProvider struct {
+	got ports.GenerateRequest
+}
+
+func (r *recordingProvider) Name() string                           { return "stub" }
+func (r *recordingProvider) IsConfigured(ctx context.Context) error { return nil }
+func (r *recordingProvider) Close() error                           { return nil }
+
+func (r *recordingProvider) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
+	r.got = req
+	return ports.GenerateResponse{}, nil
+}
+
+func (r *recordingProvider) StreamGenerate(ctx context.Context, req ports.GenerateRequest) (<-chan ports.StreamChunk, error) {
+	r.got = req
+	ch := make(chan ports.StreamChunk)
+	close(ch)
+	return ch, nil
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestWrap_LayersDefaults(t *testing.T) {
+	inner := &recordingProvider{}
+	providerDefaults := ports.Sampling{Temperature: ptr(float32(0.7)), MaxTokens: 2048, Seed: ptr(1)}
+	useCase := ports.Sampling{Temperature: ptr(float32(0.1)), Stop: []string{"\n\n"}}
+	p := Wrap(Wrap(inner, providerDefaults), useCase)
+
+	req := ports.GenerateRequest{Prompt: "x", Sampling: ports.Sampling{MaxTokens: 256}}
+	if _, err := p.Generate(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	got := inner.got.Sampling
+	if got.MaxTokens != 256 {
+		t.Errorf("request value must win, got max tokens %d", got.MaxTokens)
+	}
+	if got.Temperature == nil || *got.Temperature != 0.1 {
+		t.Errorf("use-case value must beat the provider default, got %v", got.Temperature)
+	}
+	if len(got.Stop) != 1 || got.Seed == nil || *got.Seed != 1 {
+		t.Errorf("unset fields must be filled from both layers, got %+v", got)
+	}
+
+	if _, err := p.StreamGenerate(context.Background(), ports.GenerateRequest{Prompt: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if inner.got.MaxTokens != 2048 {
+		t.Errorf("expected defaults on streamed requests, got %+v", inner.got.Sampling)
+	}
+}
+
+func TestWrap_NoDefaults(t *testing.T) {
+	inner := &recordingProvider{}
+	if Wrap(inner, ports.Sampling{}) != ports.Provider(inner) {
+		t.Error("expected inner provider to be returned unchanged")
+	}
+}
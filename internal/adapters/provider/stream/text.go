@@ -0,0 +1,41 @@
+// Package stream replays stored responses as provider streams.
+package stream
+
+import (
+	"context"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// chunkSize is the approximate size of the chunks Text emits.
+const chunkSize = 24
+
+// Text streams text in small chunks, then last when it is not nil. It stops
+// early when ctx is cancelled, so a consumer that stops reading doesn't leak
+// the goroutine.
+func Text(ctx context.Context, text string, last *ports.StreamChunk) <-chan ports.StreamChunk {
+	ch := make(chan ports.StreamChunk)
+	go func() {
+		defer close(ch)
+		for len(text) > 0 {
+			n := min(chunkSize, len(text))
+			// Don't split a multi-byte UTF-8 sequence.
+			for n < len(text) && text[n]&0xC0 == 0x80 {
+				n++
+			}
+			select {
+			case ch <- ports.StreamChunk{Content: text[:n]}:
+			case <-ctx.Done():
+				return
+			}
+			text = text[n:]
+		}
+		if last != nil {
+			select {
+			case ch <- *last:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,47 @@
+package stream
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+func TestText_ChunksWithoutSplittingRunes(t *testing.T) {
+	text := strings.Repeat("héllo wörld ✓ ", 10)
+	var got strings.Builder
+	var last ports.StreamChunk
+	for chunk := range Text(context.Background(), text, &ports.StreamChunk{IsLast: true}) {
+		if !utf8.ValidString(chunk.Content) {
+			t.Errorf("chunk splits a rune: %q", chunk.Content)
+		}
+		got.WriteString(chunk.Content)
+		last = chunk
+	}
+	if got.String() != text {
+		t.Errorf("got %q, want %q", got.String(), text)
+	}
+	if !last.IsLast {
+		t.Error("expected the last chunk at the end")
+	}
+}
+
+func TestText_StopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := Text(ctx, "short", &ports.StreamChunk{IsLast: true})
+	<-ch
+	cancel()
+
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("expected the stream to close after cancellation")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stream was never closed")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
@@ -72,6 +73,37 @@ func (s *Store) Save(sessionName string, state *ports.SessionState) error {
 	return os.Rename(tmp, path)
 }
 
+func (s *Store) List() ([]ports.SessionInfo, error) {
+	dir := filepath.Join(s.base(), "sessions")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var out []ports.SessionInfo
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if e.IsDir() || !ok {
+			continue
+		}
+		st, err := s.Load(name)
+		if err != nil {
+			continue // Skip unreadable files rather than failing the listing
+		}
+		out = append(out, ports.SessionInfo{
+			Name:        name,
+			Summary:     st.Summary,
+			RecentLines: len(st.Recent),
+			UpdatedAt:   st.UpdatedAt,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+	return out, nil
+}
+
 var safeNameRE = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
 
 func (s *Store) sessionPath(sessionName string) (string, error) {
@@ -84,11 +116,14 @@ func (s *Store) sessionPath(sessionName string) (string, error) {
 		name = name[:80]
 	}
 
-	base := s.baseDir
-	if strings.TrimSpace(base) == "" {
-		base = "."
+	return filepath.Join(s.base(), "sessions", name+".json"), nil
+}
+
+func (s *Store) base() string {
+	if strings.TrimSpace(s.baseDir) == "" {
+		return "."
 	}
-	return filepath.Join(base, "sessions", name+".json"), nil
+	return s.baseDir
 }
 
 var _ ports.SessionStore = (*Store)(nil)
@@ -0,0 +1,74 @@
+package terminal
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxHistory is how many lines of input history are kept.
+const maxHistory = 500
+
+// fileHistory is a bounded input history that appends every new entry to a file.
+// It implements term.History.
+type fileHistory struct {
+	path    string
+	max     int
+	entries []string // oldest first
+}
+
+// loadHistory reads the newest max entries from path. A missing file is an empty history.
+func loadHistory(path string, max int) *fileHistory {
+	h := &fileHistory{path: path, max: max}
+	if path == "" {
+		return h
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.TrimSpace(line) != "" {
+			h.push(line)
+		}
+	}
+	return h
+}
+
+// Add records entry unless it is blank or repeats the previous entry.
+func (h *fileHistory) Add(entry string) {
+	if strings.TrimSpace(entry) == "" || strings.Contains(entry, "\n") {
+		return
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1] == entry {
+		return
+	}
+	h.push(entry)
+	if h.path == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	_, _ = f.WriteString(entry + "\n")
+}
+
+func (h *fileHistory) Len() int { return len(h.entries) }
+
+// At returns the entry idx steps back; 0 is the newest.
+func (h *fileHistory) At(idx int) string { return h.entries[len(h.entries)-1-idx] }
+
+func (h *fileHistory) push(entry string) {
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > h.max {
+		h.entries = h.entries[len(h.entries)-h.max:]
+	}
+}
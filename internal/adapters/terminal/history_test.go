@@ -0,0 +1,35 @@
+package terminal
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/term"
+)
+
+var _ term.History = (*fileHistory)(nil)
+
+func TestFileHistory_PersistsAndBounds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vibe", "chat_history")
+
+	h := loadHistory(path, 3)
+	for _, line := range []string{"one", "two", "two", "  ", "three", "four"} {
+		h.Add(line)
+	}
+	if h.Len() != 3 || h.At(0) != "four" || h.At(2) != "two" {
+		t.Errorf("unexpected history: %v", h.entries)
+	}
+
+	reloaded := loadHistory(path, 3)
+	if reloaded.Len() != 3 || reloaded.At(0) != "four" || reloaded.At(2) != "two" {
+		t.Errorf("expected the newest entries after reload, got %v", reloaded.entries)
+	}
+}
+
+func TestFileHistory_InMemory(t *testing.T) {
+	h := loadHistory("", 10)
+	h.Add("ls")
+	if h.Len() != 1 || h.At(0) != "ls" {
+		t.Errorf("unexpected history: %v", h.entries)
+	}
+}
@@ -0,0 +1,91 @@
+// Package terminal reads interactive input with line editing and history.
+package terminal
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// LineReader reads one line of user input at a time.
+type LineReader interface {
+	// ReadLine shows prompt and returns the line without its newline.
+	// It returns io.EOF when input ends (Ctrl+D on an empty line, Ctrl+C, closed stdin).
+	ReadLine(prompt string) (string, error)
+	// ReadAnswer reads a reply to a question, such as a confirmation. Unlike
+	// ReadLine it keeps the reply out of the history.
+	ReadAnswer(prompt string) (string, error)
+}
+
+// NewLineReader returns a line editor (arrow keys, word jumps, history) when stdin
+// is a terminal, and a plain line reader otherwise. History is kept in historyPath;
+// an empty path keeps it in memory only.
+func NewLineReader(historyPath string) LineReader {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return &plainReader{in: bufio.NewReader(os.Stdin)}
+	}
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, "")
+	t.History = loadHistory(historyPath, maxHistory)
+	return &editor{fd: fd, term: t}
+}
+
+// editor switches the terminal to raw mode only while a line is read, so the
+// rest of the program can keep printing normally.
+type editor struct {
+	fd   int
+	term *term.Terminal
+}
+
+func (e *editor) ReadLine(prompt string) (string, error) {
+	return e.read(prompt)
+}
+
+func (e *editor) ReadAnswer(prompt string) (string, error) {
+	history := e.term.History
+	e.term.History = loadHistory("", 0)
+	defer func() { e.term.History = history }()
+	return e.read(prompt)
+}
+
+func (e *editor) read(prompt string) (string, error) {
+	state, err := term.MakeRaw(e.fd)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = term.Restore(e.fd, state) }()
+
+	if w, h, err := term.GetSize(e.fd); err == nil {
+		_ = e.term.SetSize(w, h)
+	}
+	e.term.SetPrompt(prompt)
+	line, err := e.term.ReadLine()
+	if errors.Is(err, term.ErrPasteIndicator) {
+		err = nil
+	}
+	return line, err
+}
+
+type plainReader struct {
+	in *bufio.Reader
+}
+
+func (p *plainReader) ReadLine(prompt string) (string, error) {
+	_, _ = io.WriteString(os.Stdout, prompt)
+	line, err := p.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (p *plainReader) ReadAnswer(prompt string) (string, error) {
+	return p.ReadLine(prompt)
+}
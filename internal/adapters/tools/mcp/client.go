@@ -0,0 +1,197 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/pkg/config"
+)
+
+const (
+	// defaultTimeout bounds connecting to a server and listing its tools.
+	defaultTimeout = 30 * time.Second
+	// closeGrace is how long a server gets to exit or end its session.
+	closeGrace = 2 * time.Second
+	// maxToolPages stops listing tools from a server that keeps returning cursors.
+	maxToolPages = 100
+)
+
+// transport carries JSON-RPC messages to one server.
+type transport interface {
+	// send delivers a request and waits for its response.
+	send(ctx context.Context, req message) (message, error)
+	// notify delivers a notification.
+	notify(ctx context.Context, n message) error
+	close() error
+}
+
+// Client is a connection to one MCP server. It is safe for concurrent use.
+type Client struct {
+	name      string
+	transport transport
+	nextID    atomic.Int64
+	// Server is the name and version the server reported on initialize.
+	Server string
+}
+
+// Connect starts or dials the server described by cfg and performs the
+// initialize handshake. HTTP servers are reached through httpClient, or Go's
+// default client when it is nil. The returned client must be closed.
+func Connect(ctx context.Context, name string, cfg config.MCPServerConfig, version string, httpClient *http.Client) (*Client, error) {
+	var t transport
+	switch {
+	case strings.TrimSpace(cfg.Command) != "" && strings.TrimSpace(cfg.URL) != "":
+		return nil, fmt.Errorf("mcp server '%s': set either command or url, not both", name)
+	case strings.TrimSpace(cfg.Command) != "":
+		stdio, err := startStdio(cfg.Command, cfg.Args, cfg.Env)
+		if err != nil {
+			return nil, fmt.Errorf("mcp server '%s': %w", name, err)
+		}
+		t = stdio
+	case strings.TrimSpace(cfg.URL) != "":
+		t = newHTTP(cfg.URL, cfg.Headers, httpClient)
+	default:
+		return nil, fmt.Errorf("mcp server '%s': command or url is required", name)
+	}
+
+	c := &Client{name: name, transport: t}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	initCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := c.initialize(initCtx, version); err != nil {
+		_ = t.close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Name is the server's name in the configuration.
+func (c *Client) Name() string {
+	return c.name
+}
+
+func (c *Client) initialize(ctx context.Context, version string) error {
+	var result initializeResult
+	err := c.call(ctx, "initialize", initializeParams{
+		ProtocolVersion: protocolVersion,
+		Capabilities:    map[string]any{},
+		ClientInfo:      clientInfo{Name: "vibe", Version: version},
+	}, &result)
+	if err != nil {
+		return err
+	}
+	c.Server = strings.TrimSpace(result.ServerInfo.Name + " " + result.ServerInfo.Version)
+	if err := c.transport.notify(ctx, newNotification("notifications/initialized")); err != nil {
+		return fmt.Errorf("mcp server '%s': %w", c.name, err)
+	}
+	return nil
+}
+
+// Tools lists the server's tools, following pagination cursors.
+func (c *Client) Tools(ctx context.Context) ([]*Tool, error) {
+	var tools []*Tool
+	cursor := ""
+	for page := 0; page < maxToolPages; page++ {
+		var result listToolsResult
+		if err := c.call(ctx, "tools/list", listToolsParams{Cursor: cursor}, &result); err != nil {
+			return nil, err
+		}
+		for _, info := range result.Tools {
+			tools = append(tools, newTool(c, info))
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		cursor = result.NextCursor
+	}
+	return tools, nil
+}
+
+// callTool invokes the named tool with arguments, a JSON object.
+func (c *Client) callTool(ctx context.Context, name string, arguments json.RawMessage) (callToolResult, error) {
+	var result callToolResult
+	err := c.call(ctx, "tools/call", callToolParams{Name: name, Arguments: arguments}, &result)
+	return result, err
+}
+
+// call sends a request and decodes its result into out.
+func (c *Client) call(ctx context.Context, method string, params, out any) error {
+	req, err := newRequest(c.nextID.Add(1), method, params)
+	if err != nil {
+		return err
+	}
+	resp, err := c.transport.send(ctx, req)
+	if err != nil {
+		return fmt.Errorf("mcp server '%s': %s: %w", c.name, method, err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("mcp server '%s': %s: %w", c.name, method, resp.Error)
+	}
+	if len(resp.Result) == 0 {
+		return fmt.Errorf("mcp server '%s': %s: empty response", c.name, method)
+	}
+	if err := json.Unmarshal(resp.Result, out); err != nil {
+		return fmt.Errorf("mcp server '%s': %s: invalid result: %w", c.name, method, err)
+	}
+	return nil
+}
+
+// Close disconnects from the server, stopping it when vibe started it.
+func (c *Client) Close() error {
+	return c.transport.close()
+}
+
+// Load connects to every enabled server in servers and returns their tools.
+// A server that fails is reported in the returned error and skipped, so one
+// broken server does not take the others down. HTTP servers are reached
+// through httpClient, as in Connect. Close the returned clients when done.
+func Load(ctx context.Context, servers map[string]config.MCPServerConfig, version string, httpClient *http.Client) ([]*Client, []*Tool, error) {
+	var clients []*Client
+	var tools []*Tool
+	var errs []error
+	for _, name := range sortedNames(servers) {
+		cfg := servers[name]
+		if cfg.Disabled {
+			continue
+		}
+		c, err := Connect(ctx, name, cfg, version, httpClient)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		timeout := cfg.Timeout
+		if timeout <= 0 {
+			timeout = defaultTimeout
+		}
+		listCtx, cancel := context.WithTimeout(ctx, timeout)
+		listed, err := c.Tools(listCtx)
+		cancel()
+		if err != nil {
+			_ = c.Close()
+			errs = append(errs, err)
+			continue
+		}
+		clients = append(clients, c)
+		tools = append(tools, listed...)
+	}
+	return clients, tools, errors.Join(errs...)
+}
+
+func sortedNames(servers map[string]config.MCPServerConfig) []string {
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,193 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// sessionHeader carries the session ID a Streamable HTTP server assigns on
+// initialize.
+const sessionHeader = "Mcp-Session-Id"
+
+// httpTransport talks to a Streamable HTTP server: every message is a POST,
+// answered with either a JSON body or an SSE stream that ends with the
+// response.
+type httpTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+// newHTTP returns a transport sending through client, or Go's default client
+// when it is nil.
+func newHTTP(url string, headers map[string]string, client *http.Client) *httpTransport {
+	expanded := make(map[string]string, len(headers))
+	for k, v := range headers {
+		expanded[k] = os.ExpandEnv(v)
+	}
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &httpTransport{url: url, headers: expanded, client: client}
+}
+
+func (t *httpTransport) send(ctx context.Context, req message) (message, error) {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return message{}, err
+	}
+	defer resp.Body.Close()
+	if id := resp.Header.Get(sessionHeader); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/event-stream" {
+		var m message
+		if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+			return message{}, fmt.Errorf("invalid response from %s: %w", t.url, err)
+		}
+		return m, nil
+	}
+
+	// Skip anything the server streams before the response, and answer its
+	// requests out of band.
+	for data, err := range sseData(resp.Body) {
+		if err != nil {
+			return message{}, err
+		}
+		var m message
+		if json.Unmarshal(data, &m) != nil {
+			continue
+		}
+		if m.isRequest() {
+			t.reply(ctx, m)
+			continue
+		}
+		if bytes.Equal(m.ID, req.ID) {
+			return m, nil
+		}
+	}
+	return message{}, fmt.Errorf("%s closed the stream without a response", t.url)
+}
+
+func (t *httpTransport) notify(ctx context.Context, n message) error {
+	resp, err := t.post(ctx, n)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (t *httpTransport) reply(ctx context.Context, req message) {
+	if resp, err := t.post(ctx, replyTo(req)); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// post sends m and returns the response when its status is a success.
+func (t *httpTransport) post(ctx context.Context, m message) (*http.Response, error) {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json, text/event-stream")
+	httpReq.Header.Set("MCP-Protocol-Version", protocolVersion)
+	for k, v := range t.headers {
+		httpReq.Header.Set(k, v)
+	}
+	t.mu.Lock()
+	if t.sessionID != "" {
+		httpReq.Header.Set(sessionHeader, t.sessionID)
+	}
+	t.mu.Unlock()
+
+	resp, err := t.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%s returned %s: %s", t.url, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return resp, nil
+}
+
+// close ends the server's session, if it gave one.
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), closeGrace)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(sessionHeader, sessionID)
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// sseData yields the data of each event in an SSE stream.
+func sseData(r io.Reader) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		var data []byte
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "" {
+				if len(data) > 0 && !yield(data, nil) {
+					return
+				}
+				data = nil
+				continue
+			}
+			if value, ok := strings.CutPrefix(line, "data:"); ok {
+				if len(data) > 0 {
+					data = append(data, '\n')
+				}
+				data = append(data, strings.TrimPrefix(value, " ")...)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(nil, err)
+			return
+		}
+		if len(data) > 0 {
+			yield(data, nil)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/provider/httpclient"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+	"github.com/phamdaiminhquan/vibe-devops/pkg/config"
+)
+
+// stubEnv makes the test binary act as a stdio MCP server.
+const stubEnv = "VIBE_MCP_STUB"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(stubEnv) == "1" {
+		serveStub(os.Stdin, os.Stdout)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// serveStub answers requests with stubResponse until stdin closes. Before
+// listing tools it pings the client, which must answer.
+func serveStub(in *os.File, out *os.File) {
+	scanner := bufio.NewScanner(in)
+	enc := json.NewEncoder(out)
+	pinged := false
+	for scanner.Scan() {
+		var m message
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil || len(m.ID) == 0 {
+			continue
+		}
+		if m.Method == "" {
+			continue // the client's answer to our ping
+		}
+		if m.Method == "tools/list" && !pinged {
+			pinged = true
+			_ = enc.Encode(message{JSONRPC: "2.0", ID: json.RawMessage(`"ping-1"`), Method: "ping"})
+		}
+		_ = enc.Encode(stubResponse(m))
+	}
+}
+
+// stubResponse is the stub server's answer to req: two pages of tools, an
+// echo tool and a tool that always fails.
+func stubResponse(req message) message {
+	resp := message{JSONRPC: "2.0", ID: req.ID}
+	var result any
+	switch req.Method {
+	case "initialize":
+		result = map[string]any{
+			"protocolVersion": protocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "stub", "version": "1.0"},
+		}
+	case "tools/list":
+		var params listToolsParams
+		_ = json.Unmarshal(req.Params, &params)
+		if params.Cursor == "" {
+			result = map[string]any{
+				"tools": []map[string]any{{
+					"name":        "echo",
+					"description": "Echo the message back.",
+					"inputSchema": map[string]any{
+						"type":       "object",
+						"properties": map[string]any{"message": map[string]any{"type": "string"}},
+						"required":   []string{"message"},
+					},
+					"annotations": map[string]any{"readOnlyHint": true},
+				}},
+				"nextCursor": "page-2",
+			}
+		} else {
+			result = map[string]any{
+				"tools": []map[string]any{{
+					"name":        "restart.service",
+					"inputSchema": map[string]any{"type": "object"},
+					"annotations": map[string]any{"readOnlyHint": false, "destructiveHint": true},
+				}},
+			}
+		}
+	case "tools/call":
+		var params struct {
+			Name      string            `json:"name"`
+			Arguments map[string]string `json:"arguments"`
+		}
+		_ = json.Unmarshal(req.Params, &params)
+		switch params.Name {
+		case "echo":
+			result = map[string]any{"content": []map[string]any{{"type": "text", "text": "echo: " + params.Arguments["message"]}}}
+		default:
+			result = map[string]any{"content": []map[string]any{{"type": "text", "text": "permission denied"}}, "isError": true}
+		}
+	default:
+		resp.Error = &rpcError{Code: codeMethodNotFound, Message: "method not found"}
+		return resp
+	}
+	resp.Result, _ = json.Marshal(result)
+	return resp
+}
+
+// stubServer configures the test binary as a stdio server.
+func stubServer(t *testing.T) config.MCPServerConfig {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.MCPServerConfig{Command: exe, Env: map[string]string{stubEnv: "1"}}
+}
+
+func loadStub(t *testing.T) map[string]*Tool {
+	t.Helper()
+	clients, tools, err := Load(context.Background(), map[string]config.MCPServerConfig{"stub": stubServer(t)}, "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for _, c := range clients {
+			_ = c.Close()
+		}
+	})
+	if len(clients) != 1 || clients[0].Server != "stub 1.0" {
+		t.Fatalf("unexpected clients %+v", clients)
+	}
+	byName := map[string]*Tool{}
+	for _, tool := range tools {
+		byName[tool.Definition().Name] = tool
+	}
+	return byName
+}
+
+func TestLoad_Stdio(t *testing.T) {
+	tools := loadStub(t)
+	if len(tools) != 2 {
+		t.Fatalf("expected the tools of both pages, got %v", tools)
+	}
+
+	echo := tools["stub__echo"]
+	if echo == nil {
+		t.Fatal("stub__echo not listed")
+	}
+	def := echo.Definition()
+	if !def.ReadOnly || def.DefaultPolicy != ports.PolicyAllowed || def.Group != Group {
+		t.Errorf("expected a read-only allowed tool, got %+v", def)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(def.InputSchema), &schema); err != nil || schema["required"] == nil {
+		t.Errorf("expected the server's input schema, got %s", def.InputSchema)
+	}
+
+	restart := tools["stub__restart_service"]
+	if restart == nil {
+		t.Fatal("stub__restart_service not listed")
+	}
+	if restart.Definition().ReadOnly || restart.EvaluatePolicy(nil) != ports.PolicyWithPermission {
+		t.Errorf("expected a tool that asks first, got %+v", restart.Definition())
+	}
+}
+
+func TestTool_Run(t *testing.T) {
+	tools := loadStub(t)
+
+	// Read-only tools run in parallel, so calls share the connection.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			msg := fmt.Sprintf("hello %d", i)
+			input, _ := json.Marshal(map[string]string{"message": msg})
+			result, err := tools["stub__echo"].Run(context.Background(), input, ports.ToolExtras{})
+			if err != nil || result.IsError || result.Content != "echo: "+msg {
+				t.Errorf("unexpected result %+v, %v", result, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	result, err := tools["stub__restart_service"].Run(context.Background(), nil, ports.ToolExtras{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsError || result.Content != "permission denied" {
+		t.Errorf("expected the server's error result, got %+v", result)
+	}
+}
+
+func TestLoad_SkipsBrokenServer(t *testing.T) {
+	servers := map[string]config.MCPServerConfig{
+		"broken":   {Command: "/does/not/exist"},
+		"empty":    {},
+		"disabled": {Command: "/does/not/exist", Disabled: true},
+		"stub":     stubServer(t),
+	}
+	clients, tools, err := Load(context.Background(), servers, "test", nil)
+	for _, c := range clients {
+		defer c.Close()
+	}
+	if err == nil || !strings.Contains(err.Error(), "'broken'") || !strings.Contains(err.Error(), "'empty'") {
+		t.Errorf("expected errors for the broken servers, got %v", err)
+	}
+	if strings.Contains(fmt.Sprint(err), "disabled") {
+		t.Errorf("disabled servers should be skipped, got %v", err)
+	}
+	if len(clients) != 1 || len(tools) != 2 {
+		t.Errorf("expected the working server's tools, got %d clients and %d tools", len(clients), len(tools))
+	}
+}
+
+func TestLoad_HTTP(t *testing.T) {
+	var mu sync.Mutex
+	var sessions []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			return
+		}
+		var m message
+		_ = json.NewDecoder(r.Body).Decode(&m)
+		mu.Lock()
+		sessions = append(sessions, r.Header.Get(sessionHeader))
+		mu.Unlock()
+		if len(m.ID) == 0 {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		data, _ := json.Marshal(stubResponse(m))
+		if m.Method == "initialize" {
+			w.Header().Set(sessionHeader, "s1")
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(data)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: message\ndata: %s\n\n", `{"jsonrpc":"2.0","method":"notifications/progress"}`)
+		fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+	}))
+	defer srv.Close()
+
+	t.Setenv("STUB_TOKEN", "abc")
+	servers := map[string]config.MCPServerConfig{
+		"remote": {URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer ${STUB_TOKEN}"}},
+	}
+	clients, tools, err := Load(context.Background(), servers, "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clients[0].Close()
+	if len(tools) != 2 || tools[0].Definition().Name != "remote__echo" {
+		t.Fatalf("unexpected tools %v", tools)
+	}
+	result, err := tools[0].Run(context.Background(), json.RawMessage(`{"message":"hi"}`), ports.ToolExtras{})
+	if err != nil || result.Content != "echo: hi" {
+		t.Errorf("unexpected result %+v, %v", result, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if sessions[0] != "" || sessions[len(sessions)-1] != "s1" {
+		t.Errorf("expected the session ID after initialize, got %q", sessions)
+	}
+}
+
+func TestLoad_HTTPClientReadTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m message
+		_ = json.NewDecoder(r.Body).Decode(&m)
+		if len(m.ID) == 0 || r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		if m.Method == "initialize" {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(stubResponse(m))
+			return
+		}
+		// Start the stream, then stall.
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client, err := httpclient.New(httpclient.Options{ReadTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := map[string]config.MCPServerConfig{"stalled": {URL: srv.URL}}
+	start := time.Now()
+	clients, _, err := Load(context.Background(), servers, "test", client)
+	if err == nil || !strings.Contains(err.Error(), "'stalled'") || len(clients) != 0 {
+		t.Errorf("expected the stalled server to fail, got %d clients, %v", len(clients), err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the read timeout to end the call, took %s", elapsed)
+	}
+}
+
+func TestToolName(t *testing.T) {
+	cases := map[string]string{
+		"read_file":             "fs__read_file",
+		"issues.search":         "fs__issues_search",
+		"list dirs/files":       "fs__list_dirs_files",
+		strings.Repeat("x", 80): "fs__" + strings.Repeat("x", 60),
+	}
+	for in, want := range cases {
+		if got := toolName("fs", in); got != want {
+			t.Errorf("toolName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Package mcp connects to Model Context Protocol servers and offers their
+// tools to the agent as ports.Tool values.
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// protocolVersion is the MCP revision the client asks for; servers answer
+// with the revision they speak.
+const protocolVersion = "2025-06-18"
+
+// JSON-RPC error codes answered to server requests.
+const (
+	codeMethodNotFound = -32601
+)
+
+// message is a JSON-RPC 2.0 request, notification or response. ID is empty on
+// notifications, and kept raw so requests from the server can be answered
+// with the ID they came with.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// isRequest reports whether m is a request from the server.
+func (m message) isRequest() bool {
+	return m.Method != "" && len(m.ID) > 0
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+func newRequest(id int64, method string, params any) (message, error) {
+	m := message{JSONRPC: "2.0", ID: json.RawMessage(strconv.FormatInt(id, 10)), Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return message{}, err
+		}
+		m.Params = raw
+	}
+	return m, nil
+}
+
+func newNotification(method string) message {
+	return message{JSONRPC: "2.0", Method: method}
+}
+
+type clientInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type initializeParams struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	Capabilities    map[string]any `json:"capabilities"`
+	ClientInfo      clientInfo     `json:"clientInfo"`
+}
+
+type initializeResult struct {
+	ProtocolVersion string     `json:"protocolVersion"`
+	ServerInfo      clientInfo `json:"serverInfo"`
+}
+
+type listToolsParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+type listToolsResult struct {
+	Tools      []toolInfo `json:"tools"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// toolInfo is a tool as listed by a server.
+type toolInfo struct {
+	Name        string          `json:"name"`
+	Title       string          `json:"title,omitempty"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+	Annotations *annotations    `json:"annotations,omitempty"`
+}
+
+// annotations are the server's hints about a tool's behaviour. They are not
+// guarantees; vibe only uses them to pick a default policy.
+type annotations struct {
+	Title        string `json:"title,omitempty"`
+	ReadOnlyHint *bool  `json:"readOnlyHint,omitempty"`
+}
+
+type callToolParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type callToolResult struct {
+	Content           []content       `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError,omitempty"`
+}
+
+// content is one item of a tool result: text, image, audio, resource_link or
+// an embedded resource.
+type content struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	MimeType string    `json:"mimeType,omitempty"`
+	URI      string    `json:"uri,omitempty"`
+	Resource *resource `json:"resource,omitempty"`
+}
+
+type resource struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+}
+
+// replyTo answers a request from the server. The client offers no
+// capabilities, so it only answers pings.
+func replyTo(req message) message {
+	if req.Method == "ping" {
+		return message{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage("{}")}
+	}
+	return message{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}}
+}
@@ -0,0 +1,183 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxStderr is how much of a server's stderr is kept for error messages.
+const maxStderr = 4096
+
+// stdioTransport talks to a server started as a subprocess, one JSON message
+// per line on its stdin and stdout. Requests may be in flight concurrently;
+// responses are matched to them by ID.
+type stdioTransport struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr *tailBuffer
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]chan message
+	err     error // set once stdout is closed
+	done    chan struct{}
+}
+
+func startStdio(command string, args []string, env map[string]string) (*stdioTransport, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+os.ExpandEnv(v))
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	t := &stdioTransport{
+		cmd:     cmd,
+		stdin:   stdin,
+		stderr:  &tailBuffer{max: maxStderr},
+		pending: map[string]chan message{},
+		done:    make(chan struct{}),
+	}
+	cmd.Stderr = t.stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start '%s': %w", command, err)
+	}
+	go t.read(stdout)
+	return t, nil
+}
+
+// read dispatches the server's messages until stdout closes.
+func (t *stdioTransport) read(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var m message
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			continue // servers may log non-JSON lines by mistake
+		}
+		switch {
+		case m.isRequest():
+			_ = t.write(replyTo(m))
+		case len(m.ID) > 0:
+			t.mu.Lock()
+			ch, ok := t.pending[string(m.ID)]
+			delete(t.pending, string(m.ID))
+			t.mu.Unlock()
+			if ok {
+				ch <- m
+			}
+		}
+	}
+
+	err := scanner.Err()
+	if err == nil {
+		err = errors.New("server closed the connection")
+	}
+	if stderr := strings.TrimSpace(t.stderr.String()); stderr != "" {
+		err = fmt.Errorf("%w: %s", err, stderr)
+	}
+	t.mu.Lock()
+	t.err = err
+	t.pending = map[string]chan message{}
+	t.mu.Unlock()
+	close(t.done)
+}
+
+func (t *stdioTransport) send(ctx context.Context, req message) (message, error) {
+	ch := make(chan message, 1)
+	key := string(req.ID)
+	t.mu.Lock()
+	if t.err != nil {
+		t.mu.Unlock()
+		return message{}, t.err
+	}
+	t.pending[key] = ch
+	t.mu.Unlock()
+
+	if err := t.write(req); err != nil {
+		t.forget(key)
+		return message{}, err
+	}
+	select {
+	case m := <-ch:
+		return m, nil
+	case <-t.done:
+		return message{}, t.err
+	case <-ctx.Done():
+		t.forget(key)
+		return message{}, ctx.Err()
+	}
+}
+
+func (t *stdioTransport) notify(_ context.Context, n message) error {
+	return t.write(n)
+}
+
+func (t *stdioTransport) write(m message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err = t.stdin.Write(append(data, '\n'))
+	return err
+}
+
+func (t *stdioTransport) forget(key string) {
+	t.mu.Lock()
+	delete(t.pending, key)
+	t.mu.Unlock()
+}
+
+// close closes the server's stdin, which asks it to exit, and kills it if it
+// is still running shortly after.
+func (t *stdioTransport) close() error {
+	_ = t.stdin.Close()
+	select {
+	case <-t.done:
+	case <-time.After(closeGrace):
+		_ = t.cmd.Process.Kill()
+	}
+	_ = t.cmd.Wait()
+	return nil
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		b.buf = b.buf[len(b.buf)-b.max:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
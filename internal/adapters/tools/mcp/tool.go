@@ -0,0 +1,159 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+const (
+	// Group is the tool group of every MCP tool.
+	Group = "mcp"
+	// maxNameLen is the longest function name the providers accept.
+	maxNameLen = 64
+	// maxOutput caps the text of a tool result sent back to the model.
+	maxOutput = 16000
+)
+
+// Tool is a tool listed by an MCP server. Its name is prefixed with the
+// server's, e.g. "github__search_issues", so tools of different servers never
+// clash with each other or with vibe's own tools.
+type Tool struct {
+	client *Client
+	info   toolInfo
+	def    ports.ToolDefinition
+}
+
+func newTool(c *Client, info toolInfo) *Tool {
+	title := info.Title
+	if info.Annotations != nil && info.Annotations.Title != "" {
+		title = info.Annotations.Title
+	}
+	if title == "" {
+		title = info.Name
+	}
+	description := strings.TrimSpace(info.Description)
+	if description == "" {
+		description = title + "."
+	}
+	schema := `{"type": "object", "properties": {}}`
+	if len(bytes.TrimSpace(info.InputSchema)) > 0 {
+		schema = string(info.InputSchema)
+	}
+
+	// Only a tool the server marks read-only runs without asking.
+	readOnly := info.Annotations != nil && info.Annotations.ReadOnlyHint != nil && *info.Annotations.ReadOnlyHint
+	policy := ports.PolicyWithPermission
+	if readOnly {
+		policy = ports.PolicyAllowed
+	}
+
+	return &Tool{
+		client: c,
+		info:   info,
+		def: ports.ToolDefinition{
+			Name:          toolName(c.name, info.Name),
+			DisplayTitle:  fmt.Sprintf("%s (%s)", title, c.name),
+			Description:   fmt.Sprintf("%s (MCP server '%s')", description, c.name),
+			WouldLikeTo:   fmt.Sprintf("call %s on MCP server %s", info.Name, c.name),
+			IsCurrently:   fmt.Sprintf("calling %s on %s", info.Name, c.name),
+			HasAlready:    fmt.Sprintf("called %s on %s", info.Name, c.name),
+			ReadOnly:      readOnly,
+			InputSchema:   schema,
+			DefaultPolicy: policy,
+			Group:         Group,
+		},
+	}
+}
+
+// Definition returns the tool metadata
+func (t *Tool) Definition() ports.ToolDefinition {
+	return t.def
+}
+
+// EvaluatePolicy returns the default policy: the server's hints don't depend
+// on the input.
+func (t *Tool) EvaluatePolicy(input json.RawMessage) ports.ToolPolicy {
+	return t.def.DefaultPolicy
+}
+
+// Run calls the tool on its server. A result the server flags as an error is
+// returned as an error result for the agent to read.
+func (t *Tool) Run(ctx context.Context, input json.RawMessage, extras ports.ToolExtras) (ports.ToolResult, error) {
+	arguments := bytes.TrimSpace(input)
+	if len(arguments) == 0 || bytes.Equal(arguments, []byte("null")) {
+		arguments = []byte("{}")
+	}
+	if extras.OnPartialOutput != nil {
+		extras.OnPartialOutput(ports.PartialOutput{Content: t.def.IsCurrently, Status: "executing"})
+	}
+
+	result, err := t.client.callTool(ctx, t.info.Name, arguments)
+	if err != nil {
+		return ports.ToolResult{IsError: true, Content: err.Error(), Status: "failed"}, err
+	}
+	out := ports.ToolResult{Content: renderContent(result), Status: "completed"}
+	if result.IsError {
+		out.IsError, out.Status = true, "failed"
+	}
+	return out, nil
+}
+
+// renderContent joins the result's content as text. Binary items are
+// described rather than inlined.
+func renderContent(result callToolResult) string {
+	var parts []string
+	for _, c := range result.Content {
+		switch c.Type {
+		case "text":
+			parts = append(parts, c.Text)
+		case "image", "audio":
+			parts = append(parts, fmt.Sprintf("[%s: %s]", c.Type, c.MimeType))
+		case "resource_link":
+			parts = append(parts, fmt.Sprintf("[resource: %s]", c.URI))
+		case "resource":
+			if c.Resource == nil {
+				continue
+			}
+			if c.Resource.Text != "" {
+				parts = append(parts, c.Resource.Text)
+			} else {
+				parts = append(parts, fmt.Sprintf("[resource: %s]", c.Resource.URI))
+			}
+		}
+	}
+	if len(parts) == 0 && len(result.StructuredContent) > 0 {
+		parts = append(parts, string(result.StructuredContent))
+	}
+	output := strings.Join(parts, "\n")
+	if len(output) > maxOutput {
+		output = output[:maxOutput] + "\n...(truncated)"
+	}
+	return output
+}
+
+// toolName builds "<server>__<tool>" from the characters function names
+// allow, cut to the providers' length limit.
+func toolName(server, tool string) string {
+	clean := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+				return r
+			default:
+				return '_'
+			}
+		}, s)
+	}
+	name := clean(server) + "__" + clean(tool)
+	if len(name) > maxNameLen {
+		name = name[:maxNameLen]
+	}
+	return name
+}
+
+var _ ports.Tool = (*Tool)(nil)
@@ -0,0 +1,85 @@
+package mcp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/phamdaiminhquan/vibe-devops/pkg/config"
+)
+
+// LocalServers returns the enabled servers that vibe starts as local
+// processes. A project's configuration can name any command there, so they
+// only start once the user trusts them.
+func LocalServers(servers map[string]config.MCPServerConfig) map[string]config.MCPServerConfig {
+	local := map[string]config.MCPServerConfig{}
+	for name, cfg := range servers {
+		if !cfg.Disabled && strings.TrimSpace(cfg.Command) != "" {
+			local[name] = cfg
+		}
+	}
+	return local
+}
+
+// CommandLines describes servers for a trust prompt, one "name: command args"
+// line per server.
+func CommandLines(servers map[string]config.MCPServerConfig) []string {
+	lines := make([]string, 0, len(servers))
+	for _, name := range sortedNames(servers) {
+		cfg := servers[name]
+		lines = append(lines, name+": "+strings.Join(append([]string{cfg.Command}, cfg.Args...), " "))
+	}
+	return lines
+}
+
+// TrustStore remembers, per project directory, the settings the user agreed
+// to trust, such as the local servers to start. Any change to them asks again.
+type TrustStore struct {
+	path string
+}
+
+// NewTrustStore returns a store kept in the JSON file at path.
+func NewTrustStore(path string) *TrustStore {
+	return &TrustStore{path: path}
+}
+
+// Trusted reports whether the user trusted exactly these settings for project.
+func (s *TrustStore) Trusted(project string, settings any) bool {
+	return s.load()[project] == fingerprint(settings)
+}
+
+// Trust records that the user trusts settings for project.
+func (s *TrustStore) Trust(project string, settings any) error {
+	trusted := s.load()
+	trusted[project] = fingerprint(settings)
+	data, err := json.MarshalIndent(trusted, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0o600)
+}
+
+// load reads the store; a missing or unreadable file trusts nothing.
+func (s *TrustStore) load() map[string]string {
+	trusted := map[string]string{}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return trusted
+	}
+	_ = json.Unmarshal(data, &trusted)
+	return trusted
+}
+
+// fingerprint hashes the settings; JSON sorts map keys, so it does not depend
+// on their order.
+func fingerprint(settings any) string {
+	data, _ := json.Marshal(settings)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
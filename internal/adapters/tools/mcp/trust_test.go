@@ -0,0 +1,51 @@
+package mcp
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/phamdaiminhquan/vibe-devops/pkg/config"
+)
+
+func TestLocalServers(t *testing.T) {
+	servers := map[string]config.MCPServerConfig{
+		"fs":       {Command: "npx", Args: []string{"-y", "server-fs", "."}},
+		"off":      {Command: "rm", Disabled: true},
+		"remote":   {URL: "https://mcp.example.com"},
+		"blank":    {Command: "  "},
+		"postgres": {Command: "pg-mcp"},
+	}
+	local := LocalServers(servers)
+	if len(local) != 2 || local["fs"].Command != "npx" || local["postgres"].Command != "pg-mcp" {
+		t.Errorf("expected the enabled stdio servers, got %v", local)
+	}
+	lines := CommandLines(local)
+	if len(lines) != 2 || lines[0] != "fs: npx -y server-fs ." || lines[1] != "postgres: pg-mcp" {
+		t.Errorf("unexpected command lines %q", lines)
+	}
+}
+
+func TestTrustStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vibe", "trusted_mcp.json")
+	servers := map[string]config.MCPServerConfig{"fs": {Command: "npx", Args: []string{"server-fs"}}}
+
+	store := NewTrustStore(path)
+	if store.Trusted("/work/app", servers) {
+		t.Fatal("a new store trusts nothing")
+	}
+	if err := store.Trust("/work/app", servers); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewTrustStore(path)
+	if !reloaded.Trusted("/work/app", servers) {
+		t.Error("expected the answer to be remembered")
+	}
+	if reloaded.Trusted("/work/other", servers) {
+		t.Error("trust is per project")
+	}
+	changed := map[string]config.MCPServerConfig{"fs": {Command: "npx", Args: []string{"evil-server"}}}
+	if reloaded.Trusted("/work/app", changed) {
+		t.Error("a changed server list must be trusted again")
+	}
+}
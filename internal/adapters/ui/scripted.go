@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// Scripted is a UI for tests. Prompts take their answers from a script, in
+// order, and everything shown is recorded in Lines.
+type Scripted struct {
+	mu      sync.Mutex
+	answers []string
+	// Lines records prompts and output, e.g. "confirm: Run it?" or "command: ls".
+	Lines  []string
+	output bytes.Buffer
+}
+
+// NewScripted returns a UI that answers prompts with answers, in order. Once
+// they run out, Confirm says no, Choose picks nothing and Input returns io.EOF.
+func NewScripted(answers ...string) *Scripted {
+	return &Scripted{answers: answers}
+}
+
+func (s *Scripted) Confirm(question string) bool {
+	answer, _ := s.next("confirm: " + question)
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
+
+func (s *Scripted) Choose(question string, choices []ports.Choice) string {
+	answer, _ := s.next("choose: " + question)
+	for _, c := range choices {
+		if strings.EqualFold(answer, c.Key) {
+			return c.Key
+		}
+	}
+	return ""
+}
+
+func (s *Scripted) Input(question string) (string, error) {
+	return s.next("input: " + question)
+}
+
+func (s *Scripted) Progress(message string) { s.record("progress: " + message) }
+func (s *Scripted) Token(text string)       { s.record("token: " + text) }
+func (s *Scripted) Explain(text string)     { s.record("explain: " + text) }
+
+func (s *Scripted) Message(level ports.MessageLevel, text string) {
+	s.record(strings.TrimSpace(icon(level) + text))
+}
+
+func (s *Scripted) Command(label, cmd string) { s.record("command: " + cmd) }
+
+func (s *Scripted) Result(outcome ports.CommandOutcome) {
+	s.record(fmt.Sprintf("result: %s exit_code=%d", outcome.Command, outcome.ExitCode))
+}
+
+// Output collects command output; read it back with Printed.
+func (s *Scripted) Output() io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.output.Write(p)
+	})
+}
+
+// Printed returns the command output written so far.
+func (s *Scripted) Printed() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.output.String()
+}
+
+// Remaining reports how many scripted answers were not used.
+func (s *Scripted) Remaining() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.answers)
+}
+
+func (s *Scripted) next(prompt string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Lines = append(s.Lines, prompt)
+	if len(s.answers) == 0 {
+		return "", io.EOF
+	}
+	answer := s.answers[0]
+	s.answers = s.answers[1:]
+	return strings.TrimSpace(answer), nil
+}
+
+func (s *Scripted) record(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Lines = append(s.Lines, line)
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+var _ ports.UI = (*Scripted)(nil)
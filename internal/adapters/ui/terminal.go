@@ -0,0 +1,186 @@
+// Package ui implements ports.UI for the terminal and for scripted tests.
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// Terminal is the console UI. Prompts read lines from in; everything else is
+// written to out, which callers point at stderr when stdout carries data.
+type Terminal struct {
+	in *bufio.Reader
+	// answers, when set, reads prompts instead of in.
+	answers AnswerReader
+	out     io.Writer
+
+	mu sync.Mutex
+	// transient is set while a Progress line waits to be replaced.
+	transient bool
+	// streaming is set while a streamed explanation is on the current line.
+	streaming bool
+}
+
+// NewTerminal returns a UI reading answers from in and writing to out.
+func NewTerminal(in io.Reader, out io.Writer) *Terminal {
+	return &Terminal{in: bufio.NewReader(in), out: out}
+}
+
+// AnswerReader shows a prompt and reads the reply; terminal.LineReader is one.
+type AnswerReader interface {
+	ReadAnswer(prompt string) (string, error)
+}
+
+// NewLineTerminal returns a UI reading answers through in, for callers that
+// already read stdin with it. in shows the prompts itself, so out should be
+// the terminal's stdout.
+func NewLineTerminal(in AnswerReader, out io.Writer) *Terminal {
+	return &Terminal{answers: in, out: out}
+}
+
+func (t *Terminal) Confirm(question string) bool {
+	answer, _ := t.Input(question + " (y/N) ")
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
+
+func (t *Terminal) Choose(question string, choices []ports.Choice) string {
+	options := make([]string, len(choices))
+	for i, c := range choices {
+		options[i] = fmt.Sprintf("[%s] %s", c.Key, c.Label)
+	}
+	prompt := strings.Join(options, "  ") + ": "
+	if question != "" {
+		prompt = question + " " + prompt
+	}
+	answer, _ := t.Input(prompt)
+	answer = strings.ToLower(answer)
+	for _, c := range choices {
+		if answer == strings.ToLower(c.Key) {
+			return c.Key
+		}
+	}
+	return ""
+}
+
+func (t *Terminal) Input(question string) (string, error) {
+	t.mu.Lock()
+	t.settle()
+	if t.answers != nil {
+		t.mu.Unlock()
+		line, err := t.answers.ReadAnswer(question)
+		return strings.TrimSpace(line), err
+	}
+	fmt.Fprint(t.out, question)
+	t.mu.Unlock()
+
+	line, err := t.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func (t *Terminal) Progress(message string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.endStream()
+	fmt.Fprintf(t.out, "\r\033[K[VIBE] %s ", message)
+	t.transient = true
+}
+
+func (t *Terminal) Token(text string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.streaming {
+		t.clearTransient()
+		fmt.Fprint(t.out, "[VIBE] ") // Prefix for explanation
+		t.streaming = true
+	}
+	fmt.Fprint(t.out, text)
+}
+
+func (t *Terminal) Explain(text string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.streaming {
+		t.endStream()
+		return
+	}
+	t.clearTransient()
+	if strings.TrimSpace(text) != "" {
+		fmt.Fprintf(t.out, "\n[VIBE] %s\n", text)
+	}
+}
+
+func (t *Terminal) Message(level ports.MessageLevel, text string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.settle()
+	// Leading blank lines go before the icon.
+	body := strings.TrimLeft(text, "\n")
+	fmt.Fprintln(t.out, text[:len(text)-len(body)]+icon(level)+body)
+}
+
+func (t *Terminal) Command(label, cmd string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.settle()
+	fmt.Fprintf(t.out, "\n%s:\n  \033[1;36m%s\033[0m\n", label, cmd)
+}
+
+func (t *Terminal) Result(outcome ports.CommandOutcome) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.settle()
+	if outcome.Succeeded() {
+		fmt.Fprintln(t.out, "\n✅ Command executed successfully.")
+		return
+	}
+	fmt.Fprintf(t.out, "\n❌ Command failed (exit code %d).\n", outcome.ExitCode)
+	if strings.TrimSpace(outcome.Stderr) != "" {
+		fmt.Fprintf(t.out, "\n--- stderr ---\n%s\n", outcome.Stderr)
+	}
+}
+
+func (t *Terminal) Output() io.Writer { return t.out }
+
+// settle ends a streamed line and clears a progress line before other output.
+func (t *Terminal) settle() {
+	t.endStream()
+	t.clearTransient()
+}
+
+func (t *Terminal) endStream() {
+	if t.streaming {
+		fmt.Fprintln(t.out) // Newline after stream
+		t.streaming = false
+	}
+}
+
+func (t *Terminal) clearTransient() {
+	if t.transient {
+		fmt.Fprint(t.out, "\r\033[K")
+		t.transient = false
+	}
+}
+
+func icon(level ports.MessageLevel) string {
+	switch level {
+	case ports.MessageSuccess:
+		return "✅ "
+	case ports.MessageWarning:
+		return "⚠️  "
+	case ports.MessageError:
+		return "❌ "
+	default:
+		return ""
+	}
+}
+
+var _ ports.UI = (*Terminal)(nil)
@@ -0,0 +1,119 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+var planChoices = []ports.Choice{{Key: "a", Label: "approve all"}, {Key: "c", Label: "cancel"}}
+
+func TestTerminal_Prompts(t *testing.T) {
+	var out bytes.Buffer
+	term := NewTerminal(strings.NewReader("Y\nx\nA\n  some text  \n"), &out)
+
+	if !term.Confirm("Run it?") {
+		t.Error("expected Y to confirm")
+	}
+	if got := term.Choose("Run this plan?", planChoices); got != "" {
+		t.Errorf("expected no choice for an unknown key, got %q", got)
+	}
+	if got := term.Choose("Run this plan?", planChoices); got != "a" {
+		t.Errorf("expected choice a, got %q", got)
+	}
+	if got, err := term.Input("> "); err != nil || got != "some text" {
+		t.Errorf("Input: got %q, %v", got, err)
+	}
+	if _, err := term.Input("> "); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF at the end of input, got %v", err)
+	}
+	if term.Confirm("Again?") {
+		t.Error("end of input must not confirm")
+	}
+	if !strings.Contains(out.String(), "Run this plan? [a] approve all  [c] cancel: ") {
+		t.Errorf("unexpected prompt rendering:\n%s", out.String())
+	}
+}
+
+// answerQueue answers prompts from a list, as a shared line reader would.
+type answerQueue struct {
+	answers []string
+	prompts []string
+}
+
+func (q *answerQueue) ReadAnswer(prompt string) (string, error) {
+	q.prompts = append(q.prompts, prompt)
+	if len(q.answers) == 0 {
+		return "", io.EOF
+	}
+	answer := q.answers[0]
+	q.answers = q.answers[1:]
+	return answer, nil
+}
+
+func TestLineTerminal_ReadsThroughSharedReader(t *testing.T) {
+	var out bytes.Buffer
+	in := &answerQueue{answers: []string{"yes", " e "}}
+	term := NewLineTerminal(in, &out)
+
+	term.Progress("Thinking...")
+	if !term.Confirm("Run it?") {
+		t.Error("expected yes to confirm")
+	}
+	if got := term.Choose("Run this plan?", planChoices); got != "" {
+		t.Errorf("expected no choice for an unknown key, got %q", got)
+	}
+	if _, err := term.Input("> "); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF at the end of input, got %v", err)
+	}
+	if len(in.prompts) != 3 || in.prompts[0] != "Run it? (y/N) " {
+		t.Errorf("expected every prompt to go through the reader, got %q", in.prompts)
+	}
+	if strings.Contains(out.String(), "Run it?") || !strings.HasSuffix(out.String(), "\r\033[K") {
+		t.Errorf("expected the progress line cleared and no prompt on out, got %q", out.String())
+	}
+}
+
+func TestTerminal_StreamEndsBeforeOtherOutput(t *testing.T) {
+	var out bytes.Buffer
+	term := NewTerminal(strings.NewReader(""), &out)
+
+	term.Progress("Thinking...")
+	term.Token("Disk is ")
+	term.Token("full.")
+	term.Explain("Disk is full.")
+	term.Message(ports.MessageError, "Command cancelled.")
+
+	want := "\r\033[K[VIBE] Thinking... \r\033[K[VIBE] Disk is full.\n❌ Command cancelled.\n"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestScripted(t *testing.T) {
+	s := NewScripted("y", "c")
+
+	if !s.Confirm("Run it?") {
+		t.Error("expected the scripted yes")
+	}
+	if got := s.Choose("Run this plan?", planChoices); got != "c" {
+		t.Errorf("expected choice c, got %q", got)
+	}
+	if s.Confirm("Once more?") {
+		t.Error("expected no once the script ran out")
+	}
+	s.Command("Vibe wants to run", "ls")
+	_, _ = io.WriteString(s.Output(), "file.txt\n")
+
+	want := []string{"confirm: Run it?", "choose: Run this plan?", "confirm: Once more?", "command: ls"}
+	if strings.Join(s.Lines, "|") != strings.Join(want, "|") {
+		t.Errorf("unexpected lines: %q", s.Lines)
+	}
+	if s.Printed() != "file.txt\n" || s.Remaining() != 0 {
+		t.Errorf("unexpected output %q or remaining answers %d", s.Printed(), s.Remaining())
+	}
+}
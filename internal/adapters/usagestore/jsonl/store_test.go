@@ -0,0 +1,56 @@
+package jsonl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+func TestStore_AppendAndList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "usage.jsonl")
+	s := New(path)
+
+	now := time.Now()
+	old := ports.UsageRecord{Time: now.Add(-48 * time.Hour), Provider: "gemini", Model: "gemini-pro", PromptTokens: 1}
+	recent := ports.UsageRecord{Time: now, Session: "default", Provider: "openai", Model: "gpt-4o", PromptTokens: 10, CompletionTokens: 5, CostUSD: 0.0001}
+
+	for _, r := range []ports.UsageRecord{old, recent} {
+		if err := s.Append(r); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	// A torn line must not hide the rest of the history.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("{\"time\":\n")
+	_ = f.Close()
+
+	all, err := s.List(time.Time{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(all))
+	}
+
+	since, err := s.List(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(since) != 1 || since[0].Model != "gpt-4o" || since[0].CompletionTokens != 5 {
+		t.Errorf("unexpected filtered records: %+v", since)
+	}
+}
+
+func TestStore_ListMissingFile(t *testing.T) {
+	records, err := New(filepath.Join(t.TempDir(), "usage.jsonl")).List(time.Time{})
+	if err != nil || len(records) != 0 {
+		t.Errorf("expected empty history, got %v, %v", records, err)
+	}
+}
@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// ToolConfirmation describes a tool call that needs the user's approval.
+type ToolConfirmation struct {
+	Tool  ports.ToolDefinition
+	Input json.RawMessage
+	// Summary is the input on one line, e.g. the shell command for safe_shell.
+	Summary string
+}
+
+// policyFor returns the policy for running tool with input. A configured
+// override only makes the tool's own evaluation stricter, unless the user
+// trusts the overrides to loosen it too; a call the tool itself denies stays
+// denied either way.
+func (s *Service) policyFor(tool ports.Tool, input json.RawMessage) ports.ToolPolicy {
+	policy := tool.EvaluatePolicy(input)
+	if policy == ports.PolicyDenied {
+		return policy
+	}
+	override, ok := s.policies[tool.Definition().Name]
+	if !ok || (!s.loosenPolicies && strictness(override) < strictness(policy)) {
+		return policy
+	}
+	return override
+}
+
+// strictness orders policies from allowed to denied.
+func strictness(policy ports.ToolPolicy) int {
+	switch policy {
+	case ports.PolicyAllowed:
+		return 0
+	case ports.PolicyWithPermission:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// summarizeInput renders a tool input for a confirmation prompt: the value
+// itself when the input has a single string field, compact JSON otherwise.
+func summarizeInput(input json.RawMessage) string {
+	var fields map[string]any
+	if err := json.Unmarshal(input, &fields); err == nil && len(fields) == 1 {
+		for _, v := range fields {
+			if str, ok := v.(string); ok {
+				return strings.TrimSpace(str)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, input); err != nil {
+		return strings.TrimSpace(string(input))
+	}
+	return buf.String()
+}
@@ -1,22 +1,39 @@
 package agent
 
 import (
+	"encoding/json"
+	"fmt"
 	"strings"
 
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
 	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
 )
 
-func buildAgentPrompt(goos, userRequest string, transcript []string, tools []ports.Tool, contextItems []ports.ContextItem) string {
+// agentSystemPrompt renders the rules, environment and tool list. It only depends on
+// the tool mode, so it stays identical across steps and providers can cache it.
+// When nativeTools is true the tools are offered through the provider's function-calling API.
+func agentSystemPrompt(goos string, tools []ports.Tool, nativeTools bool) string {
 	var b strings.Builder
 	b.WriteString("You are Vibe, a CLI assistant that proposes ONE shell command for the user to run.\n")
 	b.WriteString("You MAY request safe read-only tools to inspect the workspace before proposing a command.\n")
 	b.WriteString("\n")
 	b.WriteString("CRITICAL OUTPUT RULES:\n")
-	b.WriteString("- Output EXACTLY ONE JSON object. No markdown, no code fences, no extra text.\n")
-	b.WriteString("- Use {\"type\":\"tool\",\"thought\":\"user-friendly status\",\"tool\":...,\"input\":{...}} to call a tool.\n")
+	if nativeTools {
+		b.WriteString("- To call a tool, use the function-calling interface. Put a short, friendly status message for the user in your text (e.g., 'Checking backend folder...').\n")
+		b.WriteString("- You may call several tools in one turn when the calls don't depend on each other; read-only calls run in parallel.\n")
+		b.WriteString("- Otherwise output EXACTLY ONE JSON object. No markdown, no code fences, no extra text.\n")
+	} else {
+		b.WriteString("- Output EXACTLY ONE JSON object. No markdown, no code fences, no extra text.\n")
+		b.WriteString("- Use {\"type\":\"tool\",\"thought\":\"user-friendly status\",\"tool\":...,\"input\":{...}} to call a tool.\n")
+		b.WriteString("- To gather several things at once, use {\"type\":\"tool\",\"thought\":...,\"calls\":[{\"tool\":...,\"input\":{...}},...]}. Read-only calls run in parallel and you get every result in order.\n")
+	}
 	b.WriteString("- Use {\"type\":\"done\",\"command\":...,\"explanation\":...} when you want to propose a command to run.\n")
-	b.WriteString("- Use {\"type\":\"answer\",\"explanation\":...} when you can answer WITHOUT a command OR need to clarify user intent (e.g. 'What is be?').\n")
-	b.WriteString("- 'thought' is REQUIRED for tools. It must be a short, friendly status message for the user (e.g., 'Checking backend folder...').\n")
+	b.WriteString("- Use {\"type\":\"plan\",\"explanation\":...,\"steps\":[{\"command\":...,\"purpose\":...,\"expected\":...,\"rollback\":...}]} when the job needs several commands run in order (e.g. rotate logs, reload a service, check its status) instead of chaining them with &&. rollback may be empty if a step cannot be undone.\n")
+	b.WriteString("- Use {\"type\":\"answer\",\"explanation\":...} when you can answer WITHOUT a command.\n")
+	b.WriteString("- Use {\"type\":\"ask\",\"question\":...,\"choices\":[...]} when you need information only the user has or must clarify their intent (e.g. 'What is be?'). choices is optional. The user's reply comes back as USER_ANSWER and you continue the same task.\n")
+	if !nativeTools {
+		b.WriteString("- 'thought' is REQUIRED for tools. It must be a short, friendly status message for the user (e.g., 'Checking backend folder...').\n")
+	}
 	b.WriteString("- command MUST be a single-line command string (no surrounding backticks).\n")
 	b.WriteString("\n")
 	b.WriteString("Environment:\n")
@@ -24,56 +41,162 @@ func buildAgentPrompt(goos, userRequest string, transcript []string, tools []por
 	b.WriteString(strings.TrimSpace(goos))
 	b.WriteString("\n\n")
 
-	// Add context items if available
+	b.WriteString("Available tools:\n")
+	if nativeTools {
+		b.WriteString("(provided via function calling)\n")
+	} else {
+		for _, t := range tools {
+			if t == nil {
+				continue
+			}
+			def := t.Definition()
+			b.WriteString("- ")
+			b.WriteString(def.Name)
+			b.WriteString(": ")
+			b.WriteString(def.Description)
+			b.WriteString(" Input schema: ")
+			b.WriteString(def.InputSchema)
+			b.WriteString("\n")
+		}
+		if len(tools) == 0 {
+			b.WriteString("(none)\n")
+		}
+	}
+	b.WriteString("\n")
+
+	b.WriteString("Reminder: If the task can be solved without tools, return type=done immediately.\n")
+	b.WriteString("If you use tools, keep tool calls minimal and stop once you have enough info.\n")
+	b.WriteString("EFFICIENCY TIP: You can run complex shell commands! Instead of 3 separate calls (e.g. check dir, then ps, then netstat), use ONE safe_shell call with joined commands (e.g. 'ls -F && ps aux | grep app && netstat -tulpn'). Save your steps.\n")
+
+	return b.String()
+}
+
+// buildAgentMessages renders the conversation for one agent step: the system prompt,
+// the task as the first user turn, then the transcript replayed as turns.
+//
+// In native mode TOOL_CALL/TOOL_OUTPUT lines become assistant tool calls answered by
+// RoleTool messages. In JSON mode the call is replayed as the assistant's JSON action
+// and its output as a user turn, since tool messages need a native call to answer.
+// AGENT_ACTION (an earlier final action, as JSON) and AGENT_QUESTION lines are
+// replayed as assistant turns. Any other transcript lines (session notes,
+// execution results, USER_ANSWER, USER_MESSAGE) are user turns.
+func buildAgentMessages(goos, userRequest string, transcript []string, tools []ports.Tool, contextItems []ports.ContextItem, nativeTools bool) []chat.Message {
+	messages := []chat.Message{{Role: chat.RoleSystem, Content: agentSystemPrompt(goos, tools, nativeTools)}}
+
+	var task strings.Builder
 	if len(contextItems) > 0 {
-		b.WriteString("User-provided Context:\n")
+		task.WriteString("User-provided Context:\n")
 		for _, item := range contextItems {
-			b.WriteString("--- ")
-			b.WriteString(item.Name)
+			task.WriteString("--- ")
+			task.WriteString(item.Name)
 			if item.Description != "" {
-				b.WriteString(" (")
-				b.WriteString(item.Description)
-				b.WriteString(")")
+				task.WriteString(" (")
+				task.WriteString(item.Description)
+				task.WriteString(")")
 			}
-			b.WriteString(" ---\n")
-			b.WriteString(item.Content)
-			b.WriteString("\n\n")
+			task.WriteString(" ---\n")
+			task.WriteString(item.Content)
+			task.WriteString("\n\n")
 		}
 	}
+	task.WriteString("Task:\n")
+	task.WriteString(userRequest)
+	messages = append(messages, chat.Message{Role: chat.RoleUser, Content: task.String()})
 
-	b.WriteString("Available tools:\n")
-	for _, t := range tools {
-		if t == nil {
-			continue
+	// addUser appends to the previous user turn so that roles keep alternating.
+	addUser := func(text string) {
+		if last := &messages[len(messages)-1]; last.Role == chat.RoleUser {
+			last.Content += "\n\n" + text
+			return
 		}
-		def := t.Definition()
-		b.WriteString("- ")
-		b.WriteString(def.Name)
-		b.WriteString(": ")
-		b.WriteString(def.Description)
-		b.WriteString(" Input schema: ")
-		b.WriteString(def.InputSchema)
-		b.WriteString("\n")
-	}
-	if len(tools) == 0 {
-		b.WriteString("(none)\n")
+		messages = append(messages, chat.Message{Role: chat.RoleUser, Content: text})
 	}
-	b.WriteString("\n")
 
-	b.WriteString("Task:\n")
-	b.WriteString(userRequest)
-	b.WriteString("\n\n")
+	var pending []string
+	flush := func() {
+		if len(pending) > 0 {
+			addUser(strings.Join(pending, "\n"))
+			pending = nil
+		}
+	}
 
-	b.WriteString("Transcript (most recent last):\n")
+	calls := 0
+	var lastCall chat.ToolCall
 	for _, line := range transcript {
-		b.WriteString(line)
-		b.WriteString("\n")
+		switch {
+		case strings.HasPrefix(line, "USER_REQUEST: "), strings.HasPrefix(line, "GOOS: "):
+			// Already part of the task and the system prompt.
+		case strings.HasPrefix(line, "TOOL_CALL: "):
+			flush()
+			name, input, _ := strings.Cut(strings.TrimPrefix(line, "TOOL_CALL: "), " ")
+			input = strings.TrimSpace(input)
+			if !json.Valid([]byte(input)) {
+				input = "{}"
+			}
+			lastCall = chat.ToolCall{ID: fmt.Sprintf("call_%d", calls), Name: name, Arguments: json.RawMessage(input)}
+			calls++
+			if nativeTools {
+				messages = append(messages, chat.Message{Role: chat.RoleAssistant, ToolCalls: []chat.ToolCall{lastCall}})
+			} else {
+				action, _ := json.Marshal(struct {
+					Type  string          `json:"type"`
+					Tool  string          `json:"tool"`
+					Input json.RawMessage `json:"input"`
+				}{ActionTypeTool, name, lastCall.Arguments})
+				messages = append(messages, chat.Message{Role: chat.RoleAssistant, Content: string(action)})
+			}
+		case strings.HasPrefix(line, "AGENT_ACTION: "):
+			flush()
+			messages = append(messages, chat.Message{Role: chat.RoleAssistant, Content: strings.TrimPrefix(line, "AGENT_ACTION: ")})
+		case strings.HasPrefix(line, "AGENT_QUESTION: "):
+			flush()
+			question := strings.TrimPrefix(line, "AGENT_QUESTION: ")
+			if !nativeTools {
+				action, _ := json.Marshal(struct {
+					Type     string `json:"type"`
+					Question string `json:"question"`
+				}{ActionTypeAsk, question})
+				question = string(action)
+			}
+			messages = append(messages, chat.Message{Role: chat.RoleAssistant, Content: question})
+		case strings.HasPrefix(line, "TOOL_OUTPUT: ") && messages[len(messages)-1].Role == chat.RoleAssistant:
+			if nativeTools {
+				messages = append(messages, chat.Message{
+					Role:       chat.RoleTool,
+					Content:    strings.TrimPrefix(line, "TOOL_OUTPUT: "),
+					ToolCallID: lastCall.ID,
+					Name:       lastCall.Name,
+				})
+			} else {
+				addUser(line)
+			}
+		default:
+			pending = append(pending, line)
+		}
 	}
-	b.WriteString("\n")
+	flush()
 
-	b.WriteString("Reminder: If the task can be solved without tools, return type=done immediately.\n")
-	b.WriteString("If you use tools, keep tool calls minimal and stop once you have enough info.\n")
-	b.WriteString("EFFICIENCY TIP: You can run complex shell commands! Instead of 3 separate calls (e.g. check dir, then ps, then netstat), use ONE safe_shell call with joined commands (e.g. 'ls -F && ps aux | grep app && netstat -tulpn'). Save your steps.\n")
+	return messages
+}
 
-	return b.String()
+// toolSpecs converts tool definitions into native function declarations.
+// Tools with an invalid InputSchema fall back to an unconstrained object.
+func toolSpecs(tools []ports.Tool) []ports.ToolSpec {
+	specs := make([]ports.ToolSpec, 0, len(tools))
+	for _, t := range tools {
+		if t == nil {
+			continue
+		}
+		def := t.Definition()
+		params := json.RawMessage(strings.TrimSpace(def.InputSchema))
+		if !json.Valid(params) {
+			params = json.RawMessage(`{"type":"object"}`)
+		}
+		specs = append(specs, ports.ToolSpec{
+			Name:        def.Name,
+			Description: def.Description,
+			Parameters:  params,
+		})
+	}
+	return specs
 }
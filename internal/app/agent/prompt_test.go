@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
+)
+
+func TestBuildAgentMessages_JSONProtocol(t *testing.T) {
+	transcript := []string{
+		"PROJECT_SESSION_SUMMARY: uses docker compose",
+		"USER_REQUEST: restart the api",
+		"GOOS: linux",
+		`TOOL_CALL: read_file {"path":"compose.yaml"}`,
+		"TOOL_OUTPUT: services: api",
+		"EXEC_COMMAND: docker compose restart api",
+		"EXEC_RESULT: exit_code=1",
+	}
+	msgs := buildAgentMessages("linux", "restart the api", transcript, nil, nil, false)
+
+	roles := make([]string, len(msgs))
+	for i, m := range msgs {
+		roles[i] = string(m.Role)
+	}
+	if got := strings.Join(roles, ","); got != "system,user,assistant,user" {
+		t.Fatalf("unexpected roles: %s", got)
+	}
+	if !strings.Contains(msgs[1].Content, "Task:\nrestart the api") || !strings.Contains(msgs[1].Content, "PROJECT_SESSION_SUMMARY") {
+		t.Errorf("expected task and session notes in the first user turn:\n%s", msgs[1].Content)
+	}
+	if strings.Contains(msgs[1].Content, "USER_REQUEST:") {
+		t.Errorf("request markers must not be repeated:\n%s", msgs[1].Content)
+	}
+	if msgs[2].Content != `{"type":"tool","tool":"read_file","input":{"path":"compose.yaml"}}` {
+		t.Errorf("unexpected assistant turn: %s", msgs[2].Content)
+	}
+	if !strings.HasPrefix(msgs[3].Content, "TOOL_OUTPUT: services: api\n\nEXEC_COMMAND:") {
+		t.Errorf("expected tool output and execution result in one user turn:\n%s", msgs[3].Content)
+	}
+}
+
+func TestBuildAgentMessages_NativeTools(t *testing.T) {
+	transcript := []string{
+		`TOOL_CALL: list_dir {"path":"."}`,
+		"TOOL_OUTPUT: - [file] go.mod",
+		`TOOL_CALL: read_file {"path":"go.mod"}`,
+		"TOOL_OUTPUT: module x",
+	}
+	msgs := buildAgentMessages("linux", "which go version?", transcript, nil, nil, true)
+
+	if len(msgs) != 6 {
+		t.Fatalf("expected 6 messages, got %d", len(msgs))
+	}
+	if !strings.Contains(msgs[0].Content, "(provided via function calling)") {
+		t.Errorf("expected native tool mode in the system prompt")
+	}
+	call, result := msgs[4], msgs[5]
+	if call.Role != chat.RoleAssistant || len(call.ToolCalls) != 1 || call.ToolCalls[0].Name != "read_file" {
+		t.Fatalf("unexpected assistant turn: %+v", call)
+	}
+	if result.Role != chat.RoleTool || result.ToolCallID != call.ToolCalls[0].ID || result.Name != "read_file" || result.Content != "module x" {
+		t.Errorf("unexpected tool turn: %+v", result)
+	}
+	if msgs[2].ToolCalls[0].ID == call.ToolCalls[0].ID {
+		t.Errorf("tool call IDs must be unique")
+	}
+}
+
+func TestBuildAgentMessages_ReplaysEarlierActions(t *testing.T) {
+	transcript := []string{
+		"USER_REQUEST: why is the disk full?",
+		"GOOS: linux",
+		`AGENT_ACTION: {"type":"answer","explanation":"/var/log is 40G"}`,
+		"USER_MESSAGE: clean it up",
+	}
+	msgs := buildAgentMessages("linux", "why is the disk full?", transcript, nil, nil, false)
+
+	if len(msgs) != 4 {
+		t.Fatalf("expected 4 messages, got %d", len(msgs))
+	}
+	if msgs[2].Role != chat.RoleAssistant || msgs[2].Content != `{"type":"answer","explanation":"/var/log is 40G"}` {
+		t.Errorf("unexpected assistant turn: %+v", msgs[2])
+	}
+	if msgs[3].Role != chat.RoleUser || msgs[3].Content != "USER_MESSAGE: clean it up" {
+		t.Errorf("unexpected user turn: %+v", msgs[3])
+	}
+}
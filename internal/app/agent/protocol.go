@@ -4,13 +4,18 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
 )
 
 const (
 	ActionTypeTool   = "tool"
 	ActionTypeDone   = "done"
 	ActionTypeAnswer = "answer"
+	ActionTypePlan   = "plan"
+	ActionTypeAsk    = "ask"
 )
 
 type Action struct {
@@ -20,20 +25,132 @@ type Action struct {
 	Input       json.RawMessage `json:"input,omitempty"`
 	Command     string          `json:"command,omitempty"`
 	Explanation string          `json:"explanation,omitempty"`
+	// Calls carries several tool calls for one step, instead of Tool and Input.
+	Calls []ToolInvocation `json:"calls,omitempty"`
+	// Steps is the ordered plan of a plan action.
+	Steps []PlanStep `json:"steps,omitempty"`
+	// Question and the optional Choices make up an ask action.
+	Question string   `json:"question,omitempty"`
+	Choices  []string `json:"choices,omitempty"`
+
+	// UnknownFields lists keys the model sent that ParseAction ignored.
+	UnknownFields []string `json:"-"`
+}
+
+// ToolInvocation is one tool call of a tool action.
+type ToolInvocation struct {
+	Tool  string          `json:"tool"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+// Invocations returns the tool calls of a tool action, in order.
+func (a Action) Invocations() []ToolInvocation {
+	if len(a.Calls) > 0 {
+		return a.Calls
+	}
+	return []ToolInvocation{{Tool: a.Tool, Input: a.Input}}
+}
+
+// PlanStep is one command of a multi-step plan.
+type PlanStep struct {
+	Command string `json:"command"`
+	// Purpose says why the step is needed; Expected what success looks like.
+	Purpose  string `json:"purpose,omitempty"`
+	Expected string `json:"expected,omitempty"`
+	// Rollback is a command that undoes the step, if there is one.
+	Rollback string `json:"rollback,omitempty"`
 }
 
+// actionFields are the keys of Action's JSON form.
+var actionFields = map[string]bool{"type": true, "thought": true, "tool": true, "input": true, "command": true, "explanation": true, "calls": true, "steps": true, "question": true, "choices": true}
+
+// ActionSchema is the JSON Schema of Action, sent to providers as their
+// structured-output format in the JSON protocol.
+var ActionSchema = json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"type": {"type": "string", "enum": ["tool", "done", "answer", "plan", "ask"]},
+		"thought": {"type": "string"},
+		"tool": {"type": "string"},
+		"input": {"type": "object"},
+		"command": {"type": "string"},
+		"explanation": {"type": "string"},
+		"calls": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {
+					"tool": {"type": "string"},
+					"input": {"type": "object"}
+				},
+				"required": ["tool"]
+			}
+		},
+		"question": {"type": "string"},
+		"choices": {"type": "array", "items": {"type": "string"}},
+		"steps": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {
+					"command": {"type": "string"},
+					"purpose": {"type": "string"},
+					"expected": {"type": "string"},
+					"rollback": {"type": "string"}
+				},
+				"required": ["command"]
+			}
+		}
+	},
+	"required": ["type"]
+}`)
+
+// ParseAction extracts the agent action from a model response. It tolerates
+// what models commonly get wrong: code fences and prose around the JSON,
+// trailing commas, single-quoted strings, and several objects in one answer
+// (the first valid action wins). Unknown fields are ignored and reported in
+// Action.UnknownFields.
 func ParseAction(modelText string) (Action, error) {
-	obj, err := extractFirstJSONObject(modelText)
+	objects, err := extractJSONObjects(modelText)
 	if err != nil {
 		return Action{}, err
 	}
 
+	var firstErr error
+	for _, obj := range objects {
+		a, err := decodeAction(obj)
+		if err != nil {
+			if repaired := repairJSON(obj); !bytes.Equal(repaired, obj) {
+				if ra, rerr := decodeAction(repaired); rerr == nil {
+					a, err = ra, nil
+				}
+			}
+		}
+		if err == nil {
+			return a, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return Action{}, firstErr
+}
+
+func decodeAction(obj []byte) (Action, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(obj, &fields); err != nil {
+		return Action{}, fmt.Errorf("invalid JSON action: %w", err)
+	}
 	var a Action
-	dec := json.NewDecoder(bytes.NewReader(obj))
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(&a); err != nil {
+	if err := json.Unmarshal(obj, &a); err != nil {
 		return Action{}, fmt.Errorf("invalid JSON action: %w", err)
 	}
+	for k := range fields {
+		if !actionFields[k] {
+			a.UnknownFields = append(a.UnknownFields, k)
+		}
+	}
+	sort.Strings(a.UnknownFields)
 
 	a.Type = strings.TrimSpace(a.Type)
 	if a.Type == "" {
@@ -42,12 +159,20 @@ func ParseAction(modelText string) (Action, error) {
 
 	switch a.Type {
 	case ActionTypeTool:
+		if len(a.Calls) > 0 {
+			for i := range a.Calls {
+				a.Calls[i].Tool = strings.TrimSpace(a.Calls[i].Tool)
+				if a.Calls[i].Tool == "" {
+					return Action{}, fmt.Errorf("missing field: calls[%d].tool", i)
+				}
+				a.Calls[i].Input = defaultInput(a.Calls[i].Input)
+			}
+			break
+		}
 		if strings.TrimSpace(a.Tool) == "" {
 			return Action{}, fmt.Errorf("missing field: tool")
 		}
-		if len(a.Input) == 0 {
-			a.Input = json.RawMessage([]byte(`{}`))
-		}
+		a.Input = defaultInput(a.Input)
 	case ActionTypeDone:
 		if strings.TrimSpace(a.Command) == "" {
 			return Action{}, fmt.Errorf("missing field: command")
@@ -56,6 +181,21 @@ func ParseAction(modelText string) (Action, error) {
 		if strings.TrimSpace(a.Explanation) == "" {
 			return Action{}, fmt.Errorf("missing field: explanation")
 		}
+	case ActionTypeAsk:
+		a.Question = strings.TrimSpace(a.Question)
+		if a.Question == "" {
+			return Action{}, fmt.Errorf("missing field: question")
+		}
+	case ActionTypePlan:
+		if len(a.Steps) == 0 {
+			return Action{}, fmt.Errorf("missing field: steps")
+		}
+		for i := range a.Steps {
+			a.Steps[i].Command = strings.TrimSpace(a.Steps[i].Command)
+			if a.Steps[i].Command == "" {
+				return Action{}, fmt.Errorf("missing field: steps[%d].command", i)
+			}
+		}
 	default:
 		return Action{}, fmt.Errorf("unknown type: %s", a.Type)
 	}
@@ -63,49 +203,151 @@ func ParseAction(modelText string) (Action, error) {
 	return a, nil
 }
 
+// ActionFromToolCall maps a native function call to a tool action.
+// Any text the model sent alongside the call becomes the user-facing thought.
+func ActionFromToolCall(call chat.ToolCall, text string) Action {
+	return Action{
+		Type:    ActionTypeTool,
+		Thought: strings.TrimSpace(text),
+		Tool:    call.Name,
+		Input:   defaultInput(call.Arguments),
+	}
+}
+
+// ActionFromToolCalls maps the native function calls of one model turn to a
+// tool action; several calls become Calls.
+func ActionFromToolCalls(calls []chat.ToolCall, text string) Action {
+	if len(calls) == 1 {
+		return ActionFromToolCall(calls[0], text)
+	}
+	a := Action{Type: ActionTypeTool, Thought: strings.TrimSpace(text)}
+	for _, call := range calls {
+		a.Calls = append(a.Calls, ToolInvocation{Tool: call.Name, Input: defaultInput(call.Arguments)})
+	}
+	return a
+}
+
+// defaultInput turns a missing tool input into an empty object.
+func defaultInput(input json.RawMessage) json.RawMessage {
+	if len(bytes.TrimSpace(input)) == 0 || string(input) == "null" {
+		return json.RawMessage(`{}`)
+	}
+	return input
+}
+
 func extractFirstJSONObject(text string) ([]byte, error) {
+	objects, err := extractJSONObjects(text)
+	if err != nil {
+		return nil, err
+	}
+	return objects[0], nil
+}
+
+// extractJSONObjects returns every top-level {...} span in text, in order.
+// Strings in single or double quotes are skipped when matching braces.
+func extractJSONObjects(text string) ([][]byte, error) {
 	s := strings.TrimSpace(text)
-	// Common model behavior: wrap in ```json ... ``` fences.
-	s = strings.TrimPrefix(s, "```json")
-	s = strings.TrimPrefix(s, "```")
-	s = strings.TrimSuffix(s, "```")
-	s = strings.TrimSpace(s)
-
-	start := strings.IndexByte(s, '{')
-	if start < 0 {
+	if !strings.Contains(s, "{") {
 		return nil, fmt.Errorf("no JSON object found")
 	}
 
-	inString := false
+	var objects [][]byte
+	var quote byte // the open string's quote character, or 0
 	escaped := false
-	depth := 0
-	for i := start; i < len(s); i++ {
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
 		c := s[i]
-		if escaped {
-			escaped = false
-			continue
-		}
-		if c == '\\' && inString {
-			escaped = true
-			continue
-		}
-		if c == '"' {
-			inString = !inString
-			continue
-		}
-		if inString {
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == quote:
+				quote = 0
+			}
 			continue
 		}
 		switch c {
+		case '"', '\'':
+			// Quotes only open strings inside an object; prose may contain apostrophes.
+			if depth > 0 {
+				quote = c
+			}
 		case '{':
+			if depth == 0 {
+				start = i
+			}
 			depth++
 		case '}':
+			if depth == 0 {
+				continue
+			}
 			depth--
 			if depth == 0 {
-				return []byte(s[start : i+1]), nil
+				objects = append(objects, []byte(s[start:i+1]))
 			}
 		}
 	}
 
-	return nil, fmt.Errorf("unterminated JSON object")
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("unterminated JSON object")
+	}
+	return objects, nil
+}
+
+// repairJSON rewrites the JSON-like syntax models often produce into JSON:
+// single-quoted strings become double-quoted and trailing commas are dropped.
+func repairJSON(obj []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(obj))
+	var quote byte
+	for i := 0; i < len(obj); i++ {
+		c := obj[i]
+		switch {
+		case quote == '"':
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(obj) {
+				i++
+				out.WriteByte(obj[i])
+			} else if c == '"' {
+				quote = 0
+			}
+		case quote == '\'':
+			switch {
+			case c == '\\' && i+1 < len(obj) && obj[i+1] == '\'':
+				i++
+				out.WriteByte('\'')
+			case c == '\\' && i+1 < len(obj):
+				i++
+				out.WriteByte(c)
+				out.WriteByte(obj[i])
+			case c == '"':
+				out.WriteString(`\"`)
+			case c == '\'':
+				quote = 0
+				out.WriteByte('"')
+			default:
+				out.WriteByte(c)
+			}
+		case c == '"':
+			quote = c
+			out.WriteByte(c)
+		case c == '\'':
+			quote = c
+			out.WriteByte('"')
+		case c == ',':
+			j := i + 1
+			for j < len(obj) && strings.IndexByte(" \t\r\n", obj[j]) >= 0 {
+				j++
+			}
+			if j < len(obj) && (obj[j] == '}' || obj[j] == ']') {
+				continue
+			}
+			out.WriteByte(c)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes()
 }
@@ -63,3 +63,102 @@ func TestParseAction_Answer(t *testing.T) {
 		t.Fatalf("expected explanation")
 	}
 }
+
+func TestParseAction_Lenient(t *testing.T) {
+	cases := map[string]string{
+		"trailing comma":   `{"type":"done","command":"echo hi","explanation":"ok",}`,
+		"single quotes":    `{'type':'done','command':'echo "hi"','explanation':'it\'s ok'}`,
+		"first valid":      "Here you go: {\"type\":\"oops\"} or better {\"type\":\"done\",\"command\":\"echo hi\"} {\"type\":\"answer\",\"explanation\":\"x\"}",
+		"prose apostrophe": "Here's the action: {\"type\":\"done\",\"command\":\"echo hi\"}",
+	}
+	for name, text := range cases {
+		a, err := ParseAction(text)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if a.Type != ActionTypeDone || a.Command == "" {
+			t.Errorf("%s: unexpected action: %+v", name, a)
+		}
+	}
+
+	a, err := ParseAction(`{'type':'done','command':'echo "hi"','explanation':'it\'s ok'}`)
+	if err != nil || a.Command != `echo "hi"` || a.Explanation != "it's ok" {
+		t.Errorf("single-quoted strings not converted: %+v (%v)", a, err)
+	}
+}
+
+func TestParseAction_UnknownFieldsReported(t *testing.T) {
+	a, err := ParseAction(`{"type":"done","command":"ls","confidence":0.9,"alternatives":[]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(a.UnknownFields) != 2 || a.UnknownFields[0] != "alternatives" || a.UnknownFields[1] != "confidence" {
+		t.Errorf("unexpected unknown fields: %v", a.UnknownFields)
+	}
+}
+
+func TestParseAction_ReportsFirstError(t *testing.T) {
+	if _, err := ParseAction(`{"type":"done"} {"type":"tool"}`); err == nil || err.Error() != "missing field: command" {
+		t.Errorf("expected the first candidate's error, got %v", err)
+	}
+	if _, err := ParseAction("no json here"); err == nil {
+		t.Error("expected an error without a JSON object")
+	}
+}
+
+func TestParseAction_Plan(t *testing.T) {
+	a, err := ParseAction(`{"type":"plan","explanation":"rotate logs","steps":[
+		{"command":" logrotate -f /etc/logrotate.d/nginx ","purpose":"rotate","expected":"new access.log","rollback":""},
+		{"command":"systemctl reload nginx","purpose":"reopen log files"}
+	]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.Type != ActionTypePlan || len(a.Steps) != 2 {
+		t.Fatalf("unexpected action: %+v", a)
+	}
+	if a.Steps[0].Command != "logrotate -f /etc/logrotate.d/nginx" || a.Steps[0].Expected != "new access.log" {
+		t.Errorf("unexpected first step: %+v", a.Steps[0])
+	}
+
+	if _, err := ParseAction(`{"type":"plan","steps":[]}`); err == nil || err.Error() != "missing field: steps" {
+		t.Errorf("expected an error for an empty plan, got %v", err)
+	}
+	if _, err := ParseAction(`{"type":"plan","steps":[{"command":"ls"},{"purpose":"nothing"}]}`); err == nil || err.Error() != "missing field: steps[1].command" {
+		t.Errorf("expected an error for a step without a command, got %v", err)
+	}
+}
+
+func TestParseAction_ToolCalls(t *testing.T) {
+	a, err := ParseAction(`{"type":"tool","thought":"Looking around...","calls":[{"tool":"list_dir","input":{"path":"."}},{"tool":" read_file ","input":null}]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	calls := a.Invocations()
+	if len(calls) != 2 || calls[0].Tool != "list_dir" || calls[1].Tool != "read_file" || string(calls[1].Input) != "{}" {
+		t.Errorf("unexpected calls: %+v", calls)
+	}
+
+	single, _ := ParseAction(`{"type":"tool","tool":"list_dir"}`)
+	if calls := single.Invocations(); len(calls) != 1 || calls[0].Tool != "list_dir" {
+		t.Errorf("expected a single invocation, got %+v", calls)
+	}
+
+	if _, err := ParseAction(`{"type":"tool","calls":[{"input":{}}]}`); err == nil || err.Error() != "missing field: calls[0].tool" {
+		t.Errorf("expected an error for a call without a tool, got %v", err)
+	}
+}
+
+func TestParseAction_Ask(t *testing.T) {
+	a, err := ParseAction(`{"type":"ask","question":" Which port? ","choices":["80","443"]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.Type != ActionTypeAsk || a.Question != "Which port?" || len(a.Choices) != 2 {
+		t.Errorf("unexpected action: %+v", a)
+	}
+	if _, err := ParseAction(`{"type":"ask","choices":["a"]}`); err == nil || err.Error() != "missing field: question" {
+		t.Errorf("expected an error without a question, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/provider/replay"
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/tools/fs"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// Regression tests for recorded agent runs. To add one, record a run against
+// testdata/workspace with VIBE_CASSETTE=<file> VIBE_CASSETTE_MODE=record and
+// copy the cassette into testdata/cassettes. Replay is lenient, so prompt
+// wording changes don't invalidate the recordings.
+func TestSuggestCommand_RecordedRuns(t *testing.T) {
+	cases := []struct {
+		cassette string
+		request  string
+		command  string
+		tools    []string
+	}{
+		{
+			cassette: "docker_run.json",
+			request:  "build and run this project in docker",
+			command:  "docker build -t web . && docker run --rm -p 8080:8080 web",
+			tools:    []string{"list_dir", "read_file"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.cassette, func(t *testing.T) {
+			player, err := replay.NewPlayer(filepath.Join("testdata", "cassettes", tc.cassette), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			workspace := filepath.Join("testdata", "workspace")
+			tools := []ports.Tool{fs.NewReadFileTool(workspace), fs.NewListDirTool(workspace), fs.NewGrepTool(workspace)}
+
+			svc := NewService(player, tools, nil, 5)
+			resp, err := svc.SuggestCommand(context.Background(), SuggestRequest{UserRequest: tc.request, GOOS: "linux"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Command != tc.command {
+				t.Errorf("unexpected command: %q", resp.Command)
+			}
+
+			var called []string
+			for _, line := range resp.Transcript {
+				if name, ok := strings.CutPrefix(line, "TOOL_CALL: "); ok {
+					called = append(called, strings.Fields(name)[0])
+				}
+			}
+			if strings.Join(called, ",") != strings.Join(tc.tools, ",") {
+				t.Errorf("expected tool calls %v, got %v", tc.tools, called)
+			}
+			if player.Remaining() != 0 {
+				t.Errorf("%d recorded interactions were not replayed", player.Remaining())
+			}
+		})
+	}
+}
@@ -2,19 +2,36 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
+	"time"
 
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/tokens"
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
 	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
 )
 
 type Service struct {
-	provider        ports.Provider
-	tools           []ports.Tool
-	logger          *slog.Logger
-	maxSteps        int
+	provider   ports.Provider
+	tools      []ports.Tool
+	logger     *slog.Logger
+	maxSteps   int
+	maxRepairs int
+	// toolWorkers bounds how many read-only tools run at once; toolTimeout caps each run.
+	toolWorkers     int
+	toolTimeout     time.Duration
 	contextRegistry ports.ContextProviderRegistry
+	budget          *tokens.Budgeter
+	// policies override tool policies by tool name; they only make a tool
+	// stricter unless loosenPolicies is set.
+	policies       map[string]ports.ToolPolicy
+	loosenPolicies bool
+
+	// nativeToolsDisabled is set once the model rejects native tool definitions.
+	nativeToolsDisabled bool
 }
 
 func NewService(provider ports.Provider, tools []ports.Tool, logger *slog.Logger, maxSteps int) *Service {
@@ -24,7 +41,18 @@ func NewService(provider ports.Provider, tools []ports.Tool, logger *slog.Logger
 	if maxSteps <= 0 {
 		maxSteps = 15
 	}
-	return &Service{provider: provider, tools: tools, logger: logger, maxSteps: maxSteps}
+	return &Service{provider: provider, tools: tools, logger: logger, maxSteps: maxSteps, maxRepairs: defaultMaxRepairs,
+		toolWorkers: defaultToolWorkers, toolTimeout: defaultToolTimeout}
+}
+
+// defaultMaxRepairs is how many times a malformed action is sent back to the model by default.
+const defaultMaxRepairs = 2
+
+// WithMaxRepairs sets how many correction turns are spent on a malformed action
+// before the run fails. Zero disables repairs.
+func (s *Service) WithMaxRepairs(n int) *Service {
+	s.maxRepairs = max(n, 0)
+	return s
 }
 
 // WithContextRegistry adds a context provider registry to the service
@@ -33,6 +61,33 @@ func (s *Service) WithContextRegistry(registry ports.ContextProviderRegistry) *S
 	return s
 }
 
+// WithTokenBudget fits every step's messages into the model's context window.
+func (s *Service) WithTokenBudget(budget *tokens.Budgeter) *Service {
+	s.budget = budget
+	return s
+}
+
+// WithToolLimits sets how many read-only tools run concurrently and how long one
+// tool run may take. Zero keeps the default.
+func (s *Service) WithToolLimits(workers int, timeout time.Duration) *Service {
+	if workers > 0 {
+		s.toolWorkers = workers
+	}
+	if timeout > 0 {
+		s.toolTimeout = timeout
+	}
+	return s
+}
+
+// WithToolPolicies overrides the policy of the named tools (`tools.policies` in .vibe.yaml).
+// An override only makes a tool stricter unless loosen is set, for overrides
+// the user trusts.
+func (s *Service) WithToolPolicies(policies map[string]ports.ToolPolicy, loosen bool) *Service {
+	s.policies = policies
+	s.loosenPolicies = loosen
+	return s
+}
+
 type SuggestRequest struct {
 	UserRequest string
 	GOOS        string
@@ -45,16 +100,35 @@ type SuggestRequest struct {
 
 	// OnToken is called when a token is generated (streaming)
 	OnToken func(token string)
+
+	// OnConfirm approves tool calls whose policy requires permission.
+	// Without it such calls are refused.
+	OnConfirm func(ToolConfirmation) bool
+
+	// OnAsk shows a clarifying question and returns the user's reply.
+	// Without it an ask action fails the run with ErrNeedsInput.
+	OnAsk func(Question) (string, error)
 }
 
+// Question is a clarifying question from the agent, with optional answer choices.
+type Question struct {
+	Text    string
+	Choices []string
+}
+
+// ErrNeedsInput is returned when the agent asks a question and nobody can answer it.
+var ErrNeedsInput = errors.New("agent needs user input")
+
 type StepInfo struct {
 	Step    int
-	Type    string // "thinking", "tool_call", "tool_output"
+	Type    string // "thinking", "tool_call", "tool_done", "context_trimmed", "repair", "tool_refused"
 	Message string
 }
 
 type SuggestResponse struct {
-	Command     string
+	Command string
+	// Plan is set instead of Command when the agent proposes several steps.
+	Plan        []PlanStep
 	Explanation string
 	StepsUsed   int
 	Transcript  []string
@@ -69,7 +143,7 @@ func (s *Service) SuggestCommand(ctx context.Context, req SuggestRequest) (Sugge
 	transcript := s.initializeTranscript(req)
 
 	// Resolve @mentions to context items (only on first step)
-	contextItems := s.resolveContextMentions(ctx, req.UserRequest)
+	contextItems := s.ResolveContext(ctx, req.UserRequest)
 
 	s.logger.InfoContext(ctx, "agent start", "request", req.UserRequest, "max_steps", s.maxSteps, "context_items", len(contextItems))
 
@@ -91,36 +165,44 @@ func (s *Service) SuggestCommand(ctx context.Context, req SuggestRequest) (Sugge
 			req.OnProgress(StepInfo{Step: step + 1, Type: "thinking", Message: "Analyzing request..."})
 		}
 
-		prompt := buildAgentPrompt(req.GOOS, req.UserRequest, transcript, s.tools, contextItems)
-		s.logger.DebugContext(ctx, "agent generate", "provider", s.provider.Name(), "step", step+1)
+		native := s.useNativeTools()
+		messages := s.fitMessages(ctx, buildAgentMessages(req.GOOS, req.UserRequest, transcript, s.tools, contextItems, native), native, req.OnProgress, step)
+		s.logger.DebugContext(ctx, "agent generate", "provider", s.provider.Name(), "step", step+1, "native_tools", native)
 
-		var responseText string
+		responseText, toolCalls, err := s.generate(ctx, messages, native, req.OnToken, step)
+		if native && errors.Is(err, ports.ErrToolsUnsupported) {
+			s.logger.WarnContext(ctx, "native tool calling unsupported, falling back to JSON protocol", "error", err)
+			s.nativeToolsDisabled = true
+			messages = s.fitMessages(ctx, buildAgentMessages(req.GOOS, req.UserRequest, transcript, s.tools, contextItems, false), false, req.OnProgress, step)
+			responseText, toolCalls, err = s.generate(ctx, messages, false, req.OnToken, step)
+		}
+		if errors.Is(err, ports.ErrBudgetExceeded) {
+			s.logger.WarnContext(ctx, "agent stopped by budget", "error", err, "step", step+1)
+			return SuggestResponse{StepsUsed: step, Transcript: transcript}, err
+		}
+		if err != nil {
+			s.logger.ErrorContext(ctx, "agent generate failed", "error", err, "step", step+1)
+			return SuggestResponse{}, fmt.Errorf("agent generation failed at step %d: %w", step+1, err)
+		}
 
-		if req.OnToken != nil {
-			// Smart streaming: buffer tokens, parse JSON, stream only thought/explanation
-			responseText = s.smartStreamGenerate(ctx, prompt, req.OnToken, step)
-			if responseText == "" {
-				// Fallback to non-streaming if smart stream failed
-				resp, err := s.provider.Generate(ctx, ports.GenerateRequest{Prompt: prompt})
-				if err != nil {
-					s.logger.ErrorContext(ctx, "agent generate failed", "error", err, "step", step+1)
-					return SuggestResponse{}, fmt.Errorf("agent generation failed at step %d: %w", step+1, err)
-				}
-				responseText = resp.Text
-			}
+		var action Action
+		if len(toolCalls) > 0 {
+			action = ActionFromToolCalls(toolCalls, responseText)
 		} else {
-			resp, err := s.provider.Generate(ctx, ports.GenerateRequest{Prompt: prompt})
+			action, err = ParseAction(responseText)
 			if err != nil {
-				s.logger.ErrorContext(ctx, "agent generate failed", "error", err, "step", step+1)
-				return SuggestResponse{}, fmt.Errorf("agent generation failed at step %d: %w", step+1, err)
+				s.logger.WarnContext(ctx, "agent parse error", "error", err, "response", responseText)
+				action, err = s.repairAction(ctx, messages, responseText, err, req, step)
+				if errors.Is(err, ports.ErrBudgetExceeded) {
+					return SuggestResponse{StepsUsed: step, Transcript: transcript}, err
+				}
+				if err != nil {
+					return SuggestResponse{}, err
+				}
 			}
-			responseText = resp.Text
 		}
-
-		action, err := ParseAction(responseText)
-		if err != nil {
-			s.logger.WarnContext(ctx, "agent parse error", "error", err, "response", responseText)
-			return SuggestResponse{}, fmt.Errorf("agent protocol parse error: %w", err)
+		if len(action.UnknownFields) > 0 {
+			s.logger.WarnContext(ctx, "agent action has unknown fields", "fields", action.UnknownFields, "step", step+1)
 		}
 
 		s.logger.InfoContext(ctx, "agent action", "type", action.Type, "step", step+1)
@@ -139,6 +221,39 @@ func (s *Service) SuggestCommand(ctx context.Context, req SuggestRequest) (Sugge
 				Transcript:  transcript,
 			}, nil
 
+		case ActionTypePlan:
+			s.logger.InfoContext(ctx, "agent plan", "steps", len(action.Steps))
+			return SuggestResponse{
+				Plan:        action.Steps,
+				Explanation: strings.TrimSpace(action.Explanation),
+				StepsUsed:   step + 1,
+				Transcript:  transcript,
+			}, nil
+
+		case ActionTypeAsk:
+			q := Question{Text: action.Question, Choices: action.Choices}
+			if req.OnAsk == nil {
+				s.logger.WarnContext(ctx, "agent asked a question in non-interactive mode", "question", q.Text)
+				return SuggestResponse{StepsUsed: step + 1, Transcript: transcript}, fmt.Errorf("%w: %s", ErrNeedsInput, q.Text)
+			}
+			reply, err := req.OnAsk(q)
+			if err != nil {
+				return SuggestResponse{StepsUsed: step + 1, Transcript: transcript}, fmt.Errorf("failed to read the answer: %w", err)
+			}
+			s.logger.InfoContext(ctx, "agent question answered", "question", q.Text, "answer", reply)
+			if strings.TrimSpace(reply) == "" {
+				reply = "(no answer)"
+			}
+			question := q.Text
+			if len(q.Choices) > 0 {
+				question += " (choices: " + strings.Join(q.Choices, ", ") + ")"
+			}
+			transcript = append(transcript,
+				"AGENT_QUESTION: "+question,
+				"USER_ANSWER: "+strings.TrimSpace(reply),
+			)
+			continue
+
 		case ActionTypeAnswer:
 			s.logger.InfoContext(ctx, "agent answer", "explanation", action.Explanation)
 			return SuggestResponse{
@@ -149,32 +264,37 @@ func (s *Service) SuggestCommand(ctx context.Context, req SuggestRequest) (Sugge
 			}, nil
 
 		case ActionTypeTool:
+			calls := action.Invocations()
 			// Callback: Tool Call
 			if req.OnProgress != nil {
-				msg := fmt.Sprintf("Using tool: %s", action.Tool)
-				if action.Thought != "" {
-					msg = fmt.Sprintf("[%s] %s", action.Tool, action.Thought)
+				for i, call := range calls {
+					msg := fmt.Sprintf("Using tool: %s", call.Tool)
+					if i == 0 && action.Thought != "" {
+						msg = fmt.Sprintf("[%s] %s", call.Tool, action.Thought)
+					}
+					req.OnProgress(StepInfo{Step: step + 1, Type: "tool_call", Message: msg})
 				}
-				req.OnProgress(StepInfo{Step: step + 1, Type: "tool_call", Message: msg})
 			}
 
-			// Execute tool and continue loop
-			toolOutput := s.executeTool(ctx, action, toolsByName)
-
-			// Callback: Tool Output
-			if req.OnProgress != nil {
-				// Truncate output for UI if too long
-				displayOut := toolOutput
-				if len(displayOut) > 100 {
-					displayOut = displayOut[:100] + "..."
+			// Execute tools and continue loop
+			outputs := s.executeTools(ctx, calls, toolsByName, req, step)
+
+			for i, call := range calls {
+				// Callback: Tool Output
+				if req.OnProgress != nil {
+					// Truncate output for UI if too long
+					displayOut := outputs[i]
+					if len(displayOut) > 100 {
+						displayOut = displayOut[:100] + "..."
+					}
+					req.OnProgress(StepInfo{Step: step + 1, Type: "tool_done", Message: "Result: " + displayOut})
 				}
-				req.OnProgress(StepInfo{Step: step + 1, Type: "tool_done", Message: "Result: " + displayOut})
-			}
 
-			transcript = append(transcript,
-				fmt.Sprintf("TOOL_CALL: %s %s", action.Tool, strings.TrimSpace(string(action.Input))),
-				fmt.Sprintf("TOOL_OUTPUT: %s", strings.TrimSpace(toolOutput)),
-			)
+				transcript = append(transcript,
+					fmt.Sprintf("TOOL_CALL: %s %s", call.Tool, strings.TrimSpace(string(call.Input))),
+					fmt.Sprintf("TOOL_OUTPUT: %s", strings.TrimSpace(outputs[i])),
+				)
+			}
 			continue
 
 		default:
@@ -189,33 +309,135 @@ func (s *Service) SuggestCommand(ctx context.Context, req SuggestRequest) (Sugge
 	}, fmt.Errorf("agent exceeded max steps (%d) without returning a command", s.maxSteps)
 }
 
-func (s *Service) executeTool(ctx context.Context, action Action, toolsByName map[string]ports.Tool) string {
-	toolName := strings.TrimSpace(action.Tool)
-	tool, ok := toolsByName[toolName]
-	if !ok {
-		msg := fmt.Sprintf("agent requested unknown tool: %s", toolName)
-		s.logger.WarnContext(ctx, "unknown tool", "tool", toolName)
-		return "ERROR: " + msg
+// repairAction sends a malformed response back to the model with the parse error
+// and asks for a corrected action, up to maxRepairs times. Repairs don't count as
+// steps and the malformed output is kept out of the transcript.
+func (s *Service) repairAction(ctx context.Context, messages []chat.Message, bad string, parseErr error, req SuggestRequest, step int) (Action, error) {
+	messages = append([]chat.Message(nil), messages...)
+	for attempt := 1; attempt <= s.maxRepairs; attempt++ {
+		if req.OnProgress != nil {
+			req.OnProgress(StepInfo{Step: step + 1, Type: "repair", Message: fmt.Sprintf("Malformed action (%v), asking the model to fix it (%d/%d)", parseErr, attempt, s.maxRepairs)})
+		}
+		messages = append(messages,
+			chat.Message{Role: chat.RoleAssistant, Content: bad},
+			chat.Message{Role: chat.RoleUser, Content: repairPrompt(parseErr)},
+		)
+		s.logger.DebugContext(ctx, "agent repair", "step", step+1, "attempt", attempt)
+
+		text, toolCalls, err := s.generate(ctx, messages, false, req.OnToken, step)
+		if errors.Is(err, ports.ErrBudgetExceeded) {
+			s.logger.WarnContext(ctx, "agent stopped by budget", "error", err, "step", step+1)
+			return Action{}, err
+		}
+		if err != nil {
+			return Action{}, fmt.Errorf("agent generation failed at step %d: %w", step+1, err)
+		}
+		if len(toolCalls) > 0 {
+			return ActionFromToolCalls(toolCalls, text), nil
+		}
+		action, err := ParseAction(text)
+		if err == nil {
+			s.logger.InfoContext(ctx, "agent action repaired", "step", step+1, "attempts", attempt)
+			return action, nil
+		}
+		s.logger.WarnContext(ctx, "agent parse error", "error", err, "response", text, "attempt", attempt)
+		bad, parseErr = text, err
+	}
+	if s.maxRepairs == 0 {
+		return Action{}, fmt.Errorf("agent protocol parse error: %w", parseErr)
+	}
+	return Action{}, fmt.Errorf("agent protocol parse error after %d repair attempts: %w", s.maxRepairs, parseErr)
+}
+
+// repairPrompt is the correction turn sent after a malformed action.
+func repairPrompt(parseErr error) string {
+	return fmt.Sprintf("Your last response could not be parsed: %v.\n"+
+		"Reply again with EXACTLY ONE JSON object and nothing else, in one of these forms:\n"+
+		`{"type":"tool","thought":"...","tool":"<name>","input":{...}}`+"\n"+
+		`{"type":"tool","thought":"...","calls":[{"tool":"<name>","input":{...}}]}`+"\n"+
+		`{"type":"done","command":"<shell command>","explanation":"..."}`+"\n"+
+		`{"type":"answer","explanation":"..."}`+"\n"+
+		`{"type":"ask","question":"...","choices":["..."]}`+"\n"+
+		`{"type":"plan","explanation":"...","steps":[{"command":"...","purpose":"...","expected":"...","rollback":"..."}]}`, parseErr)
+}
+
+// useNativeTools reports whether tools should be offered through the provider's function-calling API.
+func (s *Service) useNativeTools() bool {
+	return len(s.tools) > 0 && !s.nativeToolsDisabled && ports.SupportsToolCalling(s.provider)
+}
+
+// fitMessages trims messages to the token budget, if any, and reports what was dropped.
+func (s *Service) fitMessages(ctx context.Context, messages []chat.Message, native bool, onProgress func(StepInfo), step int) []chat.Message {
+	if s.budget == nil {
+		return messages
 	}
+	var specs []ports.ToolSpec
+	if native {
+		specs = toolSpecs(s.tools)
+	}
+	fitted, report := s.budget.Fit(messages, specs)
+	if report.Trimmed() {
+		s.logger.WarnContext(ctx, "agent context trimmed", "step", step+1, "before", report.Before, "after", report.After,
+			"limit", report.Limit, "truncated", report.Truncated, "dropped", report.Dropped)
+		if onProgress != nil {
+			onProgress(StepInfo{Step: step + 1, Type: "context_trimmed", Message: report.String()})
+		}
+	}
+	return fitted
+}
 
-	s.logger.DebugContext(ctx, "tool execution start", "tool", toolName)
+// generate runs one model turn, streaming when onToken is set, and returns the text plus any native tool calls.
+func (s *Service) generate(ctx context.Context, messages []chat.Message, native bool, onToken func(string), step int) (string, []chat.ToolCall, error) {
+	genReq := ports.GenerateRequest{Messages: messages}
+	if native {
+		genReq.Tools = toolSpecs(s.tools)
+	} else {
+		genReq.Output = &ports.OutputFormat{Name: "agent_action", Schema: ActionSchema}
+	}
 
-	// Create tool extras with empty callbacks for now
-	extras := ports.ToolExtras{
-		WorkDir: "",
+	if onToken != nil {
+		// Smart streaming: buffer tokens, parse JSON, stream only thought/explanation
+		text, calls := s.smartStreamGenerate(ctx, genReq, onToken, step)
+		if text != "" || len(calls) > 0 {
+			return text, calls, nil
+		}
+		// Fallback to non-streaming if smart stream failed
 	}
 
-	result, err := tool.Run(ctx, action.Input, extras)
+	resp, err := s.provider.Generate(ctx, genReq)
 	if err != nil {
-		s.logger.ErrorContext(ctx, "tool execution failed", "tool", toolName, "error", err)
-		return fmt.Sprintf("ERROR: %v", err)
+		return "", nil, err
+	}
+	return resp.Text, resp.ToolCalls, nil
+}
+
+// authorizeTool applies the tool's policy and returns why the call was refused, or "" to run it.
+func (s *Service) authorizeTool(ctx context.Context, tool ports.Tool, input json.RawMessage, req SuggestRequest, step int) string {
+	def := tool.Definition()
+	var refusal string
+	switch policy := s.policyFor(tool, input); policy {
+	case ports.PolicyAllowed:
+		return ""
+	case ports.PolicyDenied:
+		refusal = fmt.Sprintf("tool %s is denied by policy; do not call it again, find another way", def.Name)
+	default:
+		confirm := ToolConfirmation{Tool: def, Input: input, Summary: summarizeInput(input)}
+		if req.OnConfirm != nil && req.OnConfirm(confirm) {
+			s.logger.InfoContext(ctx, "tool call approved", "tool", def.Name)
+			return ""
+		}
+		if req.OnConfirm == nil {
+			refusal = fmt.Sprintf("tool %s needs the user's permission, which cannot be asked for here; find another way", def.Name)
+		} else {
+			refusal = fmt.Sprintf("the user declined the %s call (%s); propose something else or ask what they want", def.Name, confirm.Summary)
+		}
 	}
-	if result.IsError {
-		s.logger.WarnContext(ctx, "tool returned error result", "tool", toolName)
-		return "ERROR: " + result.Content
+
+	s.logger.WarnContext(ctx, "tool call refused", "tool", def.Name, "reason", refusal)
+	if req.OnProgress != nil {
+		req.OnProgress(StepInfo{Step: step + 1, Type: "tool_refused", Message: refusal})
 	}
-	s.logger.DebugContext(ctx, "tool execution success", "tool", toolName)
-	return result.Content
+	return refusal
 }
 
 func (s *Service) mapToolsByName() map[string]ports.Tool {
@@ -241,8 +463,8 @@ func (s *Service) initializeTranscript(req SuggestRequest) []string {
 	return transcript
 }
 
-// resolveContextMentions parses @mentions from user input and resolves them to context items
-func (s *Service) resolveContextMentions(ctx context.Context, input string) []ports.ContextItem {
+// ResolveContext parses @mentions from user input and resolves them to context items
+func (s *Service) ResolveContext(ctx context.Context, input string) []ports.ContextItem {
 	if s.contextRegistry == nil {
 		return nil
 	}
@@ -279,13 +501,15 @@ func (s *Service) resolveContextMentions(ctx context.Context, input string) []po
 
 // smartStreamGenerate buffers streaming tokens, parses JSON, and streams only thought/explanation
 // This provides a clean UX by not showing raw JSON structure to the user
-func (s *Service) smartStreamGenerate(ctx context.Context, prompt string, onToken func(string), step int) string {
-	streamCh, err := s.provider.StreamGenerate(ctx, ports.GenerateRequest{Prompt: prompt})
+func (s *Service) smartStreamGenerate(ctx context.Context, genReq ports.GenerateRequest, onToken func(string), step int) (string, []chat.ToolCall) {
+	streamCh, err := s.provider.StreamGenerate(ctx, genReq)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "smart stream failed", "error", err, "step", step+1)
-		return "" // Signal to use fallback
+		return "", nil // Signal to use fallback
 	}
 
+	var toolCalls []chat.ToolCall
+
 	var fullBuffer strings.Builder
 	var lastStreamedLen int
 
@@ -298,8 +522,9 @@ func (s *Service) smartStreamGenerate(ctx context.Context, prompt string, onToke
 	for chunk := range streamCh {
 		if chunk.Error != nil {
 			s.logger.ErrorContext(ctx, "smart stream chunk error", "error", chunk.Error)
-			return "" // Signal to use fallback
+			return "", nil // Signal to use fallback
 		}
+		toolCalls = append(toolCalls, chunk.ToolCalls...)
 
 		fullBuffer.WriteString(chunk.Content)
 		currentText := fullBuffer.String()
@@ -362,7 +587,7 @@ func (s *Service) smartStreamGenerate(ctx context.Context, prompt string, onToke
 		}
 	}
 
-	return fullBuffer.String()
+	return fullBuffer.String(), toolCalls
 }
 
 // findUnescapedQuote finds the first unescaped quote in a string
@@ -0,0 +1,569 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/tokens"
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// scriptedProvider returns canned responses in order and records every request.
+type scriptedProvider struct {
+	native    bool
+	responses []ports.GenerateResponse
+	errs      []error
+	requests  []ports.GenerateRequest
+}
+
+func (p *scriptedProvider) Name() string                           { return "scripted" }
+func (p *scriptedProvider) IsConfigured(ctx context.Context) error { return nil }
+func (p *scriptedProvider) Close() error                           { return nil }
+func (p *scriptedProvider) SupportsToolCalling() bool              { return p.native }
+
+func (p *scriptedProvider) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
+	i := len(p.requests)
+	p.requests = append(p.requests, req)
+	if i < len(p.errs) && p.errs[i] != nil {
+		return ports.GenerateResponse{}, p.errs[i]
+	}
+	if i >= len(p.responses) {
+		return ports.GenerateResponse{}, fmt.Errorf("unexpected call %d", i+1)
+	}
+	return p.responses[i], nil
+}
+
+func (p *scriptedProvider) StreamGenerate(ctx context.Context, req ports.GenerateRequest) (<-chan ports.StreamChunk, error) {
+	return nil, fmt.Errorf("streaming not scripted")
+}
+
+type echoTool struct {
+	inputs []string
+}
+
+func (t *echoTool) Definition() ports.ToolDefinition {
+	return ports.ToolDefinition{
+		Name:        "echo",
+		Description: "Echo the input back",
+		ReadOnly:    true,
+		InputSchema: `{"type":"object","properties":{"text":{"type":"string"}},"required":["text"]}`,
+	}
+}
+
+func (t *echoTool) EvaluatePolicy(input json.RawMessage) ports.ToolPolicy { return ports.PolicyAllowed }
+
+func (t *echoTool) Run(ctx context.Context, input json.RawMessage, extras ports.ToolExtras) (ports.ToolResult, error) {
+	t.inputs = append(t.inputs, string(input))
+	return ports.ToolResult{Content: "echoed"}, nil
+}
+
+func TestSuggestCommand_NativeToolCalls(t *testing.T) {
+	tool := &echoTool{}
+	provider := &scriptedProvider{
+		native: true,
+		responses: []ports.GenerateResponse{
+			{Text: "Checking...", ToolCalls: []chat.ToolCall{{ID: "call_0", Name: "echo", Arguments: json.RawMessage(`{"text":"hi"}`)}}},
+			{Text: `{"type":"done","command":"echo hi","explanation":"ok"}`},
+		},
+	}
+
+	svc := NewService(provider, []ports.Tool{tool}, nil, 5)
+	resp, err := svc.SuggestCommand(context.Background(), SuggestRequest{UserRequest: "say hi", GOOS: "linux"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Command != "echo hi" {
+		t.Errorf("unexpected command: %q", resp.Command)
+	}
+	if len(tool.inputs) != 1 || tool.inputs[0] != `{"text":"hi"}` {
+		t.Errorf("unexpected tool inputs: %v", tool.inputs)
+	}
+	if len(provider.requests[0].Tools) != 1 || provider.requests[0].Tools[0].Name != "echo" {
+		t.Errorf("expected tools to be offered natively, got %+v", provider.requests[0].Tools)
+	}
+	if provider.requests[0].Output != nil {
+		t.Errorf("structured output must not be combined with native tools")
+	}
+	msgs := provider.requests[1].Messages
+	if len(msgs) != 4 || msgs[2].Role != chat.RoleAssistant || msgs[3].Role != chat.RoleTool {
+		t.Fatalf("expected system, user, assistant, tool turns, got %+v", msgs)
+	}
+	if msgs[3].Content != "echoed" || msgs[3].ToolCallID != msgs[2].ToolCalls[0].ID || msgs[3].Name != "echo" {
+		t.Errorf("unexpected tool turn: %+v answering %+v", msgs[3], msgs[2].ToolCalls)
+	}
+}
+
+func TestSuggestCommand_FallsBackWhenToolsUnsupported(t *testing.T) {
+	provider := &scriptedProvider{
+		native: true,
+		errs:   []error{fmt.Errorf("%w: llama2", ports.ErrToolsUnsupported)},
+		responses: []ports.GenerateResponse{
+			{},
+			{Text: `{"type":"done","command":"ls","explanation":"list"}`},
+		},
+	}
+
+	svc := NewService(provider, []ports.Tool{&echoTool{}}, nil, 5)
+	resp, err := svc.SuggestCommand(context.Background(), SuggestRequest{UserRequest: "list files", GOOS: "linux"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Command != "ls" {
+		t.Errorf("unexpected command: %q", resp.Command)
+	}
+	if len(provider.requests) != 2 || len(provider.requests[1].Tools) != 0 {
+		t.Fatalf("expected a JSON-protocol retry without tools, got %d requests", len(provider.requests))
+	}
+	if system := provider.requests[1].Messages[0]; !strings.Contains(system.Content, "- echo: Echo the input back") {
+		t.Errorf("expected tools described in the fallback system prompt")
+	}
+}
+
+func TestSuggestCommand_JSONProtocolWithoutToolCalling(t *testing.T) {
+	tool := &echoTool{}
+	provider := &scriptedProvider{
+		responses: []ports.GenerateResponse{
+			{Text: `{"type":"tool","thought":"Checking...","tool":"echo","input":{"text":"x"}}`},
+			{Text: `{"type":"answer","explanation":"done"}`},
+		},
+	}
+
+	svc := NewService(provider, []ports.Tool{tool}, nil, 5)
+	resp, err := svc.SuggestCommand(context.Background(), SuggestRequest{UserRequest: "explain", GOOS: "linux"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Explanation != "done" || len(tool.inputs) != 1 {
+		t.Errorf("unexpected result: %+v, tool inputs %v", resp, tool.inputs)
+	}
+	if len(provider.requests[0].Tools) != 0 {
+		t.Errorf("tools must not be sent to providers without native tool calling")
+	}
+	if out := provider.requests[0].Output; out == nil || string(out.Schema) != string(ActionSchema) {
+		t.Errorf("expected the action schema as structured output, got %+v", out)
+	}
+}
+
+func TestSuggestCommand_RepairsMalformedAction(t *testing.T) {
+	provider := &scriptedProvider{
+		responses: []ports.GenerateResponse{
+			{Text: `Sure! I would run ls.`},
+			{Text: `{"type":"done","command":"ls","explanation":"list files"}`},
+		},
+	}
+
+	var steps []StepInfo
+	svc := NewService(provider, nil, nil, 5)
+	resp, err := svc.SuggestCommand(context.Background(), SuggestRequest{
+		UserRequest: "list files",
+		GOOS:        "linux",
+		OnProgress:  func(s StepInfo) { steps = append(steps, s) },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Command != "ls" || resp.StepsUsed != 1 {
+		t.Errorf("unexpected result: %+v", resp)
+	}
+
+	msgs := provider.requests[1].Messages
+	if len(msgs) < 2 {
+		t.Fatalf("expected a correction turn, got %+v", msgs)
+	}
+	bad, correction := msgs[len(msgs)-2], msgs[len(msgs)-1]
+	if bad.Role != chat.RoleAssistant || bad.Content != "Sure! I would run ls." {
+		t.Errorf("expected the malformed output to be replayed, got %+v", bad)
+	}
+	if correction.Role != chat.RoleUser || !strings.Contains(correction.Content, "no JSON object found") {
+		t.Errorf("expected the parse error in the correction turn, got %+v", correction)
+	}
+	if len(steps) < 2 || steps[1].Type != "repair" {
+		t.Errorf("expected a repair progress event, got %+v", steps)
+	}
+}
+
+func TestSuggestCommand_GivesUpAfterMaxRepairs(t *testing.T) {
+	provider := &scriptedProvider{
+		responses: []ports.GenerateResponse{{Text: "nope"}, {Text: "still nope"}},
+	}
+
+	svc := NewService(provider, nil, nil, 5).WithMaxRepairs(1)
+	_, err := svc.SuggestCommand(context.Background(), SuggestRequest{UserRequest: "list files", GOOS: "linux"})
+	if err == nil || !strings.Contains(err.Error(), "after 1 repair attempts") {
+		t.Errorf("expected a parse error after one repair, got %v", err)
+	}
+	if len(provider.requests) != 2 {
+		t.Errorf("expected 2 calls, got %d", len(provider.requests))
+	}
+}
+
+func TestSuggestCommand_ReturnsPlan(t *testing.T) {
+	provider := &scriptedProvider{
+		responses: []ports.GenerateResponse{
+			{Text: `{"type":"plan","explanation":"two steps","steps":[{"command":"make build"},{"command":"make test","rollback":"make clean"}]}`},
+		},
+	}
+
+	svc := NewService(provider, nil, nil, 5)
+	resp, err := svc.SuggestCommand(context.Background(), SuggestRequest{UserRequest: "build and test", GOOS: "linux"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Command != "" || len(resp.Plan) != 2 || resp.Plan[1].Rollback != "make clean" || resp.Explanation != "two steps" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestSuggestCommand_StopsOnBudget(t *testing.T) {
+	provider := &scriptedProvider{
+		native: true,
+		errs:   []error{nil, fmt.Errorf("%w: spent $0.02 of $0.01", ports.ErrBudgetExceeded)},
+		responses: []ports.GenerateResponse{
+			{ToolCalls: []chat.ToolCall{{Name: "echo", Arguments: json.RawMessage(`{"text":"hi"}`)}}},
+		},
+	}
+
+	svc := NewService(provider, []ports.Tool{&echoTool{}}, nil, 5)
+	resp, err := svc.SuggestCommand(context.Background(), SuggestRequest{UserRequest: "say hi", GOOS: "linux"})
+	if !errors.Is(err, ports.ErrBudgetExceeded) {
+		t.Fatalf("expected budget error, got %v", err)
+	}
+	if resp.StepsUsed != 1 || len(resp.Transcript) == 0 {
+		t.Errorf("expected partial progress to be returned, got %+v", resp)
+	}
+}
+
+// floodTool returns far more output than fits in a small context window.
+type floodTool struct{ echoTool }
+
+func (t *floodTool) Run(ctx context.Context, input json.RawMessage, extras ports.ToolExtras) (ports.ToolResult, error) {
+	return ports.ToolResult{Content: strings.Repeat("log line\n", 2000)}, nil
+}
+
+func TestSuggestCommand_TrimsToTokenBudget(t *testing.T) {
+	provider := &scriptedProvider{
+		native: true,
+		responses: []ports.GenerateResponse{
+			{ToolCalls: []chat.ToolCall{{ID: "call_0", Name: "echo", Arguments: json.RawMessage(`{"text":"hi"}`)}}},
+			{Text: `{"type":"done","command":"echo hi","explanation":"ok"}`},
+		},
+	}
+	est := tokens.EstimatorFor("openai", "gpt-4o")
+	svc := NewService(provider, []ports.Tool{&floodTool{}}, nil, 5).
+		WithTokenBudget(tokens.NewBudgeter(est, 4096, 512))
+
+	var trimmed []StepInfo
+	_, err := svc.SuggestCommand(context.Background(), SuggestRequest{
+		UserRequest: "say hi",
+		GOOS:        "linux",
+		OnProgress: func(info StepInfo) {
+			if info.Type == "context_trimmed" {
+				trimmed = append(trimmed, info)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(trimmed) != 1 {
+		t.Fatalf("expected one context_trimmed event, got %+v", trimmed)
+	}
+	msgs := provider.requests[1].Messages
+	if got := est.Messages(msgs) + est.Tools(provider.requests[1].Tools); got > 4096-512 {
+		t.Errorf("prompt of ~%d tokens does not fit the budget", got)
+	}
+	if !strings.Contains(msgs[len(msgs)-1].Content, "truncated") {
+		t.Errorf("expected the tool output to be truncated, got %d bytes", len(msgs[len(msgs)-1].Content))
+	}
+}
+
+// guardedTool reports a fixed policy and records the commands it runs.
+type guardedTool struct {
+	policy ports.ToolPolicy
+	ran    []string
+}
+
+func (t *guardedTool) Definition() ports.ToolDefinition {
+	return ports.ToolDefinition{Name: "shell", WouldLikeTo: "run the following command", InputSchema: `{"type":"object"}`}
+}
+
+func (t *guardedTool) EvaluatePolicy(input json.RawMessage) ports.ToolPolicy { return t.policy }
+
+func (t *guardedTool) Run(ctx context.Context, input json.RawMessage, extras ports.ToolExtras) (ports.ToolResult, error) {
+	t.ran = append(t.ran, string(input))
+	return ports.ToolResult{Content: "ran"}, nil
+}
+
+func TestSuggestCommand_EnforcesToolPolicy(t *testing.T) {
+	cases := []struct {
+		name      string
+		policy    ports.ToolPolicy
+		overrides map[string]ports.ToolPolicy
+		trusted   bool
+		confirm   func(ToolConfirmation) bool
+		runs      bool
+		refusal   string
+	}{
+		{name: "allowed", policy: ports.PolicyAllowed, runs: true},
+		{name: "denied", policy: ports.PolicyDenied, refusal: "denied by policy"},
+		{name: "approved", policy: ports.PolicyWithPermission, confirm: func(ToolConfirmation) bool { return true }, runs: true},
+		{name: "declined", policy: ports.PolicyWithPermission, confirm: func(ToolConfirmation) bool { return false }, refusal: "user declined the shell call (rm -rf build)"},
+		{name: "no confirm callback", policy: ports.PolicyWithPermission, refusal: "needs the user's permission"},
+		{name: "override cannot loosen", policy: ports.PolicyWithPermission, overrides: map[string]ports.ToolPolicy{"shell": ports.PolicyAllowed}, refusal: "needs the user's permission"},
+		{name: "override still asks", policy: ports.PolicyWithPermission, overrides: map[string]ports.ToolPolicy{"shell": ports.PolicyAllowed}, confirm: func(ToolConfirmation) bool { return false }, refusal: "user declined"},
+		{name: "trusted override allows", policy: ports.PolicyWithPermission, overrides: map[string]ports.ToolPolicy{"shell": ports.PolicyAllowed}, trusted: true, runs: true},
+		{name: "override asks", policy: ports.PolicyAllowed, overrides: map[string]ports.ToolPolicy{"shell": ports.PolicyWithPermission}, refusal: "needs the user's permission"},
+		{name: "override denies", policy: ports.PolicyAllowed, overrides: map[string]ports.ToolPolicy{"shell": ports.PolicyDenied}, refusal: "denied by policy"},
+		{name: "tool denial wins", policy: ports.PolicyDenied, overrides: map[string]ports.ToolPolicy{"shell": ports.PolicyAllowed}, trusted: true, refusal: "denied by policy"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tool := &guardedTool{policy: tc.policy}
+			provider := &scriptedProvider{
+				responses: []ports.GenerateResponse{
+					{Text: `{"type":"tool","thought":"Cleaning...","tool":"shell","input":{"command":"rm -rf build"}}`},
+					{Text: `{"type":"answer","explanation":"done"}`},
+				},
+			}
+
+			var asked []ToolConfirmation
+			req := SuggestRequest{UserRequest: "clean up", GOOS: "linux"}
+			if tc.confirm != nil {
+				req.OnConfirm = func(c ToolConfirmation) bool {
+					asked = append(asked, c)
+					return tc.confirm(c)
+				}
+			}
+
+			svc := NewService(provider, []ports.Tool{tool}, nil, 5).WithToolPolicies(tc.overrides, tc.trusted)
+			resp, err := svc.SuggestCommand(context.Background(), req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ran := len(tool.ran) == 1; ran != tc.runs {
+				t.Errorf("expected runs=%v, tool ran %v", tc.runs, tool.ran)
+			}
+			if tc.confirm != nil && (len(asked) != 1 || asked[0].Summary != "rm -rf build") {
+				t.Errorf("expected one confirmation for the command, got %+v", asked)
+			}
+
+			output := resp.Transcript[len(resp.Transcript)-1]
+			if tc.refusal != "" && (!strings.HasPrefix(output, "TOOL_OUTPUT: ERROR: ") || !strings.Contains(output, tc.refusal)) {
+				t.Errorf("expected the refusal %q to reach the model, got %q", tc.refusal, output)
+			}
+		})
+	}
+}
+
+// rendezvousTool only finishes once every call sharing `arrived` is running at the same time.
+type rendezvousTool struct {
+	name    string
+	arrived *sync.WaitGroup
+}
+
+func (t *rendezvousTool) Definition() ports.ToolDefinition {
+	return ports.ToolDefinition{Name: t.name, ReadOnly: true, InputSchema: `{"type":"object"}`}
+}
+
+func (t *rendezvousTool) EvaluatePolicy(input json.RawMessage) ports.ToolPolicy {
+	return ports.PolicyAllowed
+}
+
+func (t *rendezvousTool) Run(ctx context.Context, input json.RawMessage, extras ports.ToolExtras) (ports.ToolResult, error) {
+	t.arrived.Done()
+	done := make(chan struct{})
+	go func() { t.arrived.Wait(); close(done) }()
+	select {
+	case <-done:
+		return ports.ToolResult{Content: t.name + " ok"}, nil
+	case <-ctx.Done():
+		return ports.ToolResult{}, ctx.Err()
+	}
+}
+
+func TestSuggestCommand_RunsToolCallsInParallel(t *testing.T) {
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	writer := &guardedTool{policy: ports.PolicyAllowed}
+	provider := &scriptedProvider{
+		native: true,
+		responses: []ports.GenerateResponse{
+			{ToolCalls: []chat.ToolCall{
+				{ID: "call_0", Name: "left", Arguments: json.RawMessage(`{}`)},
+				{ID: "call_1", Name: "right", Arguments: json.RawMessage(`{}`)},
+				{ID: "call_2", Name: "shell", Arguments: json.RawMessage(`{"command":"touch x"}`)},
+			}},
+			{Text: `{"type":"answer","explanation":"done"}`},
+		},
+	}
+
+	tools := []ports.Tool{writer, &rendezvousTool{name: "left", arrived: &arrived}, &rendezvousTool{name: "right", arrived: &arrived}}
+	svc := NewService(provider, tools, nil, 5).WithToolLimits(2, time.Second)
+	resp, err := svc.SuggestCommand(context.Background(), SuggestRequest{UserRequest: "look around", GOOS: "linux"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"TOOL_CALL: left {}", "TOOL_OUTPUT: left ok",
+		"TOOL_CALL: right {}", "TOOL_OUTPUT: right ok",
+		`TOOL_CALL: shell {"command":"touch x"}`, "TOOL_OUTPUT: ran",
+	}
+	got := resp.Transcript[len(resp.Transcript)-len(want):]
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected results in call order, got:\n%s", strings.Join(got, "\n"))
+	}
+	if len(writer.ran) != 1 {
+		t.Errorf("expected the write tool to run once, ran %v", writer.ran)
+	}
+}
+
+// loggedTool records when it runs in a log shared with other tools.
+type loggedTool struct {
+	name     string
+	readOnly bool
+	mu       *sync.Mutex
+	log      *[]string
+}
+
+func (t *loggedTool) Definition() ports.ToolDefinition {
+	return ports.ToolDefinition{Name: t.name, ReadOnly: t.readOnly, InputSchema: `{"type":"object"}`}
+}
+
+func (t *loggedTool) EvaluatePolicy(input json.RawMessage) ports.ToolPolicy {
+	return ports.PolicyAllowed
+}
+
+func (t *loggedTool) Run(ctx context.Context, input json.RawMessage, extras ports.ToolExtras) (ports.ToolResult, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*t.log = append(*t.log, t.name+" "+string(input))
+	return ports.ToolResult{Content: t.name + " ok"}, nil
+}
+
+func TestSuggestCommand_KeepsToolCallOrderAroundSideEffects(t *testing.T) {
+	var mu sync.Mutex
+	var log []string
+	provider := &scriptedProvider{
+		native: true,
+		responses: []ports.GenerateResponse{
+			{ToolCalls: []chat.ToolCall{
+				{ID: "call_0", Name: "read", Arguments: json.RawMessage(`{"n":1}`)},
+				{ID: "call_1", Name: "write", Arguments: json.RawMessage(`{}`)},
+				{ID: "call_2", Name: "read", Arguments: json.RawMessage(`{"n":2}`)},
+				{ID: "call_3", Name: "read", Arguments: json.RawMessage(`{"n":3}`)},
+			}},
+			{Text: `{"type":"answer","explanation":"done"}`},
+		},
+	}
+
+	tools := []ports.Tool{
+		&loggedTool{name: "read", readOnly: true, mu: &mu, log: &log},
+		&loggedTool{name: "write", mu: &mu, log: &log},
+	}
+	svc := NewService(provider, tools, nil, 5).WithToolLimits(4, time.Second)
+	if _, err := svc.SuggestCommand(context.Background(), SuggestRequest{UserRequest: "edit", GOOS: "linux"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(log) != 4 || log[0] != `read {"n":1}` || log[1] != "write {}" {
+		t.Fatalf("expected the write to run after the read before it, got %q", log)
+	}
+	if after := strings.Join(log[2:], " "); !strings.Contains(after, `{"n":2}`) || !strings.Contains(after, `{"n":3}`) {
+		t.Errorf("expected the reads after the write to run after it, got %q", log)
+	}
+}
+
+// stuckTool never returns and ignores its context.
+type stuckTool struct{ release chan struct{} }
+
+func (t *stuckTool) Definition() ports.ToolDefinition {
+	return ports.ToolDefinition{Name: "stuck", ReadOnly: true, InputSchema: `{"type":"object"}`}
+}
+
+func (t *stuckTool) EvaluatePolicy(input json.RawMessage) ports.ToolPolicy {
+	return ports.PolicyAllowed
+}
+
+func (t *stuckTool) Run(ctx context.Context, input json.RawMessage, extras ports.ToolExtras) (ports.ToolResult, error) {
+	<-t.release
+	return ports.ToolResult{Content: "too late"}, nil
+}
+
+func TestSuggestCommand_ToolTimeout(t *testing.T) {
+	tool := &stuckTool{release: make(chan struct{})}
+	defer close(tool.release)
+	provider := &scriptedProvider{
+		responses: []ports.GenerateResponse{
+			{Text: `{"type":"tool","thought":"Waiting...","tool":"stuck"}`},
+			{Text: `{"type":"answer","explanation":"gave up"}`},
+		},
+	}
+
+	svc := NewService(provider, []ports.Tool{tool}, nil, 5).WithToolLimits(1, 20*time.Millisecond)
+	resp, err := svc.SuggestCommand(context.Background(), SuggestRequest{UserRequest: "wait", GOOS: "linux"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if last := resp.Transcript[len(resp.Transcript)-1]; last != "TOOL_OUTPUT: ERROR: tool stuck timed out after 20ms" {
+		t.Errorf("expected a timeout result, got %q", last)
+	}
+}
+
+func TestSuggestCommand_AsksAndContinues(t *testing.T) {
+	provider := &scriptedProvider{
+		responses: []ports.GenerateResponse{
+			{Text: `{"type":"ask","question":"Which environment?","choices":["staging","prod"]}`},
+			{Text: `{"type":"done","command":"kubectl --context staging get pods","explanation":"ok"}`},
+		},
+	}
+
+	var asked []Question
+	svc := NewService(provider, nil, nil, 5)
+	resp, err := svc.SuggestCommand(context.Background(), SuggestRequest{
+		UserRequest: "list the pods",
+		GOOS:        "linux",
+		OnAsk: func(q Question) (string, error) {
+			asked = append(asked, q)
+			return "staging", nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(asked) != 1 || asked[0].Text != "Which environment?" || len(asked[0].Choices) != 2 {
+		t.Errorf("unexpected questions: %+v", asked)
+	}
+	if resp.Command != "kubectl --context staging get pods" || resp.StepsUsed != 2 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	msgs := provider.requests[1].Messages
+	question, answer := msgs[len(msgs)-2], msgs[len(msgs)-1]
+	if question.Role != chat.RoleAssistant || !strings.Contains(question.Content, `"type":"ask"`) {
+		t.Errorf("expected the question as an assistant turn, got %+v", question)
+	}
+	if answer.Role != chat.RoleUser || answer.Content != "USER_ANSWER: staging" {
+		t.Errorf("expected the answer as a user turn, got %+v", answer)
+	}
+}
+
+func TestSuggestCommand_AskFailsWithoutInput(t *testing.T) {
+	provider := &scriptedProvider{
+		responses: []ports.GenerateResponse{{Text: `{"type":"ask","question":"Which environment?"}`}},
+	}
+
+	svc := NewService(provider, nil, nil, 5)
+	_, err := svc.SuggestCommand(context.Background(), SuggestRequest{UserRequest: "list the pods", GOOS: "linux"})
+	if !errors.Is(err, ErrNeedsInput) || !strings.Contains(err.Error(), "Which environment?") {
+		t.Errorf("expected ErrNeedsInput with the question, got %v", err)
+	}
+}
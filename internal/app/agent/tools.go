@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+const (
+	// defaultToolWorkers is how many read-only tools run at once by default.
+	defaultToolWorkers = 4
+	// defaultToolTimeout caps a single tool run by default.
+	defaultToolTimeout = 60 * time.Second
+)
+
+// executeTools runs the tool calls of one step and returns their outputs in call order.
+//
+// Calls are authorized one by one first, so confirmations are asked in order. The
+// authorized calls then run in the order the model gave: each run of consecutive
+// read-only calls goes concurrently on a bounded worker pool, and a call with
+// side effects runs alone once the calls before it have finished.
+func (s *Service) executeTools(ctx context.Context, calls []ToolInvocation, toolsByName map[string]ports.Tool, req SuggestRequest, step int) []string {
+	outputs := make([]string, len(calls))
+	tools := make([]ports.Tool, len(calls))
+	for i, call := range calls {
+		name := strings.TrimSpace(call.Tool)
+		tool, ok := toolsByName[name]
+		if !ok {
+			s.logger.WarnContext(ctx, "unknown tool", "tool", name)
+			outputs[i] = fmt.Sprintf("ERROR: agent requested unknown tool: %s", name)
+			continue
+		}
+		if refusal := s.authorizeTool(ctx, tool, call.Input, req, step); refusal != "" {
+			outputs[i] = "ERROR: " + refusal
+			continue
+		}
+		tools[i] = tool
+	}
+
+	var batch []int
+	for i, tool := range tools {
+		switch {
+		case tool == nil:
+		case tool.Definition().ReadOnly:
+			batch = append(batch, i)
+		default:
+			s.runParallel(ctx, batch, tools, calls, outputs)
+			batch = nil
+			outputs[i] = s.runTool(ctx, tool, calls[i].Input)
+		}
+	}
+	s.runParallel(ctx, batch, tools, calls, outputs)
+	return outputs
+}
+
+// runParallel runs the read-only calls at the given indexes on the worker pool
+// and stores their outputs.
+func (s *Service) runParallel(ctx context.Context, batch []int, tools []ports.Tool, calls []ToolInvocation, outputs []string) {
+	if len(batch) > 1 {
+		s.logger.DebugContext(ctx, "running tools in parallel", "count", len(batch), "workers", s.toolWorkers)
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(s.toolWorkers, len(batch)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				outputs[i] = s.runTool(ctx, tools[i], calls[i].Input)
+			}
+		}()
+	}
+	for _, i := range batch {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// runTool runs one authorized tool call under the per-tool timeout and renders
+// its result for the transcript. A tool that ignores its context is abandoned
+// when the timeout expires.
+func (s *Service) runTool(ctx context.Context, tool ports.Tool, input json.RawMessage) string {
+	name := tool.Definition().Name
+	s.logger.DebugContext(ctx, "tool execution start", "tool", name)
+
+	runCtx, cancel := context.WithTimeout(ctx, s.toolTimeout)
+	defer cancel()
+
+	type outcome struct {
+		result ports.ToolResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		// The call is already authorized, so the tool gets no OnConfirm and won't ask again.
+		result, err := tool.Run(runCtx, input, ports.ToolExtras{})
+		done <- outcome{result, err}
+	}()
+
+	var out outcome
+	select {
+	case out = <-done:
+	case <-runCtx.Done():
+		out.err = runCtx.Err()
+	}
+
+	if errors.Is(out.err, context.DeadlineExceeded) && ctx.Err() == nil {
+		s.logger.WarnContext(ctx, "tool timed out", "tool", name, "timeout", s.toolTimeout)
+		return fmt.Sprintf("ERROR: tool %s timed out after %s", name, s.toolTimeout)
+	}
+	if out.err != nil {
+		s.logger.ErrorContext(ctx, "tool execution failed", "tool", name, "error", out.err)
+		return fmt.Sprintf("ERROR: %v", out.err)
+	}
+	if out.result.IsError {
+		s.logger.WarnContext(ctx, "tool returned error result", "tool", name)
+		return "ERROR: " + out.result.Content
+	}
+	s.logger.DebugContext(ctx, "tool execution success", "tool", name)
+	return out.result.Content
+}
@@ -0,0 +1,188 @@
+// Package approval decides which proposed commands may run when nobody is at
+// the terminal to confirm them (CI pipelines, cron jobs, scripts).
+package approval
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/safety"
+)
+
+// Policy is the approval policy of a non-interactive run.
+type Policy string
+
+const (
+	// Strict never runs proposed commands; they are only reported.
+	Strict Policy = "strict"
+	// ReadOnly runs only commands that inspect the system, such as ls, df or docker ps.
+	ReadOnly Policy = "readonly"
+	// Auto also runs commands with side effects, unless the safety check flags them.
+	Auto Policy = "auto"
+)
+
+// Policies lists the known policies.
+var Policies = []Policy{Strict, ReadOnly, Auto}
+
+// ParsePolicy parses a policy name as given on the command line.
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(strings.ToLower(strings.TrimSpace(s))); p {
+	case Strict, ReadOnly, Auto:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid approval policy '%s' (use %s, %s or %s)", s, Strict, ReadOnly, Auto)
+	}
+}
+
+// Decision is the verdict of a policy on one command.
+type Decision struct {
+	Allowed bool
+	// Reason explains a refusal.
+	Reason string
+}
+
+// Evaluate decides whether cmd may run under p.
+func (p Policy) Evaluate(cmd string) Decision {
+	cmd = strings.TrimSpace(cmd)
+	switch p {
+	case ReadOnly:
+		if IsReadOnly(cmd) {
+			return Decision{Allowed: true}
+		}
+		return Decision{Reason: "policy readonly only runs commands that inspect the system"}
+	case Auto:
+		if IsReadOnly(cmd) {
+			return Decision{Allowed: true}
+		}
+		if check := safety.CheckCommand(cmd); check.Level >= safety.Warning {
+			return Decision{Reason: fmt.Sprintf("the safety check flagged it (%s); run it interactively", check.Description)}
+		}
+		return Decision{Allowed: true}
+	default:
+		return Decision{Reason: "policy strict never runs commands"}
+	}
+}
+
+// EvaluateTool decides whether a call to the named tool may run under p. Only
+// the shell's commands can be checked; for other tools the read-only flag is
+// all there is to go on, so only read-only tools run, and never under strict.
+func (p Policy) EvaluateTool(name string, readOnly bool) Decision {
+	switch {
+	case p != ReadOnly && p != Auto:
+		return Decision{Reason: "policy strict never runs tools that ask first"}
+	case !readOnly:
+		return Decision{Reason: fmt.Sprintf("policy %s only runs read-only tools, and %s is not", p, name)}
+	default:
+		return Decision{Allowed: true}
+	}
+}
+
+// readOnlyCommands maps commands that only read to the subcommands that keep them
+// read-only; a nil list means any arguments and an empty one none at all.
+var readOnlyCommands = map[string][]string{
+	"cat": nil, "head": nil, "tail": nil, "less": nil, "wc": nil, "grep": nil, "find": nil,
+	"ls": nil, "pwd": nil, "echo": nil, "stat": nil, "file": nil, "du": nil, "df": nil,
+	"free": nil, "uptime": nil, "ps": nil, "id": nil, "whoami": nil, "date": nil,
+	"uname": nil, "hostname": {}, "which": nil, "ss": nil, "netstat": nil, "lsof": nil,
+	"journalctl": nil,
+	"git":        {"status", "log", "diff", "show"},
+	"docker":     {"ps", "logs", "inspect", "images", "info", "version"},
+	"kubectl":    {"get", "describe", "logs", "top", "version"},
+	"systemctl":  {"status", "is-active", "is-enabled", "is-failed", "list-units"},
+}
+
+// unsafeFlags are the flags that make an otherwise read-only command write files,
+// change the host or wait forever, such as find -delete, date -s or tail -f.
+type unsafeFlags struct {
+	// short are single-letter flags, also caught inside bundles like -fn100.
+	short string
+	// valued are single-letter flags that take a value; the rest of a bundle
+	// after one of them is that value.
+	valued string
+	// long are long options, caught with or without =value. find's actions are
+	// listed here too.
+	long []string
+}
+
+// writingFlags maps commands, or "command subcommand" pairs, to their unsafe flags.
+var writingFlags = map[string]unsafeFlags{
+	"find":         {long: []string{"-delete", "-exec", "-execdir", "-ok", "-okdir", "-fprint", "-fprint0", "-fprintf", "-fls"}},
+	"tail":         {short: "fF", valued: "ncs", long: []string{"--follow"}},
+	"date":         {short: "s", valued: "dfrI", long: []string{"--set"}},
+	"ss":           {short: "K", valued: "AfFN", long: []string{"--kill"}},
+	"less":         {short: "oO", valued: "bhjkpPtTxyzD#", long: []string{"--log-file", "--LOG-FILE"}},
+	"git":          {long: []string{"--output"}},
+	"docker logs":  {short: "f", valued: "n", long: []string{"--follow"}},
+	"kubectl get":  {short: "w", valued: "fLlno", long: []string{"--watch", "--watch-only"}},
+	"kubectl logs": {short: "f", valued: "cl", long: []string{"--follow"}},
+	"journalctl": {short: "f", valued: "bDFgMnoptSuU", long: []string{
+		"--follow", "--vacuum-time", "--vacuum-size", "--vacuum-files", "--rotate", "--flush",
+		"--relinquish-var", "--smart-relinquish-var", "--sync", "--setup-keys", "--update-catalog",
+	}},
+}
+
+// hasWritingFlag reports whether the command in fields is given one of its unsafe flags.
+func hasWritingFlag(fields []string) bool {
+	flags, ok := writingFlags[fields[0]]
+	if len(fields) > 1 {
+		if sub, found := writingFlags[fields[0]+" "+fields[1]]; found {
+			flags, ok = sub, true
+		}
+	}
+	if !ok {
+		return false
+	}
+	for _, arg := range fields[1:] {
+		arg = strings.Trim(arg, `'"`)
+		if arg == "--" {
+			break
+		}
+		for _, opt := range flags.long {
+			if arg == opt || strings.HasPrefix(arg, opt+"=") {
+				return true
+			}
+		}
+		if strings.HasPrefix(arg, "--") || !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		for _, r := range arg[1:] {
+			if strings.ContainsRune(flags.short, r) {
+				return true
+			}
+			if strings.ContainsRune(flags.valued, r) {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// IsReadOnly reports whether cmd only inspects the system: every stage of a
+// pipeline is a known read-only command, and there are no redirections,
+// command lists or substitutions.
+func IsReadOnly(cmd string) bool {
+	if strings.TrimSpace(cmd) == "" || strings.ContainsAny(cmd, "><;&`\n") || strings.Contains(cmd, "$(") {
+		return false
+	}
+	for _, stage := range strings.Split(cmd, "|") {
+		fields := strings.Fields(stage)
+		if len(fields) == 0 {
+			return false
+		}
+		subcommands, ok := readOnlyCommands[fields[0]]
+		if !ok {
+			return false
+		}
+		if subcommands != nil && len(subcommands) == 0 && len(fields) > 1 {
+			return false
+		}
+		if len(subcommands) > 0 && (len(fields) < 2 || !slices.Contains(subcommands, fields[1])) {
+			return false
+		}
+		if hasWritingFlag(fields) {
+			return false
+		}
+	}
+	return true
+}
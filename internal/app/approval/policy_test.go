@@ -0,0 +1,124 @@
+package approval
+
+import "testing"
+
+func TestIsReadOnly(t *testing.T) {
+	cases := map[string]bool{
+		"ls -la /var/log":               true,
+		"df -h":                         true,
+		"ps aux | grep nginx | head -5": true,
+		"docker ps -a":                  true,
+		"git status":                    true,
+		"systemctl status nginx":        true,
+		"cat /etc/nginx/nginx.conf":     true,
+		"docker rm -f api":              false,
+		"git push":                      false,
+		"systemctl restart nginx":       false,
+		"find . -name '*.log' -delete":  false,
+		"tail -f app.log":               false,
+		"tail -F app.log":               false,
+		"tail -fn100 app.log":           false,
+		"tail -n 100 app.log":           true,
+		"journalctl -fu nginx":          false,
+		"journalctl -u nginx -n 50":     true,
+		"kubectl get pods -w":           false,
+		"kubectl get pods --watch":      false,
+		"kubectl get pods -owide":       true,
+		"kubectl logs -f api":           false,
+		"docker logs --follow api":      false,
+		"docker ps -f status=exited":    true,
+		"git diff --output=patch.txt":   false,
+		"git log --output=log.txt":      false,
+		"git diff --stat":               true,
+		"find . -fprint0 files":         false,
+		"hostname":                      true,
+		"hostname newname":              false,
+		"hostname -b newname":           false,
+		"date +%s":                      true,
+		`date -s "2020-01-01"`:          false,
+		"date --set=2020-01-01":         false,
+		"date -us 10:00":                false,
+		"journalctl --vacuum-time=1s":   false,
+		"journalctl --vacuum-size=1M":   false,
+		"journalctl --rotate":           false,
+		"journalctl --flush":            false,
+		"journalctl --relinquish-var":   false,
+		"ss -tlnp":                      true,
+		"ss -K dst 10.0.0.5":            false,
+		"ss -tK":                        false,
+		"ss --kill":                     false,
+		"less app.log":                  true,
+		"less -o file":                  false,
+		"less -O file":                  false,
+		"less --log-file=file app.log":  false,
+		"cat a > b":                     false,
+		"ls && rm -rf build":            false,
+		"echo $(rm -rf /tmp/x)":         false,
+		"ps aux | xargs kill":           false,
+		"catastrophe":                   false,
+		"":                              false,
+	}
+	for cmd, want := range cases {
+		if got := IsReadOnly(cmd); got != want {
+			t.Errorf("IsReadOnly(%q) = %v, want %v", cmd, got, want)
+		}
+	}
+}
+
+func TestPolicy_Evaluate(t *testing.T) {
+	cases := []struct {
+		policy Policy
+		cmd    string
+		want   bool
+	}{
+		{Strict, "ls", false},
+		{ReadOnly, "ls", true},
+		{ReadOnly, "mkdir build", false},
+		{Auto, "mkdir build", true},
+		{Auto, "rm -rf /", false},
+		{Auto, "chmod 777 /etc/passwd", false},
+		{Auto, "cat /etc/hosts", true},
+	}
+	for _, c := range cases {
+		d := c.policy.Evaluate(c.cmd)
+		if d.Allowed != c.want {
+			t.Errorf("%s.Evaluate(%q) = %v, want %v", c.policy, c.cmd, d.Allowed, c.want)
+		}
+		if !d.Allowed && d.Reason == "" {
+			t.Errorf("%s.Evaluate(%q): refusal without a reason", c.policy, c.cmd)
+		}
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	if p, err := ParsePolicy(" ReadOnly "); err != nil || p != ReadOnly {
+		t.Errorf("ParsePolicy: got %q, %v", p, err)
+	}
+	if _, err := ParsePolicy("yolo"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
+
+func TestPolicy_EvaluateTool(t *testing.T) {
+	cases := []struct {
+		policy   Policy
+		readOnly bool
+		want     bool
+	}{
+		{Strict, true, false},
+		{Strict, false, false},
+		{ReadOnly, true, true},
+		{ReadOnly, false, false},
+		{Auto, true, true},
+		{Auto, false, false},
+	}
+	for _, c := range cases {
+		d := c.policy.EvaluateTool("github__create_issue", c.readOnly)
+		if d.Allowed != c.want {
+			t.Errorf("%s.EvaluateTool(read-only %v) = %v, want %v", c.policy, c.readOnly, d.Allowed, c.want)
+		}
+		if !d.Allowed && d.Reason == "" {
+			t.Errorf("%s.EvaluateTool(read-only %v): refusal without a reason", c.policy, c.readOnly)
+		}
+	}
+}
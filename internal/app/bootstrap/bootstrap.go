@@ -2,24 +2,64 @@ package bootstrap
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/provider/cache"
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/provider/failover"
 	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/provider/gemini"
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/provider/httpclient"
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/provider/ollama"
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/provider/openai"
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/provider/replay"
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/provider/sampling"
 	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/sessionstore/jsonfile"
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/tools/mcp"
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/usagestore/jsonl"
 	"github.com/phamdaiminhquan/vibe-devops/internal/app/session"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/tokens"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/usage"
 	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
 	"github.com/phamdaiminhquan/vibe-devops/pkg/config"
 )
 
+// Model roles. Each can be routed to its own provider and model under `ai.roles`
+// and override sampling parameters under `ai.useCases`.
+const (
+	RoleAgent      = "agent"
+	RoleSummarizer = "summarizer"
+	// RoleAnalyzer covers `diagnose --ai` and `logs --analyze`.
+	RoleAnalyzer = "analyzer"
+)
+
+// Roles lists the known model roles.
+var Roles = []string{RoleAgent, RoleSummarizer, RoleAnalyzer}
+
 // ApplicationContext holds dependencies for the application
 type ApplicationContext struct {
-	Config   *config.Config
+	Config *config.Config
+	// Provider serves the active provider and model; roles without their own
+	// route in `ai.roles` use it.
 	Provider ports.Provider
 	Logger   *slog.Logger
+	// Usage meters every call made through Provider and the role providers.
+	Usage *usage.Tracker
+	// Tools are the tools of the MCP servers in `tools.mcpServers`, offered to
+	// the agent next to the built-in ones once ConnectTools has run.
+	Tools []ports.Tool
+
+	roles      map[string]ports.Provider
+	mcpClients []*mcp.Client
+	// policiesTrusted lets the `tools.policies` overrides loosen a tool's policy.
+	policiesTrusted bool
 }
 
 // SessionConfig holds configuration for session management
@@ -49,26 +89,488 @@ func Initialize(ctx context.Context) (*ApplicationContext, error) {
 		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
 	}
 
-	// 3. Instantiate AI provider
+	// 3. Instantiate AI providers (with retry and fallbacks) for each role, or replay a cassette
+	cassette, err := cassetteSettings(cfg)
+	if err != nil {
+		return nil, err
+	}
 	var provider ports.Provider
-	switch cfg.AI.Provider {
-	case "gemini":
-		p, err := gemini.New(cfg.AI.Gemini.APIKey, cfg.AI.Gemini.Model)
-		if err != nil {
-			return nil, err
+	roles := map[string]ports.Provider{}
+	if cassette.Path != "" && cassette.mode == replay.ModeReplay {
+		// The cassette answers for every role, in recording order.
+		logger.Info("replaying cassette", "path", cassette.Path)
+		provider, err = replay.NewPlayer(cassette.Path, cassette.Strict)
+	} else {
+		provider, err = NewProviderChain(cfg, cfg.AI.Provider, "", logger)
+		if err == nil {
+			roles, err = newRoleProviders(cfg, logger)
 		}
-		provider = p
-	default:
-		return nil, fmt.Errorf("unsupported AI provider: %s", cfg.AI.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cassette.Path != "" && cassette.mode == replay.ModeRecord {
+		logger.Info("recording cassette", "path", cassette.Path)
+		recorder := replay.NewRecorder(provider, cassette.Path, logger)
+		provider = recorder
+		for role, p := range roles {
+			roles[role] = recorder.Wrap(p)
+		}
+	}
+
+	// 4. Meter usage (outermost, so budget refusals are never retried).
+	// Replayed calls count towards budgets but are not written to the usage log.
+	project, _ := filepath.Abs(".")
+	store := NewUsageStore()
+	if cassette.Path != "" && cassette.mode == replay.ModeReplay {
+		store = nil
+	}
+	tracker := usage.NewTracker(store, usage.NewPricing(cfg.Usage.Prices), project, logger)
+	tracker.SetBudget(usage.Budget{
+		MaxCostUSD: cfg.Usage.Budget.MaxCostUSD,
+		MaxTokens:  cfg.Usage.Budget.MaxTokens,
+	})
+
+	for role, p := range roles {
+		roles[role] = usage.Wrap(p, tracker)
 	}
 
 	return &ApplicationContext{
 		Config:   cfg,
-		Provider: provider,
+		Provider: usage.Wrap(provider, tracker),
 		Logger:   logger,
+		Usage:    tracker,
+		roles:    roles,
 	}, nil
 }
 
+// newRoleProviders builds a provider chain for each role in `ai.roles` that is
+// routed somewhere other than the active provider and model.
+func newRoleProviders(cfg *config.Config, logger *slog.Logger) (map[string]ports.Provider, error) {
+	roles := map[string]ports.Provider{}
+	defaultProvider, defaultModel := strings.ToLower(strings.TrimSpace(cfg.AI.Provider)), cfg.ActiveModel()
+	for role := range cfg.AI.Roles {
+		if !slices.Contains(Roles, role) {
+			return nil, fmt.Errorf("unknown model role '%s' in ai.roles (use %s)", role, strings.Join(Roles, ", "))
+		}
+		provider, model := cfg.RoleTarget(role)
+		if provider == defaultProvider && model == defaultModel {
+			continue
+		}
+		p, err := NewProviderChain(cfg, provider, model, logger)
+		if err != nil {
+			return nil, fmt.Errorf("model role '%s': %w", role, err)
+		}
+		logger.Debug("model role routed", "role", role, "provider", provider, "model", model)
+		roles[role] = p
+	}
+	return roles, nil
+}
+
+// roleProvider returns the provider routed to role, without sampling overrides.
+func (a *ApplicationContext) roleProvider(role string) ports.Provider {
+	if p, ok := a.roles[role]; ok {
+		return p
+	}
+	return a.Provider
+}
+
+// ProviderFor returns the provider routed to role, with the sampling overrides configured for it.
+func (a *ApplicationContext) ProviderFor(role string) ports.Provider {
+	return sampling.Wrap(a.roleProvider(role), samplingParams(a.Config.AI.UseCases[role]))
+}
+
+// SwitchModel routes role to provider and model for the rest of the process,
+// metered by the same usage tracker. The configuration on disk is not changed.
+func (a *ApplicationContext) SwitchModel(role, provider, model string) error {
+	if cassette, _ := cassetteSettings(a.Config); cassette.Path != "" {
+		return fmt.Errorf("cannot switch models while a cassette is in use")
+	}
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	if !config.IsSupportedProvider(provider) {
+		return fmt.Errorf("unsupported provider '%s' (use %s)", provider, strings.Join(config.SupportedProviders, ", "))
+	}
+	p, err := NewProviderChain(a.Config, provider, model, a.Logger)
+	if err != nil {
+		return err
+	}
+	if a.Config.AI.Roles == nil {
+		a.Config.AI.Roles = map[string]config.RoleConfig{}
+	}
+	a.Config.AI.Roles[role] = config.RoleConfig{Provider: provider, Model: model}
+	if old, ok := a.roles[role]; ok {
+		_ = old.Close()
+	}
+	a.roles[role] = usage.Wrap(p, a.Usage)
+	return nil
+}
+
+// mcpReadTimeout fails a call to an MCP HTTP server that sends nothing for
+// this long, unless `ai.http.readTimeout` is set. It is well above the default
+// tool timeout, so only a stalled server hits it.
+const mcpReadTimeout = 5 * time.Minute
+
+// TrustFunc asks the user whether to trust settings from a project's
+// .vibe.yaml, shown as lines under warning, and reports the answer.
+type TrustFunc func(warning string, lines []string) bool
+
+// ConnectTools connects to the MCP servers in `tools.mcpServers` and adds
+// their tools to Tools. A server that fails is skipped and reported in the
+// returned error; the tools of the others are still added.
+//
+// Servers started as local processes come from the project's .vibe.yaml, so
+// they only start once trust approves them for this project; the answer is
+// kept until they change. A nil trust, for runs nobody can answer, skips them.
+func (a *ApplicationContext) ConnectTools(ctx context.Context, version string, trust TrustFunc) error {
+	if len(a.Config.Tools.MCPServers) == 0 {
+		return nil
+	}
+	servers := a.Config.Tools.MCPServers
+	var errs []error
+	if local := mcp.LocalServers(servers); len(local) > 0 &&
+		!trustProject("trusted_mcp.json", local, "This project's .vibe.yaml starts these MCP servers on your machine, with your permissions", mcp.CommandLines(local), trust) {
+		servers = maps.Clone(servers)
+		for name := range local {
+			delete(servers, name)
+		}
+		errs = append(errs, fmt.Errorf("not starting the project's MCP servers (%s) until you trust them", strings.Join(slices.Sorted(maps.Keys(local)), ", ")))
+	}
+
+	opts := httpOptions(a.Config.AI.HTTP)
+	if opts.ReadTimeout <= 0 {
+		opts.ReadTimeout = mcpReadTimeout
+	}
+	httpClient, err := httpclient.New(opts)
+	if err != nil {
+		return fmt.Errorf("invalid ai.http settings: %w", err)
+	}
+
+	clients, tools, err := mcp.Load(ctx, servers, version, httpClient)
+	for _, c := range clients {
+		a.Logger.Info("connected to mcp server", "name", c.Name(), "server", c.Server)
+	}
+	for _, t := range tools {
+		a.Tools = append(a.Tools, t)
+	}
+	a.mcpClients = append(a.mcpClients, clients...)
+	if err := errors.Join(append(errs, err)...); err != nil {
+		a.Logger.Warn("mcp servers failed", "error", err)
+		return err
+	}
+	return nil
+}
+
+// trustProject reports whether the user trusts settings of the project,
+// asking through trust when they have not answered for these exact settings
+// yet. The answers are kept in the named file under ~/.vibe.
+func trustProject(file string, settings any, warning string, lines []string, trust TrustFunc) bool {
+	project, err := filepath.Abs(".")
+	if err != nil {
+		return false
+	}
+	var store *mcp.TrustStore
+	if home, err := os.UserHomeDir(); err == nil {
+		store = mcp.NewTrustStore(filepath.Join(home, ".vibe", file))
+		if store.Trusted(project, settings) {
+			return true
+		}
+	}
+	if trust == nil || !trust(warning, lines) {
+		return false
+	}
+	if store != nil {
+		_ = store.Trust(project, settings)
+	}
+	return true
+}
+
+// Close stops the MCP servers and closes the active and per-role providers.
+func (a *ApplicationContext) Close() error {
+	var errs []error
+	for _, c := range a.mcpClients {
+		errs = append(errs, c.Close())
+	}
+	a.mcpClients = nil
+	for role, p := range a.roles {
+		errs = append(errs, p.Close())
+		delete(a.roles, role)
+	}
+	errs = append(errs, a.Provider.Close())
+	return errors.Join(errs...)
+}
+
+// ToolPolicies returns the tool policy overrides from `tools.policies`.
+func (a *ApplicationContext) ToolPolicies() (map[string]ports.ToolPolicy, error) {
+	policies := make(map[string]ports.ToolPolicy, len(a.Config.Tools.Policies))
+	for name, value := range a.Config.Tools.Policies {
+		policy, err := ports.ParseToolPolicy(value)
+		if err != nil {
+			return nil, fmt.Errorf("tools.policies.%s: %w", name, err)
+		}
+		policies[name] = policy
+	}
+	return policies, nil
+}
+
+// TrustToolPolicies decides whether the `allowed` overrides in
+// `tools.policies` may loosen a tool's own policy. They come from the
+// project's .vibe.yaml, so like its MCP servers they only apply once trust
+// approves them; until then the overrides can only make tools stricter. A nil
+// trust, for runs nobody can answer, keeps them from loosening anything.
+func (a *ApplicationContext) TrustToolPolicies(trust TrustFunc) error {
+	loosening := map[string]string{}
+	for name, value := range a.Config.Tools.Policies {
+		if policy, err := ports.ParseToolPolicy(value); err == nil && policy == ports.PolicyAllowed {
+			loosening[name] = value
+		}
+	}
+	if len(loosening) == 0 {
+		return nil
+	}
+	names := slices.Sorted(maps.Keys(loosening))
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = name + ": " + loosening[name]
+	}
+	a.policiesTrusted = trustProject("trusted_policies.json", loosening, "This project's .vibe.yaml lets these tools run without asking", lines, trust)
+	if !a.policiesTrusted {
+		return fmt.Errorf("not letting %s run without asking until you trust the project's policies", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// ToolPoliciesTrusted reports whether the overrides from ToolPolicies may
+// loosen a tool's policy, not only tighten it.
+func (a *ApplicationContext) ToolPoliciesTrusted() bool {
+	return a.policiesTrusted
+}
+
+// TokenBudget returns the context-window budget of the model serving role.
+// The answer's share of the window is the configured maxTokens, or an eighth of
+// the window (between 512 and 4096 tokens).
+func (a *ApplicationContext) TokenBudget(role string) *tokens.Budgeter {
+	provider, model := a.Config.RoleTarget(role)
+	window := tokens.ContextWindow(provider, model, a.Config.ContextWindow(provider))
+
+	reserve := a.Config.AI.UseCases[role].MaxTokens
+	if reserve == 0 {
+		reserve = a.providerSampling(provider).MaxTokens
+	}
+	if reserve == 0 {
+		reserve = min(max(window/8, 512), 4096)
+	}
+	return tokens.NewBudgeter(tokens.EstimatorFor(provider, model), window, reserve)
+}
+
+func (a *ApplicationContext) providerSampling(provider string) config.SamplingConfig {
+	switch provider {
+	case config.ProviderOpenAI:
+		return a.Config.AI.OpenAI.Sampling
+	case config.ProviderOllama:
+		return a.Config.AI.Ollama.Sampling
+	default:
+		return a.Config.AI.Gemini.Sampling
+	}
+}
+
+// CachedProvider returns ProviderFor(role) behind the on-disk response cache, unless
+// caching is disabled by the caller (--no-cache), by `cache.disabled` in config, or a
+// cassette is in use (cache hits would hide calls from it).
+// Use it for idempotent analysis prompts, not for agent runs.
+func (a *ApplicationContext) CachedProvider(role string, enabled bool) ports.Provider {
+	if !enabled || a.Config.Cache.Disabled {
+		return a.ProviderFor(role)
+	}
+	if cassette, _ := cassetteSettings(a.Config); cassette.Path != "" {
+		return a.ProviderFor(role)
+	}
+	// Sampling goes outside the cache so that overrides are part of the cache key.
+	_, model := a.Config.RoleTarget(role)
+	cached := cache.New(a.roleProvider(role), NewCacheStore(a.Config), model, a.Logger)
+	return sampling.Wrap(cached, samplingParams(a.Config.AI.UseCases[role]))
+}
+
+// NewCacheStore returns the response cache under ~/.vibe/cache.
+func NewCacheStore(cfg *config.Config) *cache.Store {
+	ttl := 24 * time.Hour
+	maxMB := 100
+	if cfg != nil {
+		if cfg.Cache.TTL > 0 {
+			ttl = cfg.Cache.TTL
+		}
+		if cfg.Cache.MaxSizeMB > 0 {
+			maxMB = cfg.Cache.MaxSizeMB
+		}
+	}
+	home, _ := os.UserHomeDir()
+	return cache.NewStore(filepath.Join(home, ".vibe", "cache"), ttl, int64(maxMB)<<20)
+}
+
+type cassetteOptions struct {
+	config.CassetteConfig
+	mode replay.Mode
+}
+
+// cassetteSettings resolves `ai.cassette`, overridden by VIBE_CASSETTE and VIBE_CASSETTE_MODE.
+func cassetteSettings(cfg *config.Config) (cassetteOptions, error) {
+	opts := cassetteOptions{CassetteConfig: cfg.AI.Cassette}
+	if path := strings.TrimSpace(os.Getenv("VIBE_CASSETTE")); path != "" {
+		opts.Path = path
+	}
+	if mode := strings.TrimSpace(os.Getenv("VIBE_CASSETTE_MODE")); mode != "" {
+		opts.Mode = mode
+	}
+	mode, err := replay.ParseMode(opts.Mode)
+	if err != nil {
+		return opts, err
+	}
+	opts.mode = mode
+	return opts, nil
+}
+
+// NewUsageStore returns the global usage log (~/.vibe/usage.jsonl).
+func NewUsageStore() ports.UsageStore {
+	home, _ := os.UserHomeDir()
+	return jsonl.New(filepath.Join(home, ".vibe", "usage.jsonl"))
+}
+
+// NewProvider builds the ports.Provider for the named provider section of cfg,
+// applying that section's sampling defaults. An empty model uses the section's model.
+func NewProvider(cfg *config.Config, name, model string) (ports.Provider, error) {
+	p, err := newAdapter(cfg, name, model)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case config.ProviderOpenAI:
+		return sampling.Wrap(p, samplingParams(cfg.AI.OpenAI.Sampling)), nil
+	case config.ProviderOllama:
+		return sampling.Wrap(p, samplingParams(cfg.AI.Ollama.Sampling)), nil
+	default:
+		return sampling.Wrap(p, samplingParams(cfg.AI.Gemini.Sampling)), nil
+	}
+}
+
+// NewModelCatalog returns the model listing of the named provider and a func
+// that closes it. The Ollama catalog also implements ports.ModelPuller.
+func NewModelCatalog(cfg *config.Config, name string) (ports.ModelLister, func() error, error) {
+	p, err := newAdapter(cfg, name, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	lister, ok := p.(ports.ModelLister)
+	if !ok {
+		_ = p.Close()
+		return nil, nil, fmt.Errorf("provider %s cannot list models", name)
+	}
+	return lister, p.Close, nil
+}
+
+// newAdapter builds the bare provider adapter, without decorators.
+func newAdapter(cfg *config.Config, name, model string) (ports.Provider, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if model == "" {
+		model = cfg.ModelFor(name)
+	}
+	httpClient, err := httpclient.New(httpOptions(cfg.AI.HTTP))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ai.http settings: %w", err)
+	}
+	switch name {
+	case config.ProviderGemini:
+		p, err := gemini.New(cfg.AI.Gemini.APIKey, model, httpClient)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	case config.ProviderOpenAI:
+		p := openai.New(cfg.AI.OpenAI.APIKey, model, openai.Options{
+			BaseURL:      cfg.AI.OpenAI.BaseURL,
+			Organization: cfg.AI.OpenAI.Organization,
+			APIVersion:   cfg.AI.OpenAI.APIVersion,
+			Headers:      cfg.AI.OpenAI.Headers,
+			HTTPClient:   httpClient,
+		})
+		if err := p.IsConfigured(context.Background()); err != nil {
+			return nil, err
+		}
+		return p, nil
+	case config.ProviderOllama:
+		p, err := ollama.New(cfg.AI.Ollama.Host, model, httpClient)
+		if err != nil {
+			return nil, err
+		}
+		return p.WithContextWindow(cfg.AI.Ollama.ContextWindow), nil
+	default:
+		return nil, fmt.Errorf("unsupported AI provider: %s", name)
+	}
+}
+
+func httpOptions(c config.HTTPConfig) httpclient.Options {
+	return httpclient.Options{
+		Proxy:          c.Proxy,
+		NoProxy:        c.NoProxy,
+		CABundle:       expandHome(c.CABundle),
+		ClientCert:     expandHome(c.ClientCert),
+		ClientKey:      expandHome(c.ClientKey),
+		ConnectTimeout: c.ConnectTimeout,
+		ReadTimeout:    c.ReadTimeout,
+	}
+}
+
+// expandHome resolves a leading "~/" in configured file paths.
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+func samplingParams(c config.SamplingConfig) ports.Sampling {
+	return ports.Sampling{
+		Temperature: c.Temperature,
+		TopP:        c.TopP,
+		MaxTokens:   c.MaxTokens,
+		Stop:        c.Stop,
+		Seed:        c.Seed,
+	}
+}
+
+// NewProviderChain builds the named provider (with model, or the section's model)
+// followed by `ai.fallbacks`, wrapped in the retry/failover decorator. Fallbacks
+// that cannot be built are skipped.
+func NewProviderChain(cfg *config.Config, name, model string, logger *slog.Logger) (ports.Provider, error) {
+	primary, err := NewProvider(cfg, name, model)
+	if err != nil {
+		return nil, err
+	}
+
+	providers := []ports.Provider{primary}
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(name)): true}
+	for _, name := range cfg.AI.Fallbacks {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		p, err := NewProvider(cfg, name, "")
+		if err != nil {
+			logger.Warn("skipping fallback provider", "provider", name, "error", err)
+			continue
+		}
+		providers = append(providers, p)
+	}
+
+	return failover.New(providers, failover.Policy{
+		MaxAttempts:    cfg.AI.Retry.MaxAttempts,
+		InitialBackoff: cfg.AI.Retry.InitialBackoff,
+		MaxBackoff:     cfg.AI.Retry.MaxBackoff,
+	}, logger), nil
+}
+
 // InitializeSessionService creates the session service based on config
 func InitializeSessionService(provider ports.Provider, cfg SessionConfig) *session.Service {
 	if cfg.NoSession {
@@ -0,0 +1,404 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"runtime"
+	"sort"
+	"strings"
+
+	ctxregistry "github.com/phamdaiminhquan/vibe-devops/internal/adapters/context"
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/terminal"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/agent"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/bootstrap"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/diagnose"
+	vibegit "github.com/phamdaiminhquan/vibe-devops/internal/app/git"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/session"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+const chatHelp = `Commands:
+  /context @provider query   Add context to the conversation (e.g. /context @logs app.log)
+  /tools                     List the agent's tools and their policies
+  /model [provider/]model    Show or switch the agent's model for this chat
+  /session [save|clear]      Show, save or clear the conversation
+  /undo                      Restore the checkpoint taken when the chat started
+  /diagnose                  Run a system health check and share it with the agent
+  /exit                      Save the session and quit
+Anything else is sent to the agent. Ctrl+C cancels a running turn.`
+
+// ChatHandler encapsulates the logic for the 'chat' command: one agent
+// transcript kept across turns, with slash commands in between.
+type ChatHandler struct {
+	Ctx         *bootstrap.ApplicationContext
+	Sess        *session.Service
+	SessionName string
+
+	run      *RunHandler
+	ui       ports.UI
+	in       terminal.LineReader
+	agent    *agent.Service
+	tools    []ports.Tool
+	registry *ctxregistry.Registry
+
+	// task is the first message of the conversation; the agent sees it as the request.
+	task       string
+	transcript []string
+	// pending holds context added before the first message.
+	pending []string
+	// saved is how many transcript lines are already persisted to the session.
+	saved int
+}
+
+// NewChatHandler creates a new handler instance. Messages are read from in,
+// which ui must also read its prompts from: two buffered readers on stdin
+// would steal each other's input.
+func NewChatHandler(ctx *bootstrap.ApplicationContext, sess *session.Service, ui ports.UI, in terminal.LineReader, flags RunFlags, sessionName string) *ChatHandler {
+	return &ChatHandler{
+		Ctx:         ctx,
+		Sess:        sess,
+		SessionName: sessionName,
+		run:         NewRunHandler(ctx, sess, ui, flags),
+		ui:          ui,
+		in:          in,
+		tools:       agentTools(ctx.Tools),
+		registry:    newContextRegistry(),
+	}
+}
+
+// Handle runs the read-eval-print loop until /exit or end of input.
+func (h *ChatHandler) Handle(ctx context.Context) error {
+	if err := h.newAgent(); err != nil {
+		return err
+	}
+	h.run.checkDependencies(ctx)
+	createCheckpoint(h.ui)
+
+	provider, model := h.Ctx.Config.RoleTarget(bootstrap.RoleAgent)
+	h.ui.Message(ports.MessageInfo, fmt.Sprintf("[VIBE] Chat with %s/%s. Type /help for commands, /exit to quit.", provider, model))
+	for {
+		line, err := h.in.ReadLine("vibe> ")
+		if errors.Is(err, io.EOF) {
+			h.ui.Message(ports.MessageInfo, "")
+			break
+		}
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "/") {
+			if quit := h.slashCommand(ctx, line); quit {
+				break
+			}
+			continue
+		}
+		h.send(ctx, line)
+	}
+
+	h.save(ctx)
+	return nil
+}
+
+func (h *ChatHandler) newAgent() error {
+	ag, err := h.run.newAgent(h.tools, h.registry)
+	if err != nil {
+		return err
+	}
+	h.agent = ag
+	return nil
+}
+
+// send adds a user message to the conversation and runs an agent turn.
+// Ctrl+C cancels the turn but keeps the chat open.
+func (h *ChatHandler) send(ctx context.Context, message string) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	if h.task == "" {
+		h.task = message
+		h.transcript = append(h.seedTranscript(message), h.pending...)
+		h.pending = nil
+	} else {
+		h.transcript = append(h.transcript, "USER_MESSAGE: "+message)
+		h.transcript = append(h.transcript, h.resolveContext(ctx, message)...)
+	}
+	h.turn(ctx)
+}
+
+// seedTranscript starts the conversation from the saved session, if any.
+func (h *ChatHandler) seedTranscript(task string) []string {
+	if h.Sess != nil {
+		if combined, err := h.Sess.LoadCombined(session.ScopeBoth, h.SessionName); err == nil {
+			return h.Sess.BuildSeedTranscript(combined, task, runtime.GOOS)
+		}
+	}
+	return []string{"USER_REQUEST: " + task, "GOOS: " + runtime.GOOS}
+}
+
+// turn asks the agent for the next action and carries it out. Commands and plans
+// go through the same safety checks and confirmations as 'vibe run'; with self-heal
+// the agent then reads the output and continues.
+func (h *ChatHandler) turn(ctx context.Context) {
+	followUps := 0
+	for {
+		console := h.run.console()
+		resp, err := h.agent.SuggestCommand(ctx, agent.SuggestRequest{
+			UserRequest: h.task,
+			GOOS:        runtime.GOOS,
+			Transcript:  h.transcript,
+			OnProgress:  console.progress,
+			OnToken:     h.ui.Token,
+			OnConfirm:   console.confirm,
+			OnAsk:       console.asker(),
+		})
+		if len(resp.Transcript) > 0 {
+			h.transcript = resp.Transcript
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, context.Canceled):
+				h.ui.Message(ports.MessageInfo, "[VIBE] Cancelled.")
+			case strings.Contains(err.Error(), "agent exceeded max steps"):
+				h.ui.Message(ports.MessageInfo, fmt.Sprintf("[VIBE] Agent stopped after %d steps. Say 'continue' to let it go on.", h.run.Flags.AgentMaxSteps))
+			default:
+				h.ui.Message(ports.MessageInfo, fmt.Sprintf("[VIBE] Error: %v", err))
+			}
+			return
+		}
+
+		h.ui.Explain(resp.Explanation)
+		h.recordAction(resp)
+
+		switch {
+		case len(resp.Plan) > 0:
+			outcome, transcript, err := h.run.executePlan(ctx, resp.Plan, h.transcript, h.task)
+			h.transcript = transcript
+			if outcome == planReplan {
+				continue
+			}
+			if err != nil {
+				h.ui.Message(ports.MessageInfo, fmt.Sprintf("[VIBE] %v", err))
+			}
+			return
+		case resp.Command != "":
+			if !h.runCommand(ctx, resp.Command) {
+				return
+			}
+			followUps++
+			if !h.run.Flags.SelfHeal || followUps > h.run.Flags.SelfHealMaxAttempts {
+				return
+			}
+			h.transcript = append(h.transcript,
+				"INSTRUCTION: Based on the execution result above, either answer the user's question (type=answer) or propose the next best command (type=done).")
+		default:
+			return
+		}
+	}
+}
+
+// recordAction keeps the agent's final action in the transcript, so later turns see what it answered or proposed.
+func (h *ChatHandler) recordAction(resp agent.SuggestResponse) {
+	action := agent.Action{Type: agent.ActionTypeAnswer, Explanation: resp.Explanation}
+	switch {
+	case len(resp.Plan) > 0:
+		action = agent.Action{Type: agent.ActionTypePlan, Steps: resp.Plan, Explanation: resp.Explanation}
+	case resp.Command != "":
+		action = agent.Action{Type: agent.ActionTypeDone, Command: resp.Command, Explanation: resp.Explanation}
+	}
+	if data, err := json.Marshal(action); err == nil {
+		h.transcript = append(h.transcript, "AGENT_ACTION: "+string(data))
+	}
+}
+
+// runCommand runs a proposed command once the user approves it and records the
+// result. It reports whether the command ran.
+func (h *ChatHandler) runCommand(ctx context.Context, cmd string) bool {
+	if !h.run.approveCommand(cmd) {
+		h.transcript = append(h.transcript, "EXEC_SKIPPED: the user declined to run: "+cmd)
+		return false
+	}
+	h.ui.Message(ports.MessageInfo, "Executing command...")
+	res, stdout, stderr, err := h.run.runStep(ctx, h.run.newExecutor(), cmd)
+	h.ui.Result(outcome(cmd, res, err, ""))
+	h.transcript = append(h.transcript,
+		"EXEC_COMMAND: "+cmd,
+		fmt.Sprintf("EXEC_RESULT: exit_code=%d", res.ExitCode),
+		"EXEC_STDOUT_TAIL: "+tailString(stdout, 4000),
+		"EXEC_STDERR_TAIL: "+tailString(stderr, 4000),
+	)
+	return true
+}
+
+// resolveContext turns the @mentions in input into CONTEXT transcript lines.
+func (h *ChatHandler) resolveContext(ctx context.Context, input string) []string {
+	var lines []string
+	for _, item := range h.agent.ResolveContext(ctx, input) {
+		lines = append(lines, fmt.Sprintf("CONTEXT: --- %s ---\n%s", item.Name, item.Content))
+	}
+	return lines
+}
+
+// slashCommand runs a /command and reports whether the chat should end.
+func (h *ChatHandler) slashCommand(ctx context.Context, line string) bool {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch strings.ToLower(name) {
+	case "/exit", "/quit":
+		return true
+	case "/help":
+		h.ui.Message(ports.MessageInfo, chatHelp)
+	case "/context":
+		h.addContext(ctx, arg)
+	case "/tools":
+		h.listTools()
+	case "/model":
+		h.switchModel(arg)
+	case "/session":
+		h.sessionCommand(ctx, arg)
+	case "/undo":
+		h.undo()
+	case "/diagnose":
+		h.diagnose(ctx)
+	default:
+		h.ui.Message(ports.MessageInfo, fmt.Sprintf("Unknown command %s. Type /help for commands.", name))
+	}
+	return false
+}
+
+func (h *ChatHandler) addContext(ctx context.Context, arg string) {
+	if arg == "" {
+		h.ui.Message(ports.MessageInfo, "Usage: /context @provider query. Providers:")
+		descriptions := h.registry.Descriptions()
+		sort.Slice(descriptions, func(i, j int) bool { return descriptions[i].Name < descriptions[j].Name })
+		for _, d := range descriptions {
+			h.ui.Message(ports.MessageInfo, fmt.Sprintf("  @%-8s %s", d.Name, d.Description))
+		}
+		return
+	}
+	lines := h.resolveContext(ctx, arg)
+	if len(lines) == 0 {
+		h.ui.Message(ports.MessageInfo, "No context found. Usage: /context @provider query")
+		return
+	}
+	h.note(lines...)
+	h.ui.Message(ports.MessageInfo, fmt.Sprintf("[VIBE] Added %d context item(s).", len(lines)))
+}
+
+func (h *ChatHandler) listTools() {
+	policies, _ := h.Ctx.ToolPolicies()
+	for _, tool := range h.tools {
+		def := tool.Definition()
+		access := "effects"
+		if def.ReadOnly {
+			access = "read-only"
+		}
+		policy := string(def.DefaultPolicy)
+		if override, ok := policies[def.Name]; ok {
+			policy = fmt.Sprintf("%s (configured: %s)", policy, override)
+		}
+		h.ui.Message(ports.MessageInfo, fmt.Sprintf("  %-12s %-9s %s", def.Name, access, policy))
+	}
+}
+
+func (h *ChatHandler) switchModel(arg string) {
+	provider, model := h.Ctx.Config.RoleTarget(bootstrap.RoleAgent)
+	if arg == "" {
+		h.ui.Message(ports.MessageInfo, fmt.Sprintf("Agent model: %s/%s", provider, model))
+		return
+	}
+	if p, m, ok := strings.Cut(arg, "/"); ok {
+		provider, model = p, m
+	} else {
+		model = arg
+	}
+	if err := h.Ctx.SwitchModel(bootstrap.RoleAgent, provider, model); err != nil {
+		h.ui.Message(ports.MessageError, err.Error())
+		return
+	}
+	if err := h.newAgent(); err != nil {
+		h.ui.Message(ports.MessageError, err.Error())
+		return
+	}
+	provider, model = h.Ctx.Config.RoleTarget(bootstrap.RoleAgent)
+	h.ui.Message(ports.MessageInfo, fmt.Sprintf("[VIBE] Agent model switched to %s/%s.", provider, model))
+}
+
+func (h *ChatHandler) sessionCommand(ctx context.Context, arg string) {
+	switch arg {
+	case "":
+		totals := h.Ctx.Usage.Totals()
+		h.ui.Message(ports.MessageInfo, fmt.Sprintf("Session:    %s\nTask:       %s\nTranscript: %d lines (%d unsaved)\nUsage:      %d calls, %d tokens, $%.4f",
+			h.SessionName, h.task, len(h.transcript), len(h.transcript)-h.saved, totals.Calls, totals.TotalTokens(), totals.CostUSD))
+	case "save":
+		h.save(ctx)
+	case "clear":
+		h.task, h.transcript, h.pending, h.saved = "", nil, nil, 0
+		h.ui.Message(ports.MessageInfo, "[VIBE] Conversation cleared.")
+	default:
+		h.ui.Message(ports.MessageInfo, "Usage: /session [save|clear]")
+	}
+}
+
+// save persists the transcript lines added since the last save.
+func (h *ChatHandler) save(ctx context.Context) {
+	if h.Sess == nil || h.saved >= len(h.transcript) {
+		return
+	}
+	if err := h.Sess.UpdateBoth(ctx, h.SessionName, h.transcript[h.saved:]); err != nil {
+		h.ui.Message(ports.MessageWarning, fmt.Sprintf("Failed to save session: %v", err))
+		return
+	}
+	h.saved = len(h.transcript)
+	h.ui.Message(ports.MessageInfo, fmt.Sprintf("[VIBE] Session '%s' saved.", h.SessionName))
+}
+
+func (h *ChatHandler) undo() {
+	workDir, _ := os.Getwd()
+	if !vibegit.IsGitRepo(workDir) {
+		h.ui.Message(ports.MessageInfo, "Not a git repository")
+		return
+	}
+	if !h.ui.Confirm("Restore the last checkpoint? Uncommitted changes since then are lost.") {
+		h.ui.Message(ports.MessageInfo, "Cancelled.")
+		return
+	}
+	if err := vibegit.UndoLastCheckpoint(workDir); err != nil {
+		h.ui.Message(ports.MessageError, err.Error())
+		return
+	}
+	h.ui.Message(ports.MessageInfo, "Done! Workspace restored to the last checkpoint.")
+	h.note("NOTE: the user restored the workspace to the last checkpoint; earlier changes are undone.")
+}
+
+func (h *ChatHandler) diagnose(ctx context.Context) {
+	h.ui.Message(ports.MessageInfo, "Running system diagnostics...")
+	result, err := diagnose.NewService().Run(ctx)
+	if err != nil {
+		h.ui.Message(ports.MessageError, err.Error())
+		return
+	}
+	h.ui.Message(ports.MessageInfo, diagnose.FormatReport(result))
+	var b strings.Builder
+	b.WriteString("DIAGNOSTICS: " + result.Summary)
+	for _, issue := range append(result.Errors, result.Warnings...) {
+		fmt.Fprintf(&b, "\n- [%s] %s: %s", issue.Severity, issue.Category, issue.Description)
+	}
+	h.note(b.String())
+}
+
+// note adds lines for the agent to see on its next turn.
+func (h *ChatHandler) note(lines ...string) {
+	if h.task == "" {
+		h.pending = append(h.pending, lines...)
+		return
+	}
+	h.transcript = append(h.transcript, lines...)
+}
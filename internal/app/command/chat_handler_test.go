@@ -0,0 +1,172 @@
+package command
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/ui"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+func newTestChat(t *testing.T, provider *scriptedProvider, script *ui.Scripted, flags RunFlags) *ChatHandler {
+	t.Helper()
+	if flags.AgentMaxSteps == 0 {
+		flags.AgentMaxSteps = 3
+	}
+	h := NewChatHandler(testContext(provider), nil, script, nil, flags, "test")
+	if err := h.newAgent(); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// sentText joins the messages of a request as the model saw them.
+func sentText(req ports.GenerateRequest) string {
+	var b strings.Builder
+	b.WriteString(req.Prompt)
+	for _, m := range req.Messages {
+		b.WriteString("\n" + m.Content)
+	}
+	return b.String()
+}
+
+func TestChatSlashCommand(t *testing.T) {
+	cases := []struct {
+		line string
+		quit bool
+		want string
+	}{
+		{line: "/exit", quit: true},
+		{line: "/QUIT", quit: true},
+		{line: "/help", want: "/context @provider query"},
+		{line: "/bogus now", want: "Unknown command /bogus."},
+		{line: "/session nonsense", want: "Usage: /session [save|clear]"},
+		{line: "/context", want: "Usage: /context @provider query"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.line, func(t *testing.T) {
+			script := ui.NewScripted()
+			h := newTestChat(t, &scriptedProvider{}, script, RunFlags{})
+			if quit := h.slashCommand(context.Background(), tc.line); quit != tc.quit {
+				t.Errorf("slashCommand(%q) quit = %v, want %v", tc.line, quit, tc.quit)
+			}
+			if tc.want != "" && !strings.Contains(strings.Join(script.Lines, "\n"), tc.want) {
+				t.Errorf("expected %q in the output, got %q", tc.want, script.Lines)
+			}
+		})
+	}
+}
+
+func TestChat_ReplaysEarlierAnswers(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{
+		`{"type":"answer","explanation":"The disk is 91% full."}`,
+		`{"type":"answer","explanation":"Clean /var/log first."}`,
+	}}
+	h := newTestChat(t, provider, ui.NewScripted(), RunFlags{})
+
+	h.send(context.Background(), "how full is the disk?")
+	h.send(context.Background(), "what should I clean?")
+
+	if len(provider.requests) != 2 {
+		t.Fatalf("expected two turns, got %d requests", len(provider.requests))
+	}
+	if !strings.Contains(strings.Join(h.transcript, "\n"), `AGENT_ACTION: {"type":"answer","explanation":"The disk is 91% full."}`) {
+		t.Errorf("expected the first answer in the transcript:\n%s", strings.Join(h.transcript, "\n"))
+	}
+	second := sentText(provider.requests[1])
+	for _, want := range []string{`{"type":"answer","explanation":"The disk is 91% full."}`, "USER_MESSAGE: what should I clean?"} {
+		if !strings.Contains(second, want) {
+			t.Errorf("second turn is missing %q:\n%s", want, second)
+		}
+	}
+	if h.task != "how full is the disk?" {
+		t.Errorf("expected the first message as the task, got %q", h.task)
+	}
+}
+
+func TestChat_NotesBeforeFirstMessage(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{`{"type":"answer","explanation":"ok"}`}}
+	h := newTestChat(t, provider, ui.NewScripted(), RunFlags{})
+
+	h.note("DIAGNOSTICS: disk almost full")
+	if len(h.pending) != 1 || len(h.transcript) != 0 {
+		t.Fatalf("expected the note to wait for the first message, got pending %q, transcript %q", h.pending, h.transcript)
+	}
+
+	h.send(context.Background(), "fix it")
+	if len(h.pending) != 0 {
+		t.Errorf("expected pending notes to be used, got %q", h.pending)
+	}
+	if len(h.transcript) < 3 || h.transcript[0] != "USER_REQUEST: fix it" || h.transcript[2] != "DIAGNOSTICS: disk almost full" {
+		t.Errorf("expected the note after the request, got %q", h.transcript)
+	}
+
+	h.note("NOTE: later")
+	if h.transcript[len(h.transcript)-1] != "NOTE: later" {
+		t.Errorf("expected notes after the first message to go to the transcript, got %q", h.transcript)
+	}
+}
+
+func TestChat_SessionClearStartsOver(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{
+		`{"type":"answer","explanation":"first"}`,
+		`{"type":"answer","explanation":"second"}`,
+	}}
+	script := ui.NewScripted()
+	h := newTestChat(t, provider, script, RunFlags{})
+
+	h.send(context.Background(), "old task")
+	h.note("NOTE: stale")
+	h.slashCommand(context.Background(), "/session clear")
+	if h.task != "" || len(h.transcript) != 0 || len(h.pending) != 0 {
+		t.Fatalf("expected an empty conversation, got task %q, transcript %q", h.task, h.transcript)
+	}
+
+	h.send(context.Background(), "new task")
+	if h.task != "new task" || h.transcript[0] != "USER_REQUEST: new task" {
+		t.Errorf("expected the next message to start a new task, got %q", h.transcript)
+	}
+	if sent := sentText(provider.requests[1]); strings.Contains(sent, "old task") || strings.Contains(sent, "NOTE: stale") {
+		t.Errorf("the cleared conversation leaked into the next turn:\n%s", sent)
+	}
+}
+
+func TestChat_CapsSelfHealFollowUps(t *testing.T) {
+	skipOnWindows(t)
+	provider := &scriptedProvider{responses: []string{
+		`{"type":"done","explanation":"look","command":"echo one"}`,
+		`{"type":"done","explanation":"look again","command":"echo two"}`,
+		`{"type":"done","explanation":"and again","command":"echo three"}`,
+	}}
+	script := ui.NewScripted()
+	h := newTestChat(t, provider, script, RunFlags{SelfHeal: true, SelfHealMaxAttempts: 1})
+
+	h.send(context.Background(), "inspect")
+
+	if len(provider.requests) != 2 {
+		t.Errorf("expected one follow-up turn, got %d requests", len(provider.requests))
+	}
+	if script.Printed() != "one\ntwo\n" {
+		t.Errorf("unexpected command output %q", script.Printed())
+	}
+	if !strings.Contains(strings.Join(h.transcript, "\n"), "EXEC_COMMAND: echo two") {
+		t.Errorf("expected the follow-up command in the transcript:\n%s", strings.Join(h.transcript, "\n"))
+	}
+}
+
+func TestChat_StopsWhenCommandDeclined(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{
+		`{"type":"done","explanation":"make a file","command":"touch notes.txt"}`,
+	}}
+	h := newTestChat(t, provider, ui.NewScripted("n"), RunFlags{SelfHeal: true, SelfHealMaxAttempts: 3})
+
+	h.send(context.Background(), "make notes")
+
+	if len(provider.requests) != 1 {
+		t.Errorf("a declined command must end the turn, got %d requests", len(provider.requests))
+	}
+	if last := h.transcript[len(h.transcript)-1]; last != "EXEC_SKIPPED: the user declined to run: touch notes.txt" {
+		t.Errorf("expected the refusal in the transcript, got %q", last)
+	}
+}
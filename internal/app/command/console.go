@@ -0,0 +1,105 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/tools/definitions"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/agent"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/approval"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// agentConsole connects the agent's callbacks to the UI.
+type agentConsole struct {
+	ui ports.UI
+	// policy answers confirmations in non-interactive runs; empty means ask.
+	policy approval.Policy
+	// detached is set when the UI is not this process's terminal.
+	detached bool
+}
+
+func (h *RunHandler) console() *agentConsole {
+	return &agentConsole{ui: h.UI, policy: h.Flags.Policy, detached: h.Flags.Detached}
+}
+
+func (c *agentConsole) progress(step agent.StepInfo) {
+	switch step.Type {
+	case "thinking":
+		c.ui.Progress("Thinking...")
+	case "tool_call":
+		c.ui.Message(ports.MessageInfo, "[VIBE] "+step.Message)
+	case "tool_done":
+		// Keep quiet to let next action overwrite
+	case "context_trimmed":
+		c.ui.Message(ports.MessageInfo, "[VIBE] Context window full, "+step.Message)
+	case "repair":
+		c.ui.Message(ports.MessageInfo, "[VIBE] "+step.Message)
+	case "tool_refused":
+		c.ui.Message(ports.MessageInfo, "[VIBE] Tool refused: "+step.Message)
+	}
+}
+
+// confirm asks the user to approve one tool call, or applies the policy.
+func (c *agentConsole) confirm(call agent.ToolConfirmation) bool {
+	if c.policy != "" {
+		decision := c.evaluate(call)
+		if !decision.Allowed {
+			c.ui.Message(ports.MessageInfo, fmt.Sprintf("[VIBE] Not allowing %s: %s", call.Tool.Name, decision.Reason))
+		}
+		return decision.Allowed
+	}
+	c.ui.Message(ports.MessageInfo, fmt.Sprintf("\n[VIBE] Agent would like to %s:\n   \033[1;33m%s\033[0m", call.Tool.WouldLikeTo, call.Summary))
+	return c.ui.Confirm("   Allow this one-time execution?")
+}
+
+// evaluate applies the policy to a tool call. Only the shell's input is a
+// command; other tools are judged by whether they are read-only.
+func (c *agentConsole) evaluate(call agent.ToolConfirmation) approval.Decision {
+	if call.Tool.Name != definitions.SafeShell.Name {
+		return c.policy.EvaluateTool(call.Tool.Name, call.Tool.ReadOnly)
+	}
+	var input struct {
+		Command string `json:"command"`
+	}
+	if err := json.Unmarshal(call.Input, &input); err != nil || strings.TrimSpace(input.Command) == "" {
+		return approval.Decision{Reason: "no command to check"}
+	}
+	return c.policy.Evaluate(input.Command)
+}
+
+// asker returns the callback for clarifying questions. They need someone to
+// answer, so piped and non-interactive runs get none and fail instead.
+func (c *agentConsole) asker() func(agent.Question) (string, error) {
+	if c.policy != "" || (!c.detached && !stdinIsTerminal()) {
+		return nil
+	}
+	return c.ask
+}
+
+// ask shows a clarifying question from the agent and reads the reply.
+// With choices, the reply may be a choice number.
+func (c *agentConsole) ask(q agent.Question) (string, error) {
+	text := "\n[VIBE] ❓ " + q.Text
+	for i, choice := range q.Choices {
+		text += fmt.Sprintf("\n   [%d] %s", i+1, choice)
+	}
+	c.ui.Message(ports.MessageInfo, text)
+	in, err := c.ui.Input("   > ")
+	if err != nil {
+		return "", err
+	}
+	if n, err := strconv.Atoi(in); err == nil && n >= 1 && n <= len(q.Choices) {
+		return q.Choices[n-1], nil
+	}
+	return in, nil
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal.
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,50 @@
+package command
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/tools/definitions"
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/ui"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/agent"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/approval"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+func TestAgentConsole_ConfirmAppliesPolicy(t *testing.T) {
+	shell := func(cmd string) agent.ToolConfirmation {
+		input, _ := json.Marshal(map[string]string{"command": cmd})
+		return agent.ToolConfirmation{Tool: definitions.SafeShell, Input: input, Summary: cmd}
+	}
+	// The summary of a one-field input looks like a command, but it is not one.
+	mcpTool := func(readOnly bool) agent.ToolConfirmation {
+		return agent.ToolConfirmation{
+			Tool:    ports.ToolDefinition{Name: "ops__restart", ReadOnly: readOnly},
+			Input:   json.RawMessage(`{"service":"ls"}`),
+			Summary: "ls",
+		}
+	}
+	cases := []struct {
+		name   string
+		policy approval.Policy
+		call   agent.ToolConfirmation
+		want   bool
+	}{
+		{"readonly shell read", approval.ReadOnly, shell("ls -la"), true},
+		{"readonly shell write", approval.ReadOnly, shell("touch x"), false},
+		{"auto shell write", approval.Auto, shell("touch x"), true},
+		{"readonly tool with effects", approval.ReadOnly, mcpTool(false), false},
+		{"auto tool with effects", approval.Auto, mcpTool(false), false},
+		{"auto read-only tool", approval.Auto, mcpTool(true), true},
+		{"strict read-only tool", approval.Strict, mcpTool(true), false},
+		{"shell without command", approval.Auto, agent.ToolConfirmation{Tool: definitions.SafeShell, Input: json.RawMessage(`{}`)}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &agentConsole{ui: ui.NewScripted(), policy: tc.policy}
+			if got := c.confirm(tc.call); got != tc.want {
+				t.Errorf("confirm = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
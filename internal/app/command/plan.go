@@ -0,0 +1,221 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/agent"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// planOutcome is how a plan run ended.
+type planOutcome int
+
+const (
+	planCompleted planOutcome = iota
+	planCancelled
+	planStopped
+	// planReplan asks the agent for a new plan from the current state.
+	planReplan
+)
+
+// planMode is how the user approved a plan.
+type planMode int
+
+const (
+	planApproveAll planMode = iota
+	planStepByStep
+)
+
+// executePlan shows the plan, lets the user approve all of it, approve it step by step,
+// or edit it, then runs the steps in order through the safety checks. On the first
+// failure the user stops (optionally rolling back completed steps) or asks for a new plan.
+// The returned transcript records every step that ran. Non-interactive runs
+// check each step against the approval policy and stop on the first failure.
+func (h *RunHandler) executePlan(ctx context.Context, plan []agent.PlanStep, transcript []string, originalRequest string) (planOutcome, []string, error) {
+	if len(transcript) == 0 {
+		transcript = []string{"USER_REQUEST: " + originalRequest, "GOOS: " + strings.TrimSpace(runtime.GOOS)}
+	}
+
+	var mode planMode
+	for h.interactive() {
+		h.printPlan(plan)
+		choice := h.UI.Choose("Run this plan?", []ports.Choice{
+			{Key: "a", Label: "approve all"},
+			{Key: "s", Label: "step by step"},
+			{Key: "e", Label: "edit"},
+			{Key: "c", Label: "cancel"},
+		})
+		switch choice {
+		case "a":
+			mode = planApproveAll
+		case "s":
+			mode = planStepByStep
+		case "e":
+			plan = h.editPlan(plan)
+			if len(plan) == 0 {
+				h.UI.Message(ports.MessageError, "Plan is empty, nothing to run.")
+				return planCancelled, transcript, nil
+			}
+			continue
+		default:
+			h.UI.Message(ports.MessageError, "Plan cancelled.")
+			return planCancelled, transcript, nil
+		}
+		break
+	}
+	if !h.interactive() {
+		h.printPlan(plan)
+	}
+
+	if data, err := json.Marshal(plan); err == nil {
+		transcript = append(transcript, "PLAN: "+string(data))
+	}
+
+	exec := h.newExecutor()
+	var done []agent.PlanStep
+	for i, step := range plan {
+		label := fmt.Sprintf("Step %d/%d", i+1, len(plan))
+		proceed, asked := h.gateStep(step.Command)
+		if !proceed {
+			h.UI.Message(ports.MessageError, label+" cancelled; stopping the plan.")
+			transcript = append(transcript, fmt.Sprintf("PLAN_STOPPED: %s was cancelled by the user: %s", label, step.Command))
+			return planStopped, transcript, nil
+		}
+		if mode == planStepByStep && !asked {
+			h.UI.Command(label, step.Command)
+			switch h.UI.Choose("Run it?", []ports.Choice{{Key: "y", Label: "yes"}, {Key: "s", Label: "skip"}, {Key: "q", Label: "quit"}}) {
+			case "y":
+			case "s":
+				h.UI.Message(ports.MessageInfo, fmt.Sprintf("⏭️  %s skipped.", label))
+				transcript = append(transcript, fmt.Sprintf("PLAN_STEP_SKIPPED: %s %s", label, step.Command))
+				continue
+			default:
+				h.UI.Message(ports.MessageError, "Plan stopped.")
+				transcript = append(transcript, fmt.Sprintf("PLAN_STOPPED: the user stopped the plan before %s", label))
+				return planStopped, transcript, nil
+			}
+		}
+
+		h.UI.Message(ports.MessageInfo, fmt.Sprintf("\n▶️  %s: %s", label, step.Command))
+		res, stdout, stderr, err := h.runStep(ctx, exec, step.Command)
+		h.recordExec(step.Command, res, stdout, stderr)
+		transcript = append(transcript,
+			"EXEC_COMMAND: "+step.Command,
+			fmt.Sprintf("EXEC_RESULT: exit_code=%d", res.ExitCode),
+			"EXEC_STDOUT_TAIL: "+tailString(stdout, 4000),
+			"EXEC_STDERR_TAIL: "+tailString(stderr, 4000),
+		)
+		if err == nil && res.ExitCode == 0 {
+			h.UI.Message(ports.MessageSuccess, label+" done.")
+			done = append(done, step)
+			continue
+		}
+
+		failure := fmt.Sprintf("\n%s failed (exit code %d).", label, res.ExitCode)
+		if step.Expected != "" {
+			failure += "\n   Expected: " + step.Expected
+		}
+		h.UI.Message(ports.MessageError, failure)
+		if !h.interactive() {
+			return planStopped, transcript, fmt.Errorf("plan stopped: %s failed with exit code %d", strings.ToLower(label), res.ExitCode)
+		}
+		switch h.UI.Choose("", []ports.Choice{
+			{Key: "r", Label: "re-plan from here"},
+			{Key: "b", Label: "roll back completed steps and stop"},
+			{Key: "s", Label: "stop"},
+		}) {
+		case "r":
+			transcript = append(transcript, fmt.Sprintf("INSTRUCTION: %s of the plan failed; the steps before it succeeded. "+
+				"Starting from the current state, propose a new plan (type=plan) or command (type=done), or explain the problem (type=answer).", label))
+			return planReplan, transcript, nil
+		case "b":
+			transcript = h.rollback(ctx, exec, done, transcript)
+		}
+		return planStopped, transcript, fmt.Errorf("plan stopped: %s failed with exit code %d", strings.ToLower(label), res.ExitCode)
+	}
+
+	h.UI.Message(ports.MessageSuccess, fmt.Sprintf("\nPlan completed (%d steps).", len(plan)))
+	return planCompleted, transcript, nil
+}
+
+// gateStep runs the safety check on a plan step, or the approval policy when
+// nobody is at the terminal.
+func (h *RunHandler) gateStep(cmd string) (proceed, asked bool) {
+	if !h.interactive() {
+		return h.policyAllows(cmd), true
+	}
+	return safetyGate(h.UI, cmd)
+}
+
+// printPlan shows every step with its purpose, expected outcome and rollback.
+func (h *RunHandler) printPlan(plan []agent.PlanStep) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n[VIBE] Proposed plan (%d steps):\n", len(plan))
+	for i, step := range plan {
+		fmt.Fprintf(&b, "\n  %d. %s\n", i+1, step.Purpose)
+		fmt.Fprintf(&b, "     \033[1;36m%s\033[0m\n", step.Command)
+		if step.Expected != "" {
+			fmt.Fprintf(&b, "     expect:   %s\n", step.Expected)
+		}
+		if step.Rollback != "" {
+			fmt.Fprintf(&b, "     rollback: %s\n", step.Rollback)
+		}
+	}
+	h.UI.Message(ports.MessageInfo, b.String())
+}
+
+// editPlan lets the user replace or drop each step's command.
+func (h *RunHandler) editPlan(plan []agent.PlanStep) []agent.PlanStep {
+	h.UI.Message(ports.MessageInfo, "Edit each step: Enter keeps the command, '-' drops the step, anything else replaces it.")
+	var edited []agent.PlanStep
+	for i, step := range plan {
+		in, _ := h.UI.Input(fmt.Sprintf("  %d. [%s]: ", i+1, step.Command))
+		switch in {
+		case "":
+		case "-":
+			continue
+		default:
+			step.Command = in
+		}
+		edited = append(edited, step)
+	}
+	return edited
+}
+
+// rollback runs the rollback commands of completed steps, newest first.
+func (h *RunHandler) rollback(ctx context.Context, exec ports.Executor, done []agent.PlanStep, transcript []string) []string {
+	for i := len(done) - 1; i >= 0; i-- {
+		cmd := strings.TrimSpace(done[i].Rollback)
+		if cmd == "" {
+			h.UI.Message(ports.MessageWarning, "No rollback for: "+done[i].Command)
+			continue
+		}
+		if proceed, _ := safetyGate(h.UI, cmd); !proceed {
+			h.UI.Message(ports.MessageInfo, "⏭️  Rollback skipped: "+cmd)
+			continue
+		}
+		h.UI.Message(ports.MessageInfo, "\n↩️  Rolling back: "+cmd)
+		res, _, _, err := h.runStep(ctx, exec, cmd)
+		transcript = append(transcript, "EXEC_COMMAND: "+cmd, fmt.Sprintf("EXEC_RESULT: exit_code=%d", res.ExitCode))
+		if err != nil || res.ExitCode != 0 {
+			h.UI.Message(ports.MessageError, fmt.Sprintf("Rollback failed (exit code %d).", res.ExitCode))
+		}
+	}
+	return transcript
+}
+
+// runStep runs cmd, showing its output while keeping a copy for the agent.
+func (h *RunHandler) runStep(ctx context.Context, exec ports.Executor, cmd string) (ports.ExecResult, string, string, error) {
+	var stdout, stderr strings.Builder
+	res, err := exec.Run(ctx, ports.ExecSpec{
+		Command: cmd,
+		Stdout:  io.MultiWriter(h.UI.Output(), &stdout),
+		Stderr:  io.MultiWriter(h.UI.Output(), &stderr),
+	})
+	return res, stdout.String(), stderr.String(), err
+}
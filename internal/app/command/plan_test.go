@@ -0,0 +1,106 @@
+package command
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/ui"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/agent"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/approval"
+)
+
+func skipOnWindows(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("plan steps use POSIX shell commands")
+	}
+}
+
+func TestExecutePlan_StopsAfterFailedStep(t *testing.T) {
+	skipOnWindows(t)
+	script := ui.NewScripted("a", "s")
+	h := &RunHandler{UI: script}
+
+	plan := []agent.PlanStep{
+		{Command: "echo first"},
+		{Command: "false", Expected: "never succeeds"},
+		{Command: "echo never"},
+	}
+	outcome, transcript, err := h.executePlan(context.Background(), plan, nil, "do it")
+
+	if outcome != planStopped || err == nil {
+		t.Fatalf("expected the plan to stop with an error, got %v, %v", outcome, err)
+	}
+	joined := strings.Join(transcript, "\n")
+	for _, want := range []string{"EXEC_COMMAND: echo first", "EXEC_COMMAND: false", "EXEC_RESULT: exit_code=1"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("transcript is missing %q:\n%s", want, joined)
+		}
+	}
+	if strings.Contains(joined, "EXEC_COMMAND: echo never") {
+		t.Error("steps after the failure must not run")
+	}
+	if script.Printed() != "first\n" {
+		t.Errorf("unexpected command output %q", script.Printed())
+	}
+	if script.Remaining() != 0 {
+		t.Errorf("expected both answers to be used, %d left", script.Remaining())
+	}
+}
+
+func TestExecutePlan_RollsBackCompletedSteps(t *testing.T) {
+	skipOnWindows(t)
+	script := ui.NewScripted("a", "b")
+	h := &RunHandler{UI: script}
+
+	plan := []agent.PlanStep{
+		{Command: "echo create", Rollback: "echo remove"},
+		{Command: "false"},
+	}
+	_, transcript, _ := h.executePlan(context.Background(), plan, nil, "do it")
+
+	if !strings.Contains(strings.Join(transcript, "\n"), "echo remove") {
+		t.Errorf("expected the rollback of step 1 in the transcript:\n%s", strings.Join(transcript, "\n"))
+	}
+	if script.Printed() != "create\nremove\n" {
+		t.Errorf("unexpected command output %q", script.Printed())
+	}
+}
+
+func TestExecutePlan_CancelRunsNothing(t *testing.T) {
+	script := ui.NewScripted("c")
+	h := &RunHandler{UI: script}
+
+	outcome, _, err := h.executePlan(context.Background(), []agent.PlanStep{{Command: "echo hi"}}, nil, "do it")
+	if outcome != planCancelled || err != nil {
+		t.Fatalf("expected a cancelled plan, got %v, %v", outcome, err)
+	}
+	if script.Printed() != "" {
+		t.Errorf("nothing should run, got %q", script.Printed())
+	}
+}
+
+func TestApproveCommand(t *testing.T) {
+	script := ui.NewScripted("n")
+	h := &RunHandler{UI: script}
+
+	if !h.approveCommand("ls -la") {
+		t.Error("safe read-only commands run without asking")
+	}
+	if h.approveCommand("touch notes.txt") {
+		t.Error("expected the scripted no to refuse the command")
+	}
+	if !strings.Contains(strings.Join(script.Lines, "\n"), "confirm: ") {
+		t.Errorf("expected a confirmation prompt, got %q", script.Lines)
+	}
+
+	h = &RunHandler{UI: ui.NewScripted(), Flags: RunFlags{Policy: approval.Strict}}
+	if h.approveCommand("ls -la") {
+		t.Error("the strict policy refuses every command")
+	}
+	if h.result.Status != StatusRefused {
+		t.Errorf("expected status %q, got %q", StatusRefused, h.result.Status)
+	}
+}
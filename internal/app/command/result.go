@@ -0,0 +1,116 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/agent"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// Run statuses reported in RunResult.
+const (
+	StatusAnswered  = "answered"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusRefused   = "refused"
+	StatusError     = "error"
+)
+
+// RunResult is the outcome of a run, printed with --output json.
+type RunResult struct {
+	Status      string           `json:"status"`
+	Request     string           `json:"request"`
+	Command     string           `json:"command,omitempty"`
+	Plan        []agent.PlanStep `json:"plan,omitempty"`
+	Explanation string           `json:"explanation,omitempty"`
+	// ExitCode is the exit code of the last command that ran; nil when none ran.
+	ExitCode   *int   `json:"exit_code,omitempty"`
+	StdoutTail string `json:"stdout_tail,omitempty"`
+	StderrTail string `json:"stderr_tail,omitempty"`
+	StepsUsed  int    `json:"steps_used"`
+	// Reason explains why a command was refused.
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// recordResponse keeps the agent's latest proposal in the result.
+func (h *RunHandler) recordResponse(resp agent.SuggestResponse) {
+	h.result.StepsUsed += resp.StepsUsed
+	if resp.Explanation != "" {
+		h.result.Explanation = resp.Explanation
+	}
+	if resp.Command != "" {
+		h.result.Command = resp.Command
+	}
+	if len(resp.Plan) > 0 {
+		h.result.Plan = resp.Plan
+	}
+}
+
+// recordExec keeps the outcome of the last command that ran.
+func (h *RunHandler) recordExec(cmd string, res ports.ExecResult, stdout, stderr string) {
+	exitCode := res.ExitCode
+	h.result.Command = cmd
+	h.result.ExitCode = &exitCode
+	h.result.StdoutTail = tailString(stdout, 4000)
+	h.result.StderrTail = tailString(stderr, 4000)
+	h.result.Status, h.result.Reason = "", ""
+}
+
+// Result returns the outcome of the last Handle call, given the error it
+// returned. The status is derived from what happened.
+func (h *RunHandler) Result(runErr error) RunResult {
+	r := h.result
+	switch {
+	case r.Status != "":
+	case runErr != nil && r.ExitCode == nil:
+		r.Status = StatusError
+	case r.ExitCode != nil && *r.ExitCode == 0:
+		r.Status = StatusSucceeded
+	case r.ExitCode != nil:
+		r.Status = StatusFailed
+	default:
+		r.Status = StatusAnswered
+	}
+	if runErr != nil {
+		r.Error = runErr.Error()
+	}
+	return r
+}
+
+// writeResult prints the result as JSON.
+func (h *RunHandler) writeResult(out io.Writer, runErr error) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(h.Result(runErr)); err != nil {
+		return fmt.Errorf("failed to write result: %w", err)
+	}
+	return nil
+}
+
+// outcome describes how cmd ended for the UI.
+func outcome(cmd string, res ports.ExecResult, err error, stderr string) ports.CommandOutcome {
+	exitCode := res.ExitCode
+	if err != nil && exitCode == 0 {
+		exitCode = -1
+	}
+	return ports.CommandOutcome{Command: cmd, ExitCode: exitCode, Stderr: stderr}
+}
+
+// noStdinExecutor runs commands with empty stdin, so a non-interactive run
+// never waits for input.
+type noStdinExecutor struct {
+	ports.Executor
+}
+
+func (e noStdinExecutor) Run(ctx context.Context, spec ports.ExecSpec) (ports.ExecResult, error) {
+	if spec.Stdin == nil {
+		spec.Stdin = strings.NewReader("")
+	}
+	return e.Executor.Run(ctx, spec)
+}
@@ -1,12 +1,14 @@
 package command
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
 	"strings"
+	"time"
 
 	ctxregistry "github.com/phamdaiminhquan/vibe-devops/internal/adapters/context"
 	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/context/file"
@@ -17,6 +19,7 @@ import (
 	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/tools/fs"
 	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/tools/system"
 	"github.com/phamdaiminhquan/vibe-devops/internal/app/agent"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/approval"
 	"github.com/phamdaiminhquan/vibe-devops/internal/app/bootstrap"
 	"github.com/phamdaiminhquan/vibe-devops/internal/app/dependency"
 	vibegit "github.com/phamdaiminhquan/vibe-devops/internal/app/git"
@@ -31,8 +34,21 @@ import (
 type RunFlags struct {
 	AgentMode           bool
 	AgentMaxSteps       int
+	AgentMaxRepairs     int
+	AgentToolWorkers    int
+	AgentToolTimeout    time.Duration
 	SelfHeal            bool
 	SelfHealMaxAttempts int
+	// Policy approves proposed commands without asking, for runs without a
+	// terminal. Empty means interactive.
+	Policy approval.Policy
+	// JSON prints a RunResult as JSON to Out when the run ends.
+	JSON bool
+	// SessionName is the session memory the run reads and updates; empty means "default".
+	SessionName string
+	// Detached runs answer prompts through the UI from somewhere other than
+	// this process's terminal, e.g. a 'vibe serve' client. Commands get no stdin.
+	Detached bool
 }
 
 // RunHandler encapsulates the logic for the 'run' command
@@ -41,27 +57,57 @@ type RunHandler struct {
 	Sess  *session.Service
 	Flags RunFlags
 	Dep   *dependency.Manager
+	// UI prompts the user and shows progress and results.
+	UI ports.UI
+	// Out receives the JSON result.
+	Out io.Writer
+
+	result RunResult
 }
 
 // NewRunHandler creates a new handler instance
-func NewRunHandler(ctx *bootstrap.ApplicationContext, sess *session.Service, flags RunFlags) *RunHandler {
+func NewRunHandler(ctx *bootstrap.ApplicationContext, sess *session.Service, ui ports.UI, flags RunFlags) *RunHandler {
 	return &RunHandler{
 		Ctx:   ctx,
 		Sess:  sess,
 		Flags: flags,
 		Dep:   dependency.NewManager(),
+		UI:    ui,
+		Out:   os.Stdout,
 	}
 }
 
 // Handle executes the run logic
 func (h *RunHandler) Handle(ctx context.Context, input string) error {
+	h.result = RunResult{Request: input}
+
 	// 0. Proactive Dependency Check
 	h.checkDependencies(ctx)
 
+	var err error
 	if h.Flags.AgentMode {
-		return h.runAgentMode(ctx, input)
+		err = h.runAgentMode(ctx, input)
+	} else {
+		err = h.runSingleShotMode(ctx, input)
+	}
+	if h.Flags.JSON {
+		if werr := h.writeResult(h.Out, err); werr != nil && err == nil {
+			err = werr
+		}
 	}
-	return h.runSingleShotMode(ctx, input)
+	return err
+}
+
+// interactive reports whether someone is at the terminal to answer prompts.
+func (h *RunHandler) interactive() bool {
+	return h.Flags.Policy == ""
+}
+
+func (h *RunHandler) sessionName() string {
+	if h.Flags.SessionName == "" {
+		return "default"
+	}
+	return h.Flags.SessionName
 }
 
 func (h *RunHandler) checkDependencies(ctx context.Context) {
@@ -78,7 +124,8 @@ func (h *RunHandler) checkDependencies(ctx context.Context) {
 		return
 	}
 
-	fmt.Println("\n⚠️  Dependency Check Warning:")
+	var b strings.Builder
+	b.WriteString("\n⚠️  Dependency Check Warning:\n")
 	for _, res := range results {
 		if res.Status == dependency.StatusInstalled {
 			continue
@@ -89,12 +136,12 @@ func (h *RunHandler) checkDependencies(ctx context.Context) {
 			icon = "⚠️"
 		}
 
-		fmt.Printf("  %s %s: Not found or error\n", icon, res.Dependency.Name)
+		fmt.Fprintf(&b, "  %s %s: Not found or error\n", icon, res.Dependency.Name)
 		if res.Dependency.InstallHint != "" {
-			fmt.Printf("      👉 Fix: %s\n", res.Dependency.InstallHint)
+			fmt.Fprintf(&b, "      👉 Fix: %s\n", res.Dependency.InstallHint)
 		}
 	}
-	fmt.Println()
+	h.UI.Message(ports.MessageInfo, b.String())
 	// Non-blocking for now, just warn.
 }
 
@@ -103,133 +150,83 @@ func (h *RunHandler) runAgentMode(ctx context.Context, input string) error {
 	locale.WarnIfVietnameseNotSupported(input)
 
 	// Auto-checkpoint: save uncommitted changes before AI session
-	workDir, _ := os.Getwd()
-	if vibegit.IsGitRepo(workDir) && vibegit.HasUncommittedChanges(workDir) {
-		fileCount := vibegit.CountUncommittedFiles(workDir)
-		fmt.Printf("[VIBE] Creating checkpoint... (%d uncommitted files)\n", fileCount)
-		if hash, err := vibegit.CreateCheckpoint(workDir); err == nil {
-			fmt.Printf("[VIBE] Checkpoint created: %s (use 'vibe undo' to restore)\n", hash)
-		}
-	}
-
-	// Track streaming state for UI
-	var isStreaming bool
+	createCheckpoint(h.UI)
 
-	onProgress := func(step agent.StepInfo) {
-		if isStreaming {
-			fmt.Println() // Newline after stream
-			isStreaming = false
-		}
-		switch step.Type {
-		case "thinking":
-			fmt.Printf("\r\033[K[VIBE] Thinking... ")
-		case "tool_call":
-			fmt.Printf("\r\033[K[VIBE] %s\n", step.Message)
-		case "tool_done":
-			// Keep quiet to let next action overwrite
-		}
-	}
-
-	// Smart streaming: receives only explanation text (thought is shown via onProgress)
-	onToken := func(token string) {
-		if !isStreaming {
-			fmt.Printf("\r\033[K") // Clear spinner
-			fmt.Print("[VIBE] ")   // Prefix for explanation
-			isStreaming = true
-		}
-		fmt.Print(token)
-	}
+	console := h.console()
 
 	var agentTranscript []string
 
 	// Seed transcript from session if available
 	if h.Sess != nil {
-		combined, err := h.Sess.LoadCombined(session.ScopeBoth, "default") // TODO: Parameterize session name
+		combined, err := h.Sess.LoadCombined(session.ScopeBoth, h.sessionName())
 		if err == nil {
 			agentTranscript = h.Sess.BuildSeedTranscript(combined, input, runtime.GOOS)
 		}
 	}
 
-	// Tool confirmation callback
-	toolConfirm := func(cmd string) bool {
-		if isStreaming {
-			fmt.Println()
-			isStreaming = false
-		}
-		fmt.Printf("\n[VIBE] Agent wants to run command:\n")
-		fmt.Printf("   \033[1;33m%s\033[0m\n", cmd)
-		fmt.Print("   Allow this one-time execution? (y/N) ")
-		reader := bufio.NewReader(os.Stdin)
-		in, _ := reader.ReadString('\n')
-		return strings.ToLower(strings.TrimSpace(in)) == "y"
-	}
-
-	// Note: toolConfirm callback is now passed via ToolExtras during agent execution
-	_ = toolConfirm // Callback will be used in future when agent supports OnConfirm
-
-	tools := []ports.Tool{
-		fs.NewListDirTool("."),
-		fs.NewReadFileTool("."),
-		fs.NewGrepTool("."),
-		system.NewSafeShellTool(),
+	ag, err := h.newAgent(agentTools(h.Ctx.Tools), newContextRegistry())
+	if err != nil {
+		return err
 	}
 
-	// Create context provider registry for @mentions
-	contextRegistry := ctxregistry.NewRegistry()
-	contextRegistry.Register(file.NewProvider("."))
-	contextRegistry.Register(git.NewProvider("."))
-	contextRegistry.Register(logs.NewProvider("."))
-	contextRegistry.Register(ctxsystem.NewProvider())
-
-	ag := agent.NewService(h.Ctx.Provider, tools, h.Ctx.Logger, h.Flags.AgentMaxSteps).
-		WithContextRegistry(contextRegistry)
-
 	// Loop to allow extending steps
 	for {
 		resp, err := ag.SuggestCommand(ctx, agent.SuggestRequest{
 			UserRequest: input,
 			GOOS:        runtime.GOOS,
 			Transcript:  agentTranscript,
-			OnProgress:  onProgress,
-			OnToken:     onToken,
+			OnProgress:  console.progress,
+			OnToken:     h.UI.Token,
+			OnConfirm:   console.confirm,
+			OnAsk:       console.asker(),
 		})
 
-		fmt.Printf("\r\033[K") // Clear spinner
-
 		if err != nil {
 			// Check for max steps error
-			if strings.Contains(err.Error(), "agent exceeded max steps") {
-				fmt.Printf("\n[VIBE] Agent stopped after %d steps to avoid infinite loops.\n", h.Flags.AgentMaxSteps)
-				fmt.Printf("   Latest thought: It likely needs more time or is stuck.\n")
-				fmt.Print("   Do you want to give it 10 more steps? (y/N) ")
-
-				reader := bufio.NewReader(os.Stdin)
-				in, _ := reader.ReadString('\n')
-				if strings.ToLower(strings.TrimSpace(in)) == "y" {
-					fmt.Println("Extending session...")
+			if strings.Contains(err.Error(), "agent exceeded max steps") && h.interactive() {
+				h.UI.Message(ports.MessageInfo, fmt.Sprintf("\n[VIBE] Agent stopped after %d steps to avoid infinite loops.\n"+
+					"   Latest thought: It likely needs more time or is stuck.", h.Flags.AgentMaxSteps))
+				if h.UI.Confirm("   Do you want to give it 10 more steps?") {
+					h.UI.Message(ports.MessageInfo, "Extending session...")
 					agentTranscript = resp.Transcript // Resume from where we left off
 					continue
 				}
 			}
 
+			if errors.Is(err, ports.ErrBudgetExceeded) {
+				h.UI.Message(ports.MessageInfo, fmt.Sprintf("\n[VIBE] Stopping: %v", err))
+				return nil
+			}
+			if errors.Is(err, agent.ErrNeedsInput) {
+				h.UI.Message(ports.MessageInfo, "\n[VIBE] The agent needs more information, but input is not interactive.\n"+
+					"   Add the missing details to your request and run it again.")
+				return err
+			}
+
 			// Normal error handling
 			errMsg := err.Error()
 			if strings.Contains(errMsg, "API key not valid") || strings.Contains(errMsg, "API_KEY_INVALID") {
-				fmt.Println("\nError: Invalid AI Provider API Key.")
-				fmt.Println("👉 To fix this, run:")
-				fmt.Println("   vibe config api-key \"YOUR_API_KEY\"")
+				h.UI.Message(ports.MessageError, "\nError: Invalid AI Provider API Key.\n"+
+					"👉 To fix this, run:\n"+
+					"   vibe config api-key \"YOUR_API_KEY\"")
 				return fmt.Errorf("check your API key")
 			}
 			return fmt.Errorf("AI completion failed: %w", err)
 		}
 
-		// Only print explanation if NOT already streamed (isStreaming tracks if onToken was used)
-		if strings.TrimSpace(resp.Explanation) != "" && !isStreaming {
-			fmt.Printf("\r\033[K") // Clear spinner
-			fmt.Printf("\n[VIBE] %s\n", resp.Explanation)
-		} else if isStreaming {
-			fmt.Println() // Just add newline after stream
-			isStreaming = false
+		h.UI.Explain(resp.Explanation)
+		h.recordResponse(resp)
+
+		if len(resp.Plan) > 0 {
+			outcome, transcript, err := h.executePlan(ctx, resp.Plan, resp.Transcript, input)
+			if outcome == planReplan {
+				agentTranscript = transcript
+				continue
+			}
+			if h.Sess != nil && outcome != planCancelled {
+				_ = h.Sess.UpdateBoth(ctx, h.sessionName(), transcript)
+			}
+			return err
 		}
 
 		// Skip command execution if no command suggested (type=answer scenario)
@@ -241,67 +238,87 @@ func (h *RunHandler) runAgentMode(ctx context.Context, input string) error {
 	}
 }
 
+// newAgent builds the agent with the run flags, tool policies and token budget.
+// registry may be nil when @mentions are not resolved.
+func (h *RunHandler) newAgent(tools []ports.Tool, registry ports.ContextProviderRegistry) (*agent.Service, error) {
+	policies, err := h.Ctx.ToolPolicies()
+	if err != nil {
+		return nil, err
+	}
+	ag := agent.NewService(h.Ctx.ProviderFor(bootstrap.RoleAgent), tools, h.Ctx.Logger, h.Flags.AgentMaxSteps).
+		WithMaxRepairs(h.Flags.AgentMaxRepairs).
+		WithToolLimits(h.Flags.AgentToolWorkers, h.Flags.AgentToolTimeout).
+		WithToolPolicies(policies, h.Ctx.ToolPoliciesTrusted()).
+		WithTokenBudget(h.Ctx.TokenBudget(bootstrap.RoleAgent))
+	if registry != nil {
+		ag.WithContextRegistry(registry)
+	}
+	return ag, nil
+}
+
+// agentTools are the tools offered to the agent in interactive runs: the
+// built-in ones, then the external ones from MCP servers.
+func agentTools(external []ports.Tool) []ports.Tool {
+	tools := []ports.Tool{
+		fs.NewListDirTool("."),
+		fs.NewReadFileTool("."),
+		fs.NewGrepTool("."),
+		system.NewSafeShellTool(),
+	}
+	return append(tools, external...)
+}
+
+// newContextRegistry registers the context providers for @mentions.
+func newContextRegistry() *ctxregistry.Registry {
+	registry := ctxregistry.NewRegistry()
+	registry.Register(file.NewProvider("."))
+	registry.Register(git.NewProvider("."))
+	registry.Register(logs.NewProvider("."))
+	registry.Register(ctxsystem.NewProvider())
+	return registry
+}
+
+// createCheckpoint saves uncommitted changes so 'vibe undo' can restore them.
+func createCheckpoint(ui ports.UI) {
+	workDir, _ := os.Getwd()
+	if vibegit.IsGitRepo(workDir) && vibegit.HasUncommittedChanges(workDir) {
+		fileCount := vibegit.CountUncommittedFiles(workDir)
+		ui.Message(ports.MessageInfo, fmt.Sprintf("[VIBE] Creating checkpoint... (%d uncommitted files)", fileCount))
+		if hash, err := vibegit.CreateCheckpoint(workDir); err == nil {
+			ui.Message(ports.MessageInfo, fmt.Sprintf("[VIBE] Checkpoint created: %s (use 'vibe undo' to restore)", hash))
+		}
+	}
+}
+
 func (h *RunHandler) runSingleShotMode(ctx context.Context, input string) error {
-	runner := run.NewService(h.Ctx.Provider, h.Ctx.Logger)
-	fmt.Println("Calling AI to generate command...")
-	fmt.Println("Note: Vibe single-shot mode.")
+	runner := run.NewService(h.Ctx.ProviderFor(bootstrap.RoleAgent), h.Ctx.Logger)
+	h.UI.Message(ports.MessageInfo, "Calling AI to generate command...\nNote: Vibe single-shot mode.")
 
 	cmd, err := runner.SuggestCommand(ctx, run.SuggestRequest{UserRequest: input, GOOS: runtime.GOOS})
 	if err != nil {
 		return fmt.Errorf("AI completion failed: %w", err)
 	}
+	h.result.Command = cmd
 
 	return h.executeAndHeal(ctx, cmd, nil, input)
 }
 
 func (h *RunHandler) executeAndHeal(ctx context.Context, cmd string, transcript []string, originalRequest string) error {
-	// Safety check for dangerous commands
-	safetyResult := safety.CheckCommand(cmd)
-	if safetyResult.Level >= safety.Warning {
-		action, doBackup := safety.PromptBackupChoice(cmd, safetyResult)
-		if action == "cancel" {
-			fmt.Println("❌ Command cancelled.")
-			return nil
-		}
-		if doBackup {
-			paths := safety.ExtractPaths(cmd)
-			if len(paths) > 0 {
-				fmt.Println("Creating backup...")
-				if backupPath, err := safety.CreateBackup(cmd, paths); err == nil {
-					fmt.Printf("✅ Backup created: %s\n", backupPath)
-				} else {
-					fmt.Printf("⚠️ Backup failed: %v\n", err)
-				}
-			}
-		}
-	} else if isSafeCommand(cmd) {
-		// Auto-Confirmation for safe read-only commands
-		fmt.Printf("\n✅ Vibe auto-executing safe command:\n  \033[1;36m%s\033[0m\n", cmd)
-	} else {
-		// Ask for confirmation for others
-		if !h.askConfirmation(cmd) {
-			return nil
-		}
+	if !h.approveCommand(cmd) {
+		return nil
 	}
 
 	// Initial Execution
-	fmt.Println("Executing command...")
-	exec := local.NewForOS(runtime.GOOS)
+	h.UI.Message(ports.MessageInfo, "Executing command...")
+	exec := h.newExecutor()
 	var stdoutBuf, stderrBuf strings.Builder
 	spec := ports.ExecSpec{Command: cmd, Stdout: &stdoutBuf, Stderr: &stderrBuf}
 
 	res, err := exec.Run(ctx, spec)
+	h.recordExec(cmd, res, stdoutBuf.String(), stderrBuf.String())
 
 	// Output Feedback
-	if err == nil && res.ExitCode == 0 {
-		fmt.Println("\nCommand executed successfully.")
-	} else {
-		fmt.Printf("\nCommand failed (exit code %d).\n", res.ExitCode)
-		if stderrBuf.Len() > 0 {
-			fmt.Println("\n--- stderr ---")
-			fmt.Println(stderrBuf.String())
-		}
-	}
+	h.UI.Result(outcome(cmd, res, err, stderrBuf.String()))
 
 	// Always send output to AI for analysis when in agent mode with self-heal
 	// This ensures user gets AI interpretation of results, not just "success"
@@ -310,7 +327,7 @@ func (h *RunHandler) executeAndHeal(ctx context.Context, cmd string, transcript
 	if !shouldSelfHeal {
 		// Persist simple run
 		if h.Sess != nil && len(transcript) > 0 {
-			_ = h.Sess.UpdateBoth(ctx, "default", transcript)
+			_ = h.Sess.UpdateBoth(ctx, h.sessionName(), transcript)
 		}
 		if res.ExitCode != 0 {
 			return fmt.Errorf("command failed with exit code %d", res.ExitCode)
@@ -341,22 +358,44 @@ func (h *RunHandler) executeAndHeal(ctx context.Context, cmd string, transcript
 		fs.NewReadFileTool("."),
 		fs.NewGrepTool("."),
 	}
-	ag := agent.NewService(h.Ctx.Provider, tools, h.Ctx.Logger, h.Flags.AgentMaxSteps)
+	ag, err := h.newAgent(tools, nil)
+	if err != nil {
+		return err
+	}
+	console := h.console()
 
+	// healErr is the error of a plan the agent proposed instead of a command.
+	var healErr error
 	for i := 0; i < attempts; i++ {
 		resp, err := ag.SuggestCommand(ctx, agent.SuggestRequest{
 			UserRequest: originalRequest,
 			GOOS:        runtime.GOOS,
 			Transcript:  transcript,
+			OnConfirm:   console.confirm,
+			OnAsk:       console.asker(),
 		})
+		if errors.Is(err, ports.ErrBudgetExceeded) {
+			h.UI.Message(ports.MessageInfo, fmt.Sprintf("\n[VIBE] Stopping: %v", err))
+			break
+		}
 		if err != nil {
 			return fmt.Errorf("AI completion failed (self-heal): %w", err)
 		}
 		transcript = resp.Transcript
+		h.recordResponse(resp)
 
 		if strings.TrimSpace(resp.Explanation) != "" {
-			fmt.Println("\n🧠 Agent analysis:")
-			fmt.Println(resp.Explanation)
+			h.UI.Message(ports.MessageInfo, "\n🧠 Agent analysis:\n"+resp.Explanation)
+		}
+
+		if len(resp.Plan) > 0 {
+			var outcome planOutcome
+			outcome, transcript, err = h.executePlan(ctx, resp.Plan, transcript, originalRequest)
+			if outcome == planReplan {
+				continue
+			}
+			healErr = err
+			break
 		}
 
 		if strings.TrimSpace(resp.Command) == "" {
@@ -369,18 +408,15 @@ func (h *RunHandler) executeAndHeal(ctx context.Context, cmd string, transcript
 		}
 
 		// Execute again
-		fmt.Println("Executing command...")
+		h.UI.Message(ports.MessageInfo, "Executing command...")
 		stdoutBuf.Reset()
 		stderrBuf.Reset()
 		spec := ports.ExecSpec{Command: resp.Command, Stdout: &stdoutBuf, Stderr: &stderrBuf}
 		res, err = exec.Run(ctx, spec)
+		h.recordExec(resp.Command, res, stdoutBuf.String(), stderrBuf.String())
+		h.UI.Result(outcome(resp.Command, res, err, stderrBuf.String()))
 
 		if err != nil || res.ExitCode != 0 {
-			fmt.Printf("\n❌ Command failed (exit code %d).\n", res.ExitCode)
-			if stderrBuf.Len() > 0 {
-				fmt.Println("\n--- stderr ---")
-				fmt.Println(stderrBuf.String())
-			}
 			transcript = append(transcript,
 				"EXEC_COMMAND: "+resp.Command,
 				fmt.Sprintf("EXEC_RESULT: exit_code=%d", res.ExitCode),
@@ -390,7 +426,6 @@ func (h *RunHandler) executeAndHeal(ctx context.Context, cmd string, transcript
 			continue
 		}
 
-		fmt.Println("\n✅ Command executed successfully.")
 		transcript = append(transcript,
 			"EXEC_COMMAND: "+resp.Command,
 			"EXEC_RESULT: exit_code=0",
@@ -402,21 +437,91 @@ func (h *RunHandler) executeAndHeal(ctx context.Context, cmd string, transcript
 
 	// Final Persist using session scope (simplification: assume 'default' name)
 	if h.Sess != nil {
-		_ = h.Sess.UpdateBoth(ctx, "default", transcript)
+		_ = h.Sess.UpdateBoth(ctx, h.sessionName(), transcript)
 	}
 
-	return nil
+	return healErr
+}
+
+// safetyGate runs the safety check on cmd. For risky commands the user picks
+// whether to cancel, run, or back up first; asked reports that they were prompted.
+func safetyGate(ui ports.UI, cmd string) (proceed, asked bool) {
+	result := safety.CheckCommand(cmd)
+	if result.Level < safety.Warning {
+		return true, false
+	}
+	action, doBackup := safety.PromptBackupChoice(ui, cmd, result)
+	if action == "cancel" {
+		return false, true
+	}
+	if doBackup {
+		paths := safety.ExtractPaths(cmd)
+		if len(paths) > 0 {
+			ui.Message(ports.MessageInfo, "Creating backup...")
+			if backupPath, err := safety.CreateBackup(cmd, paths); err == nil {
+				ui.Message(ports.MessageSuccess, "Backup created: "+backupPath)
+			} else {
+				ui.Message(ports.MessageWarning, fmt.Sprintf("Backup failed: %v", err))
+			}
+		}
+	}
+	return true, true
+}
+
+// approveCommand runs the safety check on cmd and asks for confirmation unless
+// the user was already asked or the command is read-only.
+func (h *RunHandler) approveCommand(cmd string) bool {
+	if !h.interactive() {
+		return h.policyAllows(cmd)
+	}
+	// Safety check for dangerous commands
+	proceed, asked := safetyGate(h.UI, cmd)
+	if !proceed {
+		h.UI.Message(ports.MessageError, "Command cancelled.")
+		return false
+	}
+	if asked {
+		return true
+	}
+	if isSafeCommand(cmd) {
+		// Auto-Confirmation for safe read-only commands
+		h.UI.Command("✅ Vibe auto-executing safe command", cmd)
+		return true
+	}
+	// Ask for confirmation for others
+	return h.askConfirmation(cmd)
+}
+
+// policyAllows applies the approval policy of a non-interactive run to cmd.
+func (h *RunHandler) policyAllows(cmd string) bool {
+	decision := h.Flags.Policy.Evaluate(cmd)
+	if !decision.Allowed {
+		h.UI.Command("[VIBE] Not running command ("+decision.Reason+")", cmd)
+		h.result.Command, h.result.Status, h.result.Reason = cmd, StatusRefused, decision.Reason
+		return false
+	}
+	h.UI.Command(fmt.Sprintf("✅ Policy %s allows command", h.Flags.Policy), cmd)
+	return true
+}
+
+// newExecutor returns the executor for proposed commands. Non-interactive runs
+// give commands no stdin.
+func (h *RunHandler) newExecutor() ports.Executor {
+	exec := local.NewForOS(runtime.GOOS)
+	if h.interactive() && !h.Flags.Detached {
+		return exec
+	}
+	return noStdinExecutor{exec}
 }
 
 func (h *RunHandler) askConfirmation(cmd string) bool {
-	fmt.Printf("\nVibe want to run command:")
-	fmt.Printf("  \033[1;36m%s\033[0m\n\n", cmd)
-	fmt.Print("Do you want to execute it? (y/N) ")
-
-	reader := bufio.NewReader(os.Stdin)
-	in, _ := reader.ReadString('\n')
-	if strings.ToLower(strings.TrimSpace(in)) != "y" {
-		fmt.Println("Execution cancelled.")
+	if !h.interactive() {
+		return h.policyAllows(cmd)
+	}
+	h.UI.Command("Vibe want to run command", cmd)
+	if !h.UI.Confirm("\nDo you want to execute it?") {
+		h.UI.Message(ports.MessageInfo, "Execution cancelled.")
+		h.result.Command, h.result.Status, h.result.Reason = cmd, StatusRefused, "declined by the user"
 		return false
 	}
 	return true
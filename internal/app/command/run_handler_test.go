@@ -0,0 +1,86 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/ui"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/bootstrap"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+	"github.com/phamdaiminhquan/vibe-devops/pkg/config"
+)
+
+// scriptedProvider returns canned responses in order and records every request.
+type scriptedProvider struct {
+	responses []string
+	requests  []ports.GenerateRequest
+}
+
+func (p *scriptedProvider) Name() string                           { return "scripted" }
+func (p *scriptedProvider) IsConfigured(ctx context.Context) error { return nil }
+func (p *scriptedProvider) Close() error                           { return nil }
+func (p *scriptedProvider) SupportsToolCalling() bool              { return false }
+
+func (p *scriptedProvider) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
+	i := len(p.requests)
+	p.requests = append(p.requests, req)
+	if i >= len(p.responses) {
+		return ports.GenerateResponse{}, fmt.Errorf("unexpected call %d", i+1)
+	}
+	return ports.GenerateResponse{Text: p.responses[i]}, nil
+}
+
+func (p *scriptedProvider) StreamGenerate(ctx context.Context, req ports.GenerateRequest) (<-chan ports.StreamChunk, error) {
+	return nil, fmt.Errorf("streaming not scripted")
+}
+
+// testContext is an application context serving provider with the default config.
+func testContext(provider ports.Provider) *bootstrap.ApplicationContext {
+	return &bootstrap.ApplicationContext{Config: &config.Config{}, Provider: provider, Logger: slog.New(slog.DiscardHandler)}
+}
+
+func TestExecuteAndHeal_RunsProposedPlan(t *testing.T) {
+	skipOnWindows(t)
+	provider := &scriptedProvider{responses: []string{
+		`{"type":"plan","explanation":"two more steps","steps":[{"command":"echo second"},{"command":"echo third"}]}`,
+	}}
+	script := ui.NewScripted("a")
+	h := &RunHandler{
+		Ctx:   testContext(provider),
+		UI:    script,
+		Flags: RunFlags{AgentMode: true, AgentMaxSteps: 3, SelfHeal: true, SelfHealMaxAttempts: 1},
+	}
+
+	if err := h.executeAndHeal(context.Background(), "echo first", nil, "do it"); err != nil {
+		t.Fatal(err)
+	}
+	if script.Printed() != "second\nthird\n" {
+		t.Errorf("expected the plan steps to run, got %q", script.Printed())
+	}
+	if script.Remaining() != 0 {
+		t.Errorf("expected the plan to be approved, %d answers left", script.Remaining())
+	}
+	if len(provider.requests) != 1 {
+		t.Errorf("expected one self-heal request, got %d", len(provider.requests))
+	}
+}
+
+func TestExecuteAndHeal_ReportsFailedPlan(t *testing.T) {
+	skipOnWindows(t)
+	provider := &scriptedProvider{responses: []string{
+		`{"type":"plan","explanation":"fix it","steps":[{"command":"false"}]}`,
+	}}
+	h := &RunHandler{
+		Ctx:   testContext(provider),
+		UI:    ui.NewScripted("a", "s"),
+		Flags: RunFlags{AgentMode: true, AgentMaxSteps: 3, SelfHeal: true},
+	}
+
+	err := h.executeAndHeal(context.Background(), "echo first", nil, "do it")
+	if err == nil || !strings.Contains(err.Error(), "plan stopped") {
+		t.Errorf("expected the plan's failure, got %v", err)
+	}
+}
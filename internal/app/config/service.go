@@ -25,49 +25,83 @@ func (s *Service) SetProvider(dir, providerName string) (*cfgpkg.Config, error)
 	if providerName == "" {
 		return nil, fmt.Errorf("provider name is empty")
 	}
-
-	cfg, err := s.store.Load(dir)
-	if err != nil {
-		return nil, err
+	if !cfgpkg.IsSupportedProvider(providerName) {
+		return nil, fmt.Errorf("unsupported provider: '%s'. Supported providers: %s", providerName, strings.Join(cfgpkg.SupportedProviders, ", "))
 	}
 
-	cfg.AI.Provider = providerName
-	if err := s.store.Write(dir, cfg); err != nil {
-		return nil, err
-	}
-	return cfg, nil
+	return s.update(dir, func(cfg *cfgpkg.Config) error {
+		cfg.AI.Provider = providerName
+		return nil
+	})
 }
 
-func (s *Service) SetGeminiAPIKey(dir, apiKey string) (*cfgpkg.Config, error) {
+// SetAPIKey stores the API key for the active provider.
+func (s *Service) SetAPIKey(dir, apiKey string) (*cfgpkg.Config, error) {
 	apiKey = strings.TrimSpace(apiKey)
 	if apiKey == "" {
 		return nil, fmt.Errorf("api key is empty")
 	}
 
-	cfg, err := s.store.Load(dir)
-	if err != nil {
-		return nil, err
-	}
-
-	cfg.AI.Gemini.APIKey = apiKey
-	if err := s.store.Write(dir, cfg); err != nil {
-		return nil, err
-	}
-	return cfg, nil
+	return s.update(dir, func(cfg *cfgpkg.Config) error {
+		switch cfg.AI.Provider {
+		case cfgpkg.ProviderGemini:
+			cfg.AI.Gemini.APIKey = apiKey
+		case cfgpkg.ProviderOpenAI:
+			cfg.AI.OpenAI.APIKey = apiKey
+		case cfgpkg.ProviderOllama:
+			return fmt.Errorf("ollama does not use an API key; use 'vibe config host <url>' instead")
+		default:
+			return fmt.Errorf("no active provider set or provider '%s' is not supported", cfg.AI.Provider)
+		}
+		return nil
+	})
 }
 
-func (s *Service) SetGeminiModel(dir, model string) (*cfgpkg.Config, error) {
+// SetModel stores the model for the active provider.
+func (s *Service) SetModel(dir, model string) (*cfgpkg.Config, error) {
 	model = strings.TrimSpace(model)
 	if model == "" {
 		return nil, fmt.Errorf("model is empty")
 	}
 
+	return s.update(dir, func(cfg *cfgpkg.Config) error {
+		switch cfg.AI.Provider {
+		case cfgpkg.ProviderGemini:
+			cfg.AI.Gemini.Model = strings.TrimPrefix(model, "models/")
+		case cfgpkg.ProviderOpenAI:
+			cfg.AI.OpenAI.Model = model
+		case cfgpkg.ProviderOllama:
+			cfg.AI.Ollama.Model = model
+		default:
+			return fmt.Errorf("no active provider set or provider '%s' is not supported", cfg.AI.Provider)
+		}
+		return nil
+	})
+}
+
+// SetOllamaHost stores the Ollama server URL.
+func (s *Service) SetOllamaHost(dir, host string) (*cfgpkg.Config, error) {
+	host = strings.TrimSpace(host)
+	if host == "" {
+		return nil, fmt.Errorf("host is empty")
+	}
+
+	return s.update(dir, func(cfg *cfgpkg.Config) error {
+		cfg.AI.Ollama.Host = host
+		return nil
+	})
+}
+
+func (s *Service) update(dir string, mutate func(cfg *cfgpkg.Config) error) (*cfgpkg.Config, error) {
 	cfg, err := s.store.Load(dir)
 	if err != nil {
 		return nil, err
 	}
 
-	cfg.AI.Gemini.Model = model
+	if err := mutate(cfg); err != nil {
+		return nil, err
+	}
+
 	if err := s.store.Write(dir, cfg); err != nil {
 		return nil, err
 	}
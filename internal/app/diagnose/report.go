@@ -94,3 +94,38 @@ func collectFixes(result *DiagnoseResult) []string {
 
 	return fixes
 }
+
+// AnalysisPrompt asks a model to explain the diagnosis and suggest fixes.
+func AnalysisPrompt(result *DiagnoseResult) string {
+	var sb strings.Builder
+	sb.WriteString("Analyze the following system diagnostics and provide a summary:\n\n")
+
+	if len(result.Errors) > 0 {
+		sb.WriteString("CRITICAL ERRORS:\n")
+		for _, issue := range result.Errors {
+			sb.WriteString(fmt.Sprintf("- %s: %s\n", issue.Category, issue.Description))
+		}
+	}
+
+	if len(result.Warnings) > 0 {
+		sb.WriteString("\nWARNINGS:\n")
+		for _, issue := range result.Warnings {
+			sb.WriteString(fmt.Sprintf("- %s: %s\n", issue.Category, issue.Description))
+		}
+	}
+
+	if len(result.OK) > 0 {
+		sb.WriteString("\nHEALTHY CHECKS:\n")
+		for _, check := range result.OK {
+			sb.WriteString(fmt.Sprintf("- %s: %s\n", check.Description, check.Value))
+		}
+	}
+
+	if len(result.Errors) == 0 && len(result.Warnings) == 0 {
+		sb.WriteString("\nThe system appears healthy. Please confirm this assessment and provide any optimization recommendations.")
+	} else {
+		sb.WriteString("\nPlease analyze root causes and suggest specific remediation steps.")
+	}
+
+	return sb.String()
+}
@@ -8,27 +8,27 @@ import (
 
 // DiagnoseResult represents the result of a diagnose operation
 type DiagnoseResult struct {
-	Warnings []Issue
-	Errors   []Issue
-	OK       []Check
-	Summary  string
+	Warnings []Issue `json:"warnings"`
+	Errors   []Issue `json:"errors"`
+	OK       []Check `json:"ok"`
+	Summary  string  `json:"summary"`
 }
 
 // Issue represents a problem found during diagnosis
 type Issue struct {
-	Category    string // disk, memory, network, service
-	Description string
-	Value       string // current value
-	Threshold   string // threshold value
-	Severity    string // warning, error
-	FixCommand  string // suggested fix command
+	Category    string `json:"category"` // disk, memory, network, service
+	Description string `json:"description"`
+	Value       string `json:"value,omitempty"`       // current value
+	Threshold   string `json:"threshold,omitempty"`   // threshold value
+	Severity    string `json:"severity"`              // warning, error
+	FixCommand  string `json:"fix_command,omitempty"` // suggested fix command
 }
 
 // Check represents a passed check
 type Check struct {
-	Category    string
-	Description string
-	Value       string
+	Category    string `json:"category"`
+	Description string `json:"description"`
+	Value       string `json:"value"`
 }
 
 // Service is the diagnose service
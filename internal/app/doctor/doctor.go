@@ -0,0 +1,348 @@
+// Package doctor checks vibe's own setup: configuration, providers, storage
+// and the external tools vibe relies on.
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/dependency"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/git"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/locale"
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+	"github.com/phamdaiminhquan/vibe-devops/pkg/config"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+// Check is one line of the report.
+type Check struct {
+	Category string `json:"category"`
+	Name     string `json:"name"`
+	Status   Status `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	// Hint suggests a fix for warnings and failures.
+	Hint string `json:"hint,omitempty"`
+}
+
+// Report is the result of Run.
+type Report struct {
+	Checks []Check `json:"checks"`
+}
+
+// Failed reports whether any check failed.
+func (r Report) Failed() bool {
+	for _, c := range r.Checks {
+		if c.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns how many checks ended with status s.
+func (r Report) Count(s Status) int {
+	n := 0
+	for _, c := range r.Checks {
+		if c.Status == s {
+			n++
+		}
+	}
+	return n
+}
+
+// ProviderFactory builds the provider adapter for a provider name and model.
+type ProviderFactory func(cfg *config.Config, name, model string) (ports.Provider, error)
+
+// Options configures the checks.
+type Options struct {
+	// Config is the loaded .vibe.yaml; ConfigErr is set instead when loading failed.
+	Config    *config.Config
+	ConfigErr error
+	// NewProvider builds each configured provider for the generation check.
+	NewProvider ProviderFactory
+	// Roles are the known model roles accepted under `ai.roles`.
+	Roles []string
+	// WorkDir is the project directory; HomeDir holds the global ~/.vibe directory.
+	WorkDir string
+	HomeDir string
+	// Timeout bounds each provider generation (default 30s).
+	Timeout time.Duration
+}
+
+// Service runs the checks.
+type Service struct {
+	opts Options
+	deps *dependency.Manager
+}
+
+// NewService creates a doctor with the default dependency list.
+func NewService(opts Options) *Service {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	return &Service{opts: opts, deps: dependency.NewManager()}
+}
+
+// Run executes every check. Provider checks make one tiny real generation each.
+func (s *Service) Run(ctx context.Context) Report {
+	var r Report
+	r.Checks = append(r.Checks, s.checkConfig()...)
+	r.Checks = append(r.Checks, s.checkProviders(ctx)...)
+	r.Checks = append(r.Checks, s.checkStorage()...)
+	r.Checks = append(r.Checks, s.checkDependencies(ctx)...)
+	r.Checks = append(r.Checks, s.checkFont(), s.checkCheckpoints())
+	return r
+}
+
+func (s *Service) checkConfig() []Check {
+	if s.opts.ConfigErr != nil || s.opts.Config == nil {
+		hint := "Run 'vibe init' to create it"
+		if s.opts.ConfigErr != nil && !errors.Is(s.opts.ConfigErr, os.ErrNotExist) {
+			hint = "Fix the YAML syntax in " + config.ConfigFileName
+		}
+		return []Check{{Category: "config", Name: config.ConfigFileName, Status: StatusFail, Detail: errorDetail(s.opts.ConfigErr), Hint: hint}}
+	}
+
+	cfg := s.opts.Config
+	var problems []string
+	if !config.IsSupportedProvider(normalize(cfg.AI.Provider)) {
+		problems = append(problems, fmt.Sprintf("unsupported ai.provider '%s'", cfg.AI.Provider))
+	}
+	for _, name := range cfg.AI.Fallbacks {
+		if !config.IsSupportedProvider(normalize(name)) {
+			problems = append(problems, fmt.Sprintf("unsupported fallback '%s'", name))
+		}
+	}
+	for role, rc := range cfg.AI.Roles {
+		if !slices.Contains(s.opts.Roles, role) {
+			problems = append(problems, fmt.Sprintf("unknown model role '%s'", role))
+		}
+		if rc.Provider != "" && !config.IsSupportedProvider(normalize(rc.Provider)) {
+			problems = append(problems, fmt.Sprintf("role '%s' uses unsupported provider '%s'", role, rc.Provider))
+		}
+	}
+	for name, value := range cfg.Tools.Policies {
+		if _, err := ports.ParseToolPolicy(value); err != nil {
+			problems = append(problems, fmt.Sprintf("tools.policies.%s: %v", name, err))
+		}
+	}
+	if len(problems) > 0 {
+		return []Check{{
+			Category: "config",
+			Name:     config.ConfigFileName,
+			Status:   StatusFail,
+			Detail:   strings.Join(problems, "; "),
+			Hint:     "Supported providers: " + strings.Join(config.SupportedProviders, ", "),
+		}}
+	}
+	return []Check{{Category: "config", Name: config.ConfigFileName, Status: StatusPass, Detail: fmt.Sprintf("provider %s, model %s", cfg.AI.Provider, cfg.ActiveModel())}}
+}
+
+// target is a provider and model to probe.
+type target struct {
+	provider, model string
+	// usedBy names what the target serves ("active", "fallback", role names).
+	usedBy []string
+}
+
+// targets lists the active provider, fallbacks and role routes, without duplicates.
+func (s *Service) targets() []*target {
+	cfg := s.opts.Config
+	var out []*target
+	add := func(provider, model, usedBy string) {
+		provider = normalize(provider)
+		if !config.IsSupportedProvider(provider) {
+			return
+		}
+		if model == "" {
+			model = cfg.ModelFor(provider)
+		}
+		for _, t := range out {
+			if t.provider == provider && t.model == model {
+				t.usedBy = append(t.usedBy, usedBy)
+				return
+			}
+		}
+		out = append(out, &target{provider: provider, model: model, usedBy: []string{usedBy}})
+	}
+	add(cfg.AI.Provider, "", "active")
+	for _, name := range cfg.AI.Fallbacks {
+		add(name, "", "fallback")
+	}
+	roles := make([]string, 0, len(cfg.AI.Roles))
+	for role := range cfg.AI.Roles {
+		roles = append(roles, role)
+	}
+	slices.Sort(roles)
+	for _, role := range roles {
+		provider, model := cfg.RoleTarget(role)
+		add(provider, model, "role "+role)
+	}
+	return out
+}
+
+func (s *Service) checkProviders(ctx context.Context) []Check {
+	if s.opts.Config == nil {
+		return []Check{{Category: "provider", Name: "providers", Status: StatusSkip, Detail: "no configuration"}}
+	}
+	var checks []Check
+	for _, t := range s.targets() {
+		checks = append(checks, s.probe(ctx, t))
+	}
+	return checks
+}
+
+// probe builds the provider, checks IsConfigured and makes a tiny real generation.
+func (s *Service) probe(ctx context.Context, t *target) Check {
+	c := Check{Category: "provider", Name: fmt.Sprintf("%s/%s (%s)", t.provider, t.model, strings.Join(t.usedBy, ", "))}
+
+	p, err := s.opts.NewProvider(s.opts.Config, t.provider, t.model)
+	if err == nil {
+		defer p.Close()
+		err = p.IsConfigured(ctx)
+	}
+	if err != nil {
+		c.Status, c.Detail = StatusFail, errorDetail(err)
+		c.Hint = providerHint(t.provider)
+		return c
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+	start := time.Now()
+	resp, err := p.Generate(ctx, ports.GenerateRequest{
+		Messages: []chat.Message{{Role: chat.RoleUser, Content: "Reply with the single word OK."}},
+		Sampling: ports.Sampling{MaxTokens: 16},
+	})
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		c.Status, c.Detail = StatusFail, errorDetail(err)
+		c.Hint = providerHint(t.provider)
+		return c
+	}
+	c.Status = StatusPass
+	c.Detail = fmt.Sprintf("answered in %s", elapsed)
+	if n := resp.Usage.TotalTokens(); n > 0 {
+		c.Detail += fmt.Sprintf(", %d tokens", n)
+	}
+	if ports.SupportsToolCalling(p) {
+		c.Detail += ", native tools"
+	}
+	return c
+}
+
+func providerHint(provider string) string {
+	switch provider {
+	case config.ProviderOllama:
+		return "Check that Ollama is running ('ollama serve') and the model is pulled ('vibe model pull <name>'); set the URL with 'vibe config host'"
+	default:
+		return "Check the API key ('vibe config api-key'), the model name ('vibe model --list') and network access"
+	}
+}
+
+// checkStorage verifies that the session directories and ~/.vibe are writable.
+func (s *Service) checkStorage() []Check {
+	dirs := []struct{ name, path string }{
+		{"project sessions", filepath.Join(s.opts.WorkDir, ".vibe", "sessions")},
+		{"global sessions", filepath.Join(s.opts.HomeDir, ".vibe", "sessions")},
+		{"usage log & cache", filepath.Join(s.opts.HomeDir, ".vibe")},
+	}
+	checks := make([]Check, 0, len(dirs))
+	for _, d := range dirs {
+		c := Check{Category: "storage", Name: d.name, Status: StatusPass, Detail: d.path}
+		if err := writable(d.path); err != nil {
+			c.Status, c.Detail = StatusFail, errorDetail(err)
+			c.Hint = "Fix the permissions of " + d.path
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// writable creates dir if needed and writes and removes a probe file in it.
+func writable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	_, err = f.WriteString("ok")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if rerr := os.Remove(name); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+func (s *Service) checkDependencies(ctx context.Context) []Check {
+	var checks []Check
+	for _, r := range s.deps.VerifyAll(ctx) {
+		c := Check{Category: "dependency", Name: r.Dependency.Name}
+		switch r.Status {
+		case dependency.StatusInstalled:
+			c.Status, c.Detail = StatusPass, strings.TrimSpace(r.Version)
+		case dependency.StatusMissing:
+			c.Status, c.Detail, c.Hint = StatusWarn, r.Dependency.Binary+" not found in PATH", r.Dependency.InstallHint
+			if !r.Dependency.Critical {
+				c.Status = StatusSkip
+			}
+		default:
+			c.Status, c.Detail, c.Hint = StatusFail, errorDetail(r.Error), r.Dependency.InstallHint
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+func (s *Service) checkFont() Check {
+	c := Check{Category: "terminal", Name: "Vietnamese font support", Status: StatusPass}
+	if ok, suggestion := locale.CheckVietnameseFontSupport(); !ok {
+		c.Status, c.Detail, c.Hint = StatusWarn, "terminal may not render Vietnamese text", suggestion
+	}
+	return c
+}
+
+// checkCheckpoints reports whether vibe could create a git checkpoint before running commands.
+func (s *Service) checkCheckpoints() Check {
+	c := Check{Category: "git", Name: "checkpoints"}
+	switch {
+	case !git.IsGitRepo(s.opts.WorkDir):
+		c.Status, c.Detail, c.Hint = StatusWarn, "not a git repository; 'vibe undo' will not be available", "Run 'git init' to enable checkpoints"
+	case !git.HasIdentity(s.opts.WorkDir):
+		c.Status, c.Detail = StatusFail, "git user.name / user.email are not set, so checkpoint commits would fail"
+		c.Hint = "Run 'git config user.name <name>' and 'git config user.email <email>'"
+	default:
+		c.Status, c.Detail = StatusPass, fmt.Sprintf("%d uncommitted files", git.CountUncommittedFiles(s.opts.WorkDir))
+	}
+	return c
+}
+
+func normalize(name string) string { return strings.ToLower(strings.TrimSpace(name)) }
+
+func errorDetail(err error) string {
+	if err == nil {
+		return "unknown error"
+	}
+	return strings.SplitN(err.Error(), "\n", 2)[0]
+}
@@ -0,0 +1,111 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+	"github.com/phamdaiminhquan/vibe-devops/pkg/config"
+)
+
+type stubProvider struct {
+	name string
+	err  error
+}
+
+func (p *stubProvider) Name() string                           { return p.name }
+func (p *stubProvider) IsConfigured(ctx context.Context) error { return nil }
+func (p *stubProvider) Close() error                           { return nil }
+
+func (p *stubProvider) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
+	if p.err != nil {
+		return ports.GenerateResponse{}, p.err
+	}
+	return ports.GenerateResponse{Text: "OK", Usage: ports.Usage{PromptTokens: 9, CompletionTokens: 1}}, nil
+}
+
+func (p *stubProvider) StreamGenerate(ctx context.Context, req ports.GenerateRequest) (<-chan ports.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func testConfig() *config.Config {
+	cfg := config.GetDefaultConfig()
+	cfg.AI.Provider = config.ProviderOpenAI
+	cfg.AI.Fallbacks = []string{"ollama", "openai"}
+	cfg.AI.Roles = map[string]config.RoleConfig{
+		"summarizer": {Provider: "ollama"},
+		"analyzer":   {Model: "gpt-4o-mini"},
+	}
+	return cfg
+}
+
+func TestCheckProviders(t *testing.T) {
+	var built []string
+	svc := NewService(Options{
+		Config: testConfig(),
+		NewProvider: func(cfg *config.Config, name, model string) (ports.Provider, error) {
+			built = append(built, name+"/"+model)
+			if name == config.ProviderOllama {
+				return &stubProvider{name: name, err: errors.New("connection refused")}, nil
+			}
+			return &stubProvider{name: name}, nil
+		},
+	})
+
+	checks := svc.checkProviders(context.Background())
+	// openai/gpt-4o (active), ollama/llama3 (fallback + summarizer), openai/gpt-4o-mini (analyzer)
+	if len(checks) != 3 {
+		t.Fatalf("expected 3 distinct targets, got %d: %v", len(checks), built)
+	}
+	if checks[0].Status != StatusPass || !strings.Contains(checks[0].Detail, "10 tokens") {
+		t.Errorf("unexpected active check: %+v", checks[0])
+	}
+	if checks[1].Status != StatusFail || checks[1].Name != "ollama/llama3 (fallback, role summarizer)" || checks[1].Hint == "" {
+		t.Errorf("unexpected ollama check: %+v", checks[1])
+	}
+	if checks[2].Name != "openai/gpt-4o-mini (role analyzer)" {
+		t.Errorf("unexpected role check: %+v", checks[2])
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	missing := NewService(Options{ConfigErr: os.ErrNotExist}).checkConfig()
+	if missing[0].Status != StatusFail || !strings.Contains(missing[0].Hint, "vibe init") {
+		t.Errorf("unexpected check for a missing config: %+v", missing[0])
+	}
+
+	cfg := testConfig()
+	cfg.AI.Roles["critic"] = config.RoleConfig{Provider: "claude"}
+	bad := NewService(Options{Config: cfg, Roles: []string{"agent", "summarizer", "analyzer"}}).checkConfig()
+	if bad[0].Status != StatusFail || !strings.Contains(bad[0].Detail, "unknown model role 'critic'") || !strings.Contains(bad[0].Detail, "'claude'") {
+		t.Errorf("unexpected check for an invalid config: %+v", bad[0])
+	}
+
+	good := NewService(Options{Config: testConfig(), Roles: []string{"agent", "summarizer", "analyzer"}}).checkConfig()
+	if good[0].Status != StatusPass {
+		t.Errorf("expected a valid config to pass, got %+v", good[0])
+	}
+}
+
+func TestCheckStorage(t *testing.T) {
+	work, home := t.TempDir(), t.TempDir()
+	// A file where ~/.vibe should be makes every global directory unusable.
+	if err := os.WriteFile(filepath.Join(home, ".vibe"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := NewService(Options{WorkDir: work, HomeDir: home}).checkStorage()
+	if checks[0].Status != StatusPass {
+		t.Errorf("expected the project directory to be writable, got %+v", checks[0])
+	}
+	if checks[1].Status != StatusFail || checks[2].Status != StatusFail || checks[2].Hint == "" {
+		t.Errorf("expected the global directories to fail, got %+v", checks[1:])
+	}
+	if entries, _ := os.ReadDir(filepath.Join(work, ".vibe", "sessions")); len(entries) != 0 {
+		t.Errorf("expected the probe file to be removed, found %d entries", len(entries))
+	}
+}
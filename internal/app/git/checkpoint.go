@@ -175,3 +175,16 @@ func CountUncommittedFiles(workDir string) int {
 	}
 	return len(lines)
 }
+
+// HasIdentity checks if git has a user name and email, which checkpoint commits need
+func HasIdentity(workDir string) bool {
+	for _, key := range []string{"user.name", "user.email"} {
+		cmd := exec.Command("git", "config", key)
+		cmd.Dir = workDir
+		output, err := cmd.Output()
+		if err != nil || strings.TrimSpace(string(output)) == "" {
+			return false
+		}
+	}
+	return true
+}
@@ -1,7 +1,6 @@
 package safety
 
 import (
-	"bufio"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -10,6 +9,8 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
 )
 
 const (
@@ -293,47 +294,40 @@ func CleanupOldBackups() {
 }
 
 // PromptBackupChoice asks user what to do with dangerous command
-func PromptBackupChoice(cmd string, result CheckResult) (action string, doBackup bool) {
-	fmt.Println()
-
+func PromptBackupChoice(ui ports.UI, cmd string, result CheckResult) (action string, doBackup bool) {
+	var b strings.Builder
 	if result.Level == Blocked {
-		fmt.Println("⚠️  BLOCKED: This command is too dangerous to execute:")
-		fmt.Printf("   %s\n", cmd)
-		fmt.Printf("   Reason: %s\n", result.Description)
+		fmt.Fprintf(&b, "\nBLOCKED: This command is too dangerous to execute:\n   %s\n   Reason: %s", cmd, result.Description)
+		ui.Message(ports.MessageWarning, b.String())
 		return "cancel", false
 	}
 
 	if result.Level == Dangerous {
-		fmt.Println("⚠️  DANGEROUS COMMAND:")
+		b.WriteString("DANGEROUS COMMAND:\n")
 	} else {
-		fmt.Println("⚠️  WARNING:")
+		b.WriteString("WARNING:\n")
 	}
 
-	fmt.Printf("   %s\n", cmd)
-	fmt.Printf("   Reason: %s\n", result.Description)
+	fmt.Fprintf(&b, "   %s\n", cmd)
+	fmt.Fprintf(&b, "   Reason: %s", result.Description)
 
 	if len(result.AffectedPaths) > 0 {
-		fmt.Println("\n   Affected system paths:")
+		b.WriteString("\n\n   Affected system paths:")
 		for _, p := range result.AffectedPaths {
-			fmt.Printf("   • %s\n", p)
+			fmt.Fprintf(&b, "\n   • %s", p)
 		}
 	}
 
 	if result.Alternative != "" {
-		fmt.Printf("\n   Suggestion: %s\n", result.Alternative)
+		fmt.Fprintf(&b, "\n\n   Suggestion: %s", result.Alternative)
 	}
+	ui.Message(ports.MessageWarning, b.String())
 
-	fmt.Println("\n   Options:")
-	fmt.Println("   [b] Create backup first, then run")
-	fmt.Println("   [r] Run without backup")
-	fmt.Println("   [c] Cancel")
-	fmt.Print("\n   Choice: ")
-
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.ToLower(strings.TrimSpace(input))
-
-	switch input {
+	switch ui.Choose("\n   Choice:", []ports.Choice{
+		{Key: "b", Label: "Create backup first, then run"},
+		{Key: "r", Label: "Run without backup"},
+		{Key: "c", Label: "Cancel"},
+	}) {
 	case "b":
 		return "run", true
 	case "r":
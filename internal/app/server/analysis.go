@@ -0,0 +1,231 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/context/logs"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/diagnose"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/session"
+)
+
+// defaultLogTail is how many lines a log analysis reads when no tail is given.
+const defaultLogTail = 100
+
+// AnalysisRequest asks for an AI analysis on top of the collected data.
+type AnalysisRequest struct {
+	AI bool `json:"ai,omitempty"`
+}
+
+// DiagnoseResponse is the result of POST /v1/diagnose.
+type DiagnoseResponse struct {
+	Result *diagnose.DiagnoseResult `json:"result"`
+	// Report is the text report printed by 'vibe diagnose'.
+	Report string `json:"report"`
+	// Analysis is the model's analysis, with AnalysisError set when it failed.
+	Analysis      string `json:"analysis,omitempty"`
+	AnalysisError string `json:"analysis_error,omitempty"`
+}
+
+// LogRequest is the body of POST /v1/logs/analyze.
+type LogRequest struct {
+	AnalysisRequest
+	// Path is the log file. Relative paths are resolved against the log
+	// directory, and the file must be inside it.
+	Path string `json:"path"`
+	// Tail is how many lines to read from the end (default 100).
+	Tail int `json:"tail,omitempty"`
+}
+
+// LogResponse is the result of POST /v1/logs/analyze.
+type LogResponse struct {
+	Path   string         `json:"path"`
+	Format string         `json:"format"`
+	Lines  int            `json:"lines"`
+	Levels map[string]int `json:"levels"`
+	Issues []LogIssue     `json:"issues"`
+	// Categories counts the issues by category.
+	Categories    map[string]int `json:"categories"`
+	Analysis      string         `json:"analysis,omitempty"`
+	AnalysisError string         `json:"analysis_error,omitempty"`
+}
+
+// LogIssue is a problem detected on one line of a log.
+type LogIssue struct {
+	Line        int    `json:"line"`
+	Level       string `json:"level"`
+	Category    string `json:"category"`
+	Description string `json:"description"`
+	Content     string `json:"content"`
+}
+
+// handleListSessions lists saved sessions; ?scope= narrows it to project or global.
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	scope := session.Scope(r.URL.Query().Get("scope"))
+	switch scope {
+	case "":
+		scope = session.ScopeBoth
+	case session.ScopeProject, session.ScopeGlobal, session.ScopeBoth:
+	default:
+		writeError(w, http.StatusBadRequest, "invalid scope (use project, global or both)")
+		return
+	}
+	listings, err := s.sessions.List(scope)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if listings == nil {
+		listings = []session.Listing{}
+	}
+	writeJSON(w, http.StatusOK, listings)
+}
+
+func (s *Server) handleDiagnose(w http.ResponseWriter, r *http.Request) {
+	var req AnalysisRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := s.diagnose(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := DiagnoseResponse{Result: result, Report: plain(diagnose.FormatReport(result))}
+	if req.AI {
+		resp.Analysis, resp.AnalysisError = s.analysis(r, diagnose.AnalysisPrompt(result))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleAnalyzeLogs(w http.ResponseWriter, r *http.Request) {
+	var req LogRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if strings.TrimSpace(req.Path) == "" {
+		writeError(w, http.StatusBadRequest, "path is required")
+		return
+	}
+	path, resolved, err := s.logPath(req.Path)
+	if errors.Is(err, errOutsideLogDir) {
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	tail := req.Tail
+	if tail <= 0 {
+		tail = defaultLogTail
+	}
+	lines, err := logs.ReadLastLines(resolved, tail)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp := LogResponse{
+		Path:       path,
+		Format:     logs.FormatName(detectFormat(lines)),
+		Lines:      len(lines),
+		Levels:     map[string]int{},
+		Issues:     []LogIssue{},
+		Categories: map[string]int{},
+	}
+	for level, count := range logs.CountByLevel(lines) {
+		resp.Levels[strings.ToLower(logs.LevelString(level))] = count
+	}
+	issues := logs.AnalyzeLines(lines)
+	for _, issue := range issues {
+		resp.Issues = append(resp.Issues, LogIssue{
+			Line:        issue.Line,
+			Level:       strings.ToLower(logs.LevelString(issue.Level)),
+			Category:    issue.Category,
+			Description: issue.Description,
+			Content:     issue.Content,
+		})
+	}
+	for category, count := range logs.SummarizeIssues(issues) {
+		resp.Categories[category] = count
+	}
+	if req.AI {
+		resp.Analysis, resp.AnalysisError = s.analysis(r, logs.AnalysisPrompt(filepath.Base(path), lines))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// errOutsideLogDir refuses log paths that leave the log directory.
+var errOutsideLogDir = errors.New("path is outside the log directory")
+
+// logPath resolves a requested log file against the log directory. It returns
+// the absolute path and the path with symlinks followed, which must still be
+// inside the directory.
+func (s *Server) logPath(requested string) (string, string, error) {
+	root := s.cfg.LogDir
+	if root == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", "", err
+		}
+		root = cwd
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", "", err
+	}
+	path := requested
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	path = filepath.Clean(path)
+	if !within(root, path) {
+		return "", "", errOutsideLogDir
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", "", err
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", "", err
+	}
+	if !within(realRoot, resolved) {
+		return "", "", errOutsideLogDir
+	}
+	return path, resolved, nil
+}
+
+// within reports whether path is root or below it; both must be clean and absolute.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// analysis runs prompt through the analyzer; a failure is reported next to the
+// collected data rather than failing the request.
+func (s *Server) analysis(r *http.Request, prompt string) (string, string) {
+	text, err := s.analyze(r.Context(), prompt)
+	if err != nil {
+		return "", err.Error()
+	}
+	return text, ""
+}
+
+// detectFormat guesses the format from the first non-empty line.
+func detectFormat(lines []string) logs.LogFormat {
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			return logs.DetectFormat(line)
+		}
+	}
+	return logs.FormatPlain
+}
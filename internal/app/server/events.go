@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/command"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// Event types streamed from a run.
+const (
+	EventProgress = "progress"
+	EventToken    = "token"
+	EventExplain  = "explain"
+	EventMessage  = "message"
+	EventCommand  = "command"
+	EventOutput   = "output"
+	EventResult   = "result"
+	EventPrompt   = "prompt"
+	EventAnswered = "answered"
+	EventDone     = "done"
+)
+
+// Prompt kinds.
+const (
+	PromptConfirm = "confirm"
+	PromptChoose  = "choose"
+	PromptInput   = "input"
+)
+
+// Event is one thing that happened during a run. IDs increase from 1, so
+// clients resume a stream with Last-Event-ID.
+type Event struct {
+	ID   int    `json:"id"`
+	Type string `json:"type"`
+	// Level is set on messages: info, success, warning or error.
+	Level string `json:"level,omitempty"`
+	Text  string `json:"text,omitempty"`
+	// Label and Command are set on command events, Command and ExitCode on results.
+	Label    string  `json:"label,omitempty"`
+	Command  string  `json:"command,omitempty"`
+	ExitCode *int    `json:"exit_code,omitempty"`
+	Prompt   *Prompt `json:"prompt,omitempty"`
+	// Result is set on the final done event.
+	Result *command.RunResult `json:"result,omitempty"`
+}
+
+// Prompt is a question the run waits on until a client answers it.
+type Prompt struct {
+	// ID is the ID of the prompt event.
+	ID       int            `json:"id"`
+	Kind     string         `json:"kind,omitempty"`
+	Question string         `json:"question,omitempty"`
+	Choices  []ports.Choice `json:"choices,omitempty"`
+}
+
+// Run statuses.
+const (
+	RunRunning  = "running"
+	RunWaiting  = "waiting"
+	RunFinished = "finished"
+)
+
+// run is one request driven over HTTP. It is the run's ports.UI: everything
+// shown becomes an event, and prompts block until answered or cancelled.
+type run struct {
+	id      string
+	request RunRequest
+	created time.Time
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	mu       sync.Mutex
+	events   []Event
+	changed  chan struct{}
+	pending  *Prompt
+	answer   chan string
+	result   *command.RunResult
+	finished time.Time
+}
+
+func newRun(id string, req RunRequest) *run {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &run{id: id, request: req, created: time.Now(), ctx: ctx, cancel: cancel, changed: make(chan struct{})}
+}
+
+func (r *run) Confirm(question string) bool {
+	answer, err := r.ask(Prompt{Kind: PromptConfirm, Question: question})
+	answer = strings.ToLower(answer)
+	return err == nil && (answer == "y" || answer == "yes")
+}
+
+func (r *run) Choose(question string, choices []ports.Choice) string {
+	answer, _ := r.ask(Prompt{Kind: PromptChoose, Question: question, Choices: choices})
+	for _, c := range choices {
+		if strings.EqualFold(answer, c.Key) {
+			return c.Key
+		}
+	}
+	return ""
+}
+
+func (r *run) Input(question string) (string, error) {
+	return r.ask(Prompt{Kind: PromptInput, Question: question})
+}
+
+func (r *run) Progress(message string) { r.publish(Event{Type: EventProgress, Text: message}) }
+func (r *run) Token(text string)       { r.publish(Event{Type: EventToken, Text: text}) }
+
+func (r *run) Explain(text string) {
+	r.publish(Event{Type: EventExplain, Text: strings.TrimSpace(text)})
+}
+
+func (r *run) Message(level ports.MessageLevel, text string) {
+	if text = strings.TrimSpace(plain(text)); text != "" {
+		r.publish(Event{Type: EventMessage, Level: levelName(level), Text: text})
+	}
+}
+
+func (r *run) Command(label, cmd string) {
+	r.publish(Event{Type: EventCommand, Label: strings.TrimSpace(label), Command: cmd})
+}
+
+func (r *run) Result(outcome ports.CommandOutcome) {
+	exitCode := outcome.ExitCode
+	r.publish(Event{Type: EventResult, Command: outcome.Command, ExitCode: &exitCode, Text: outcome.Stderr})
+}
+
+// Output streams command output as output events.
+func (r *run) Output() io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		r.publish(Event{Type: EventOutput, Text: string(p)})
+		return len(p), nil
+	})
+}
+
+// ask publishes a prompt and waits for its answer. Cancelling the run answers
+// every prompt with an error.
+func (r *run) ask(p Prompt) (string, error) {
+	r.mu.Lock()
+	p.Question = strings.TrimSpace(p.Question)
+	p.ID = len(r.events) + 1
+	r.pending, r.answer = &p, make(chan string, 1)
+	answer := r.answer
+	r.appendLocked(Event{Type: EventPrompt, Prompt: &p})
+	r.mu.Unlock()
+
+	select {
+	case a := <-answer:
+		return strings.TrimSpace(a), nil
+	case <-r.ctx.Done():
+		r.mu.Lock()
+		r.pending, r.answer = nil, nil
+		r.mu.Unlock()
+		return "", r.ctx.Err()
+	}
+}
+
+// reply answers the pending prompt. It reports false when promptID is not
+// the prompt the run waits on.
+func (r *run) reply(promptID int, answer string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending == nil || r.pending.ID != promptID {
+		return false
+	}
+	r.answer <- answer
+	r.pending, r.answer = nil, nil
+	r.appendLocked(Event{Type: EventAnswered, Prompt: &Prompt{ID: promptID}, Text: answer})
+	return true
+}
+
+// finish records the result and ends the event stream.
+func (r *run) finish(result command.RunResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.result = &result
+	r.finished = time.Now()
+	r.appendLocked(Event{Type: EventDone, Result: &result})
+	r.cancel()
+}
+
+func (r *run) publish(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.appendLocked(e)
+}
+
+func (r *run) appendLocked(e Event) {
+	e.ID = len(r.events) + 1
+	r.events = append(r.events, e)
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// eventsAfter returns the events after ID after, a channel closed on the next
+// event, and whether the run has finished.
+func (r *run) eventsAfter(after int) ([]Event, <-chan struct{}, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []Event
+	if after < len(r.events) {
+		out = append(out, r.events[max(after, 0):]...)
+	}
+	return out, r.changed, r.result != nil
+}
+
+func (r *run) done() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.result != nil
+}
+
+// status needs r.mu held.
+func (r *run) status() string {
+	switch {
+	case r.result != nil:
+		return RunFinished
+	case r.pending != nil:
+		return RunWaiting
+	default:
+		return RunRunning
+	}
+}
+
+func levelName(level ports.MessageLevel) string {
+	switch level {
+	case ports.MessageSuccess:
+		return "success"
+	case ports.MessageWarning:
+		return "warning"
+	case ports.MessageError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+var _ ports.UI = (*run)(nil)
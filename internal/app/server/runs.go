@@ -0,0 +1,262 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/approval"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/bootstrap"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/command"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// keptRuns is how many finished runs the server remembers.
+const keptRuns = 50
+
+// keepAliveInterval is how often an idle event stream gets a comment line, so
+// proxies and clients don't time it out.
+const keepAliveInterval = 15 * time.Second
+
+// RunRequest starts a run.
+type RunRequest struct {
+	Request string `json:"request"`
+	// Session is the session memory to use; empty means the server default.
+	Session string `json:"session,omitempty"`
+	// Policy approves commands without prompting (strict, readonly or auto).
+	// Empty means every command and tool call waits for a client's answer.
+	Policy   string `json:"policy,omitempty"`
+	MaxSteps int    `json:"max_steps,omitempty"`
+
+	// policy is Policy as parsed when the run was started.
+	policy approval.Policy
+}
+
+// RunInfo describes a run.
+type RunInfo struct {
+	ID         string             `json:"id"`
+	Request    RunRequest         `json:"request"`
+	Status     string             `json:"status"`
+	CreatedAt  time.Time          `json:"created_at"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+	Prompt     *Prompt            `json:"prompt,omitempty"`
+	Result     *command.RunResult `json:"result,omitempty"`
+}
+
+// AnswerRequest answers a prompt: Approve for confirmations, Answer for
+// choices (the choice key) and free text.
+type AnswerRequest struct {
+	Approve *bool  `json:"approve,omitempty"`
+	Answer  string `json:"answer,omitempty"`
+}
+
+func (s *Server) handleStartRun(w http.ResponseWriter, r *http.Request) {
+	var req RunRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.Request = strings.TrimSpace(req.Request)
+	if req.Request == "" {
+		writeError(w, http.StatusBadRequest, "request is required")
+		return
+	}
+	if req.Policy != "" {
+		policy, err := approval.ParsePolicy(req.Policy)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		req.policy = policy
+	}
+
+	s.mu.Lock()
+	for _, existing := range s.runs {
+		if !existing.done() {
+			s.mu.Unlock()
+			writeError(w, http.StatusConflict, fmt.Sprintf("run %s is still active", existing.id))
+			return
+		}
+	}
+	s.nextID++
+	rn := newRun(strconv.Itoa(s.nextID), req)
+	s.runs = append(s.runs, rn)
+	if len(s.runs) > keptRuns {
+		s.runs = s.runs[len(s.runs)-keptRuns:]
+	}
+	s.mu.Unlock()
+
+	go func() {
+		rn.finish(s.execute(rn.ctx, rn, req))
+	}()
+	writeJSON(w, http.StatusAccepted, rn.info())
+}
+
+func (s *Server) handleListRuns(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	runs := append([]*run(nil), s.runs...)
+	s.mu.Unlock()
+
+	infos := make([]RunInfo, 0, len(runs))
+	for i := len(runs) - 1; i >= 0; i-- {
+		infos = append(infos, runs[i].info())
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (s *Server) handleGetRun(w http.ResponseWriter, r *http.Request) {
+	if rn := s.lookup(w, r); rn != nil {
+		writeJSON(w, http.StatusOK, rn.info())
+	}
+}
+
+// handleCancelRun cancels a run; a pending prompt is answered with "no".
+func (s *Server) handleCancelRun(w http.ResponseWriter, r *http.Request) {
+	if rn := s.lookup(w, r); rn != nil {
+		rn.cancel()
+		writeJSON(w, http.StatusAccepted, rn.info())
+	}
+}
+
+func (s *Server) handleAnswer(w http.ResponseWriter, r *http.Request) {
+	rn := s.lookup(w, r)
+	if rn == nil {
+		return
+	}
+	promptID, err := strconv.Atoi(r.PathValue("prompt"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid prompt id")
+		return
+	}
+	var req AnswerRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	answer := req.Answer
+	if req.Approve != nil {
+		answer = "n"
+		if *req.Approve {
+			answer = "y"
+		}
+	}
+	if !rn.reply(promptID, answer) {
+		writeError(w, http.StatusConflict, fmt.Sprintf("run %s is not waiting on prompt %d", rn.id, promptID))
+		return
+	}
+	writeJSON(w, http.StatusOK, rn.info())
+}
+
+// handleEvents streams the events of a run as server-sent events, starting
+// after Last-Event-ID (or ?after=), until the run finishes.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	rn := s.lookup(w, r)
+	if rn == nil {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	after, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+	if q := r.URL.Query().Get("after"); q != "" {
+		after, _ = strconv.Atoi(q)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		events, changed, finished := rn.eventsAfter(after)
+		for _, e := range events {
+			var data bytes.Buffer
+			enc := json.NewEncoder(&data)
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(e); err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n", e.ID, e.Type, data.Bytes())
+			after = e.ID
+		}
+		flusher.Flush()
+		if finished {
+			return
+		}
+		select {
+		case <-changed:
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) *run {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rn := range s.runs {
+		if rn.id == id {
+			return rn
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Sprintf("run %s not found", id))
+	return nil
+}
+
+func (r *run) info() RunInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info := RunInfo{
+		ID:        r.id,
+		Request:   r.request,
+		Status:    r.status(),
+		CreatedAt: r.created,
+		Prompt:    r.pending,
+		Result:    r.result,
+	}
+	if r.result != nil {
+		finished := r.finished
+		info.FinishedAt = &finished
+	}
+	return info
+}
+
+// runAgent runs req through the same handler as 'vibe run', with ui as its
+// terminal.
+func (s *Server) runAgent(ctx context.Context, ui ports.UI, req RunRequest) command.RunResult {
+	flags := s.cfg.Flags
+	flags.Detached = true
+	flags.JSON = false
+	flags.AgentMode = true
+	flags.Policy = req.policy
+	if req.MaxSteps > 0 {
+		flags.AgentMaxSteps = req.MaxSteps
+	}
+
+	sessionCfg := s.cfg.Session
+	if req.Session != "" {
+		sessionCfg.Name = req.Session
+	}
+	flags.SessionName = sessionCfg.Name
+	sess := bootstrap.InitializeSessionService(s.app.ProviderFor(bootstrap.RoleSummarizer), sessionCfg)
+
+	// The budget caps each run, and its usage goes under its own session.
+	s.app.Usage.StartRun(sessionCfg.Name)
+	defer s.app.Usage.SetSession(s.cfg.Session.Name)
+
+	handler := command.NewRunHandler(s.app, sess, ui, flags)
+	err := handler.Handle(ctx, req.Request)
+	return handler.Result(err)
+}
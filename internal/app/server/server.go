@@ -0,0 +1,168 @@
+// Package server exposes the agent over a local HTTP/JSON API for 'vibe serve'.
+//
+// A run started over HTTP goes through the same command.RunHandler as
+// 'vibe run'; its prompts and output become events that clients stream over
+// SSE, and approvals come back as HTTP requests.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/bootstrap"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/command"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/diagnose"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/session"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// maxBodyBytes caps the size of request bodies.
+const maxBodyBytes = 1 << 20
+
+// Config configures a Server.
+type Config struct {
+	// Token must be sent by clients as "Authorization: Bearer <token>".
+	Token string
+	// Flags are the defaults for runs; a run request may override some of them.
+	Flags command.RunFlags
+	// Session configures session memory; runs may pick another session name.
+	Session bootstrap.SessionConfig
+	// NoCache disables the response cache for diagnose and log analysis.
+	NoCache bool
+	// LogDir is the directory log analysis may read from; empty means the
+	// working directory.
+	LogDir string
+}
+
+// Server serves the HTTP API. Only one run is active at a time, because runs
+// share the working directory and its git checkpoints.
+type Server struct {
+	app      *bootstrap.ApplicationContext
+	cfg      Config
+	sessions *session.Service
+
+	mu   sync.Mutex
+	runs []*run
+	// nextID numbers runs.
+	nextID int
+
+	// execute runs one request against the agent; tests replace it.
+	execute func(ctx context.Context, ui ports.UI, req RunRequest) command.RunResult
+	// diagnose and analyze are replaced in tests as well.
+	diagnose func(ctx context.Context) (*diagnose.DiagnoseResult, error)
+	analyze  func(ctx context.Context, prompt string) (string, error)
+}
+
+// New returns a server driving the agent of app.
+func New(app *bootstrap.ApplicationContext, cfg Config) *Server {
+	s := newServer(cfg)
+	s.app = app
+	s.sessions = bootstrap.InitializeSessionService(app.ProviderFor(bootstrap.RoleSummarizer), cfg.Session)
+	s.execute = s.runAgent
+	s.analyze = s.generate
+	return s
+}
+
+func newServer(cfg Config) *Server {
+	return &Server{
+		cfg: cfg,
+		diagnose: func(ctx context.Context) (*diagnose.DiagnoseResult, error) {
+			return diagnose.NewService().Run(ctx)
+		},
+		analyze: func(context.Context, string) (string, error) {
+			return "", errors.New("no AI provider configured")
+		},
+	}
+}
+
+// Handler returns the HTTP handler of the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/health", s.handleHealth)
+	mux.Handle("POST /v1/runs", s.authorized(s.handleStartRun))
+	mux.Handle("GET /v1/runs", s.authorized(s.handleListRuns))
+	mux.Handle("GET /v1/runs/{id}", s.authorized(s.handleGetRun))
+	mux.Handle("DELETE /v1/runs/{id}", s.authorized(s.handleCancelRun))
+	mux.Handle("GET /v1/runs/{id}/events", s.authorized(s.handleEvents))
+	mux.Handle("POST /v1/runs/{id}/prompts/{prompt}", s.authorized(s.handleAnswer))
+	mux.Handle("GET /v1/sessions", s.authorized(s.handleListSessions))
+	mux.Handle("POST /v1/diagnose", s.authorized(s.handleDiagnose))
+	mux.Handle("POST /v1/logs/analyze", s.authorized(s.handleAnalyzeLogs))
+	return mux
+}
+
+// Shutdown cancels the active run, if any.
+func (s *Server) Shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.runs {
+		r.cancel()
+	}
+}
+
+// authorized rejects requests without the server token. Browsers cannot set
+// headers on EventSource, so the token may also come as ?token=.
+func (s *Server) authorized(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			token = r.URL.Query().Get("token")
+		}
+		if s.cfg.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		next(w, r)
+	})
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// generate asks the analyzer model for a one-off analysis.
+func (s *Server) generate(ctx context.Context, prompt string) (string, error) {
+	resp, err := s.app.CachedProvider(bootstrap.RoleAnalyzer, !s.cfg.NoCache).Generate(ctx, ports.GenerateRequest{
+		Prompt: prompt,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// decodeBody reads a JSON request body into v. An empty body leaves v as is.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) error {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(v)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+var ansiRE = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// plain strips terminal colors, which mean nothing to API clients.
+func plain(s string) string {
+	return ansiRE.ReplaceAllString(s, "")
+}
@@ -0,0 +1,399 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/adapters/sessionstore/jsonfile"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/approval"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/bootstrap"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/command"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/session"
+	"github.com/phamdaiminhquan/vibe-devops/internal/app/usage"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+	"github.com/phamdaiminhquan/vibe-devops/pkg/config"
+)
+
+const testToken = "secret"
+
+// newTestServer returns a server whose runs ask to run one command and report
+// whether it was approved.
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	s := newServer(Config{Token: testToken})
+	s.execute = func(ctx context.Context, ui ports.UI, req RunRequest) command.RunResult {
+		ui.Progress("Thinking...")
+		ui.Command("Vibe want to run command", "ls -la")
+		if !ui.Confirm("Do you want to execute it?") {
+			ui.Message(ports.MessageError, "Execution cancelled.")
+			return command.RunResult{Status: command.StatusRefused, Request: req.Request, Command: "ls -la"}
+		}
+		ui.Result(ports.CommandOutcome{Command: "ls -la"})
+		return command.RunResult{Status: command.StatusSucceeded, Request: req.Request, Command: "ls -la"}
+	}
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(func() {
+		s.Shutdown()
+		ts.Close()
+	})
+	return s, ts
+}
+
+func call(t *testing.T, ts *httptest.Server, method, path, body string, out any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// waitForPrompt polls the run until it waits on a prompt.
+func waitForPrompt(t *testing.T, ts *httptest.Server, id string) *Prompt {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var info RunInfo
+		call(t, ts, "GET", "/v1/runs/"+id, "", &info)
+		if info.Prompt != nil {
+			return info.Prompt
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("run never asked for approval")
+	return nil
+}
+
+// waitForRun polls the run until it finishes.
+func waitForRun(t *testing.T, ts *httptest.Server, id string) RunInfo {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	var info RunInfo
+	for time.Now().Before(deadline) {
+		call(t, ts, "GET", "/v1/runs/"+id, "", &info)
+		if info.Status == RunFinished {
+			return info
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("run never finished")
+	return info
+}
+
+func TestAuth(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/v1/runs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(ts.URL + "/v1/runs?token=" + testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the query token to work, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(ts.URL + "/v1/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("health needs no token, got %d", resp.StatusCode)
+	}
+}
+
+func TestRun_ApproveAndStream(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	var started RunInfo
+	if code := call(t, ts, "POST", "/v1/runs", `{"request":"list files"}`, &started); code != http.StatusAccepted {
+		t.Fatalf("start: got %d", code)
+	}
+	if code := call(t, ts, "POST", "/v1/runs", `{"request":"again"}`, nil); code != http.StatusConflict {
+		t.Errorf("expected 409 while a run is active, got %d", code)
+	}
+
+	prompt := waitForPrompt(t, ts, started.ID)
+	if prompt.Kind != PromptConfirm {
+		t.Errorf("expected a confirm prompt, got %q", prompt.Kind)
+	}
+	if code := call(t, ts, "POST", "/v1/runs/"+started.ID+"/prompts/999", `{"approve":true}`, nil); code != http.StatusConflict {
+		t.Errorf("expected 409 for a stale prompt, got %d", code)
+	}
+	path := "/v1/runs/" + started.ID + "/prompts/" + strconv.Itoa(prompt.ID)
+	if code := call(t, ts, "POST", path, `{"approve":true}`, nil); code != http.StatusOK {
+		t.Fatalf("answer: got %d", code)
+	}
+
+	req, _ := http.NewRequest("GET", ts.URL+"/v1/runs/"+started.ID+"/events", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+
+	var types []string
+	var done Event
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, e.Type)
+		done = e
+	}
+	want := "progress command prompt answered result done"
+	if strings.Join(types, " ") != want {
+		t.Errorf("got events %q, want %q", strings.Join(types, " "), want)
+	}
+	if done.Result == nil || done.Result.Status != command.StatusSucceeded {
+		t.Errorf("unexpected final result %+v", done.Result)
+	}
+
+	var info RunInfo
+	call(t, ts, "GET", "/v1/runs/"+started.ID, "", &info)
+	if info.Status != RunFinished || info.FinishedAt == nil {
+		t.Errorf("expected a finished run, got %+v", info)
+	}
+}
+
+func TestRun_CancelDeniesPendingPrompt(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	var started RunInfo
+	call(t, ts, "POST", "/v1/runs", `{"request":"list files"}`, &started)
+	waitForPrompt(t, ts, started.ID)
+	if code := call(t, ts, "DELETE", "/v1/runs/"+started.ID, "", nil); code != http.StatusAccepted {
+		t.Fatalf("cancel: got %d", code)
+	}
+
+	info := waitForRun(t, ts, started.ID)
+	if info.Result == nil || info.Result.Status != command.StatusRefused {
+		t.Errorf("expected the cancelled prompt to refuse the command, got %+v", info.Result)
+	}
+}
+
+// meteredProvider answers every request at once and reports fixed usage.
+type meteredProvider struct {
+	calls int
+}
+
+func (p *meteredProvider) Name() string                       { return "metered" }
+func (p *meteredProvider) IsConfigured(context.Context) error { return nil }
+func (p *meteredProvider) Close() error                       { return nil }
+
+func (p *meteredProvider) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
+	p.calls++
+	return ports.GenerateResponse{
+		Text:  `{"type":"answer","explanation":"The disk is 91% full."}`,
+		Usage: ports.Usage{Provider: "openai", Model: "test-model", PromptTokens: 80, CompletionTokens: 20},
+	}, nil
+}
+
+func (p *meteredProvider) StreamGenerate(ctx context.Context, req ports.GenerateRequest) (<-chan ports.StreamChunk, error) {
+	return nil, errors.New("streaming not supported")
+}
+
+// usageRecords keeps appended usage records in memory.
+type usageRecords struct {
+	mu      sync.Mutex
+	records []ports.UsageRecord
+}
+
+func (u *usageRecords) Append(r ports.UsageRecord) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.records = append(u.records, r)
+	return nil
+}
+
+func (u *usageRecords) List(time.Time) ([]ports.UsageRecord, error) { return u.records, nil }
+
+func TestRun_BudgetPerRun(t *testing.T) {
+	// Runs checkpoint the git repository they start in; keep them out of this one.
+	t.Chdir(t.TempDir())
+	store := &usageRecords{}
+	logger := slog.New(slog.DiscardHandler)
+	tracker := usage.NewTracker(store, nil, "project", logger)
+	tracker.SetBudget(usage.Budget{MaxTokens: 100})
+	provider := &meteredProvider{}
+	app := &bootstrap.ApplicationContext{Config: &config.Config{}, Provider: usage.Wrap(provider, tracker), Usage: tracker, Logger: logger}
+	s := New(app, Config{
+		Token:   testToken,
+		Flags:   command.RunFlags{AgentMaxSteps: 3},
+		Session: bootstrap.SessionConfig{Name: "default", NoSession: true},
+	})
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(func() {
+		s.Shutdown()
+		ts.Close()
+	})
+
+	for _, session := range []string{"first", "second"} {
+		var started RunInfo
+		call(t, ts, "POST", "/v1/runs", `{"request":"how full is the disk?","session":"`+session+`"}`, &started)
+		if info := waitForRun(t, ts, started.ID); info.Result == nil || info.Result.Status != command.StatusAnswered {
+			t.Fatalf("run %s: expected an answer within its own budget, got %+v", session, info.Result)
+		}
+	}
+	if provider.calls != 2 {
+		t.Errorf("expected one model call per run, got %d", provider.calls)
+	}
+	if len(store.records) != 2 || store.records[0].Session != "first" || store.records[1].Session != "second" {
+		t.Errorf("expected the usage of each run under its session, got %+v", store.records)
+	}
+}
+
+func TestStartRun_Validation(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	if code := call(t, ts, "POST", "/v1/runs", `{"request":"  "}`, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an empty request, got %d", code)
+	}
+	if code := call(t, ts, "POST", "/v1/runs", `{"request":"x","policy":"yolo"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown policy, got %d", code)
+	}
+	if code := call(t, ts, "GET", "/v1/runs/42", "", nil); code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown run, got %d", code)
+	}
+}
+
+func TestStartRun_ParsesPolicy(t *testing.T) {
+	s, ts := newTestServer(t)
+	policies := make(chan approval.Policy, 1)
+	s.execute = func(ctx context.Context, ui ports.UI, req RunRequest) command.RunResult {
+		policies <- req.policy
+		return command.RunResult{Status: command.StatusAnswered, Request: req.Request}
+	}
+
+	var started RunInfo
+	if code := call(t, ts, "POST", "/v1/runs", `{"request":"list files","policy":" ReadOnly "}`, &started); code != http.StatusAccepted {
+		t.Fatalf("start: got %d", code)
+	}
+	if got := <-policies; got != approval.ReadOnly {
+		t.Errorf("expected the run to get the parsed policy, got %q", got)
+	}
+	if started.Request.Policy != " ReadOnly " {
+		t.Errorf("expected the request as sent, got %q", started.Request.Policy)
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	s, ts := newTestServer(t)
+	dir := t.TempDir()
+	store := jsonfile.New(dir)
+	if err := store.Save("deploy", &ports.SessionState{Summary: "Deployed the web app", Recent: []string{"a", "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	s.sessions = session.NewService(nil, store, nil, session.Budget{})
+
+	var listings []session.Listing
+	if code := call(t, ts, "GET", "/v1/sessions", "", &listings); code != http.StatusOK {
+		t.Fatalf("got %d", code)
+	}
+	if len(listings) != 1 || listings[0].Name != "deploy" || listings[0].Scope != session.ScopeProject || listings[0].RecentLines != 2 {
+		t.Errorf("unexpected listings %+v", listings)
+	}
+	if code := call(t, ts, "GET", "/v1/sessions?scope=everywhere", "", nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown scope, got %d", code)
+	}
+}
+
+func TestAnalyzeLogs(t *testing.T) {
+	s, ts := newTestServer(t)
+	s.analyze = func(_ context.Context, prompt string) (string, error) {
+		if !strings.Contains(prompt, "app.log") {
+			t.Errorf("prompt does not name the file:\n%s", prompt)
+		}
+		return "The database is down.", nil
+	}
+	s.cfg.LogDir = t.TempDir()
+	content := "INFO starting\nERROR connection refused to db:5432\nINFO retrying\n"
+	if err := os.WriteFile(filepath.Join(s.cfg.LogDir, "app.log"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var resp LogResponse
+	body, _ := json.Marshal(LogRequest{Path: "app.log", AnalysisRequest: AnalysisRequest{AI: true}})
+	if code := call(t, ts, "POST", "/v1/logs/analyze", string(body), &resp); code != http.StatusOK {
+		t.Fatalf("got %d", code)
+	}
+	if resp.Lines != 3 || resp.Format != "plain" || resp.Levels["error"] != 1 {
+		t.Errorf("unexpected summary %+v", resp)
+	}
+	if len(resp.Issues) == 0 || resp.Issues[0].Line != 2 {
+		t.Errorf("expected an issue on line 2, got %+v", resp.Issues)
+	}
+	if resp.Analysis != "The database is down." {
+		t.Errorf("unexpected analysis %q", resp.Analysis)
+	}
+
+	if resp.Path != filepath.Join(s.cfg.LogDir, "app.log") {
+		t.Errorf("expected the absolute path, got %q", resp.Path)
+	}
+
+	if code := call(t, ts, "POST", "/v1/logs/analyze", `{"path":"missing.log"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a missing file, got %d", code)
+	}
+}
+
+func TestAnalyzeLogs_StaysInLogDir(t *testing.T) {
+	s, ts := newTestServer(t)
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "secret.log")
+	if err := os.WriteFile(secret, []byte("ERROR token=abc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s.cfg.LogDir = t.TempDir()
+	if err := os.Symlink(secret, filepath.Join(s.cfg.LogDir, "link.log")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	for _, path := range []string{secret, "../" + filepath.Base(outside) + "/secret.log", "link.log", "/does/not/exist.log"} {
+		body, _ := json.Marshal(LogRequest{Path: path})
+		if code := call(t, ts, "POST", "/v1/logs/analyze", string(body), nil); code != http.StatusForbidden {
+			t.Errorf("expected 403 for %s, got %d", path, code)
+		}
+	}
+}
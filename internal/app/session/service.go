@@ -45,6 +45,37 @@ func NewService(provider ports.Provider, projectStore, globalStore ports.Session
 	}
 }
 
+// Listing is a saved session in one scope.
+type Listing struct {
+	Scope Scope `json:"scope"`
+	ports.SessionInfo
+}
+
+// List returns the saved sessions of scope; ScopeBoth lists project sessions first.
+func (s *Service) List(scope Scope) ([]Listing, error) {
+	var out []Listing
+	add := func(sc Scope, store ports.SessionStore) error {
+		if store == nil || (scope != sc && scope != ScopeBoth) {
+			return nil
+		}
+		infos, err := store.List()
+		if err != nil {
+			return fmt.Errorf("failed to list %s sessions: %w", sc, err)
+		}
+		for _, info := range infos {
+			out = append(out, Listing{Scope: sc, SessionInfo: info})
+		}
+		return nil
+	}
+	if err := add(ScopeProject, s.projectStore); err != nil {
+		return nil, err
+	}
+	if err := add(ScopeGlobal, s.globalStore); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 type CombinedContext struct {
 	GlobalSummary  string
 	ProjectSummary string
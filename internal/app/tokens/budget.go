@@ -0,0 +1,161 @@
+package tokens
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// minMessageTokens is the smallest size a message is truncated to.
+const minMessageTokens = 256
+
+// Budgeter fits a conversation into a model's context window.
+type Budgeter struct {
+	estimator Estimator
+	window    int
+	// reserve is kept free for the model's answer.
+	reserve int
+}
+
+// NewBudgeter creates a budgeter for a window of the given size, keeping reserve tokens for the answer.
+func NewBudgeter(estimator Estimator, window, reserve int) *Budgeter {
+	return &Budgeter{estimator: estimator, window: window, reserve: reserve}
+}
+
+// Report describes what Fit changed.
+type Report struct {
+	// Limit is the number of prompt tokens available (window minus reserve).
+	Limit  int
+	Before int
+	After  int
+	// Truncated counts messages that were shortened; Dropped counts messages removed.
+	Truncated int
+	Dropped   int
+}
+
+// Trimmed reports whether anything was truncated or dropped.
+func (r Report) Trimmed() bool { return r.Truncated > 0 || r.Dropped > 0 }
+
+func (r Report) String() string {
+	return fmt.Sprintf("prompt trimmed from ~%d to ~%d tokens (limit %d): %d messages truncated, %d dropped",
+		r.Before, r.After, r.Limit, r.Truncated, r.Dropped)
+}
+
+// Fit returns messages trimmed to the window, oldest material first:
+//
+//  1. long messages in the history (tool outputs, execution logs) are truncated;
+//  2. whole history turns are dropped, and a note says how many;
+//  3. the first user message (task plus @context items) is truncated;
+//  4. the most recent turn is truncated as a last resort.
+//
+// The system prompt is never changed. The input slice is not modified.
+func (b *Budgeter) Fit(messages []chat.Message, tools []ports.ToolSpec) ([]chat.Message, Report) {
+	limit := b.window - b.reserve - b.estimator.Tools(tools)
+	msgs := append([]chat.Message(nil), messages...)
+	report := Report{Limit: limit, Before: b.estimator.Messages(msgs)}
+	report.After = report.Before
+	if report.After <= limit {
+		return msgs, report
+	}
+
+	// head holds the system prompt and the task; the rest is history.
+	head := 0
+	for head < len(msgs) && head < 2 && msgs[head].Role != chat.RoleAssistant {
+		head++
+	}
+	units := splitUnits(msgs, head)
+	last := len(msgs)
+	if len(units) > 0 {
+		last = units[len(units)-1]
+	}
+
+	over := func() bool {
+		report.After = b.estimator.Messages(msgs)
+		return report.After > limit
+	}
+
+	// 1. Truncate long history messages, oldest first.
+	capTokens := max(limit/8, minMessageTokens)
+	for i := head; i < last && over(); i++ {
+		if b.truncate(&msgs[i], capTokens) {
+			report.Truncated++
+		}
+	}
+
+	// 2. Drop whole turns, oldest first, keeping the latest one.
+	dropped := 0
+	for len(units) > 1 && over() {
+		n := units[1] - units[0]
+		msgs = append(msgs[:units[0]], msgs[units[1]:]...)
+		dropped += n
+		for i := range units[1:] {
+			units[i+1] -= n
+		}
+		units = units[1:]
+	}
+	if dropped > 0 {
+		report.Dropped = dropped
+		note := fmt.Sprintf("[%d earlier messages were omitted to fit the context window.]", dropped)
+		if head > 0 && msgs[head-1].Role == chat.RoleUser {
+			msgs[head-1].Content += "\n\n" + note
+		}
+	}
+
+	// 3. Truncate the task message (it carries the @context items).
+	if head > 0 && msgs[head-1].Role == chat.RoleUser && over() {
+		excess := report.After - limit
+		if b.truncate(&msgs[head-1], max(b.estimator.Count(msgs[head-1].Content)-excess, minMessageTokens)) {
+			report.Truncated++
+		}
+	}
+
+	// 4. Truncate what is left of the history, newest last.
+	for i := head; i < len(msgs) && over(); i++ {
+		excess := report.After - limit
+		if b.truncate(&msgs[i], max(b.estimator.Count(msgs[i].Content)-excess, minMessageTokens)) {
+			report.Truncated++
+		}
+	}
+
+	over()
+	return msgs, report
+}
+
+// splitUnits returns the start index of each history turn after head. A turn starts
+// at an assistant message and runs until the next one, so tool results stay with
+// the call they answer.
+func splitUnits(msgs []chat.Message, head int) []int {
+	var units []int
+	for i := head; i < len(msgs); i++ {
+		if i == head || msgs[i].Role == chat.RoleAssistant {
+			units = append(units, i)
+		}
+	}
+	return units
+}
+
+// truncate shortens m.Content to at most maxTokens, keeping its beginning and end.
+// It reports whether the message changed.
+func (b *Budgeter) truncate(m *chat.Message, maxTokens int) bool {
+	tokens := b.estimator.Count(m.Content)
+	if tokens <= maxTokens {
+		return false
+	}
+	marker := fmt.Sprintf("\n…[truncated ~%d tokens]…\n", tokens-maxTokens)
+	keep := max(b.estimator.bytes(maxTokens)-len(marker), 0)
+	headLen := cutPoint(m.Content, keep*2/5)
+	tailStart := cutPoint(m.Content, len(m.Content)-(keep-headLen))
+	m.Content = m.Content[:headLen] + marker + m.Content[tailStart:]
+	return true
+}
+
+// cutPoint moves i back to the nearest UTF-8 rune boundary.
+func cutPoint(s string, i int) int {
+	i = max(0, min(i, len(s)))
+	for i > 0 && i < len(s) && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
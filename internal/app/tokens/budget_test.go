@@ -0,0 +1,108 @@
+package tokens
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
+)
+
+func conversation(steps int, outputSize int) []chat.Message {
+	msgs := []chat.Message{
+		{Role: chat.RoleSystem, Content: "You are Vibe."},
+		{Role: chat.RoleUser, Content: "Task:\nwhy is the api down?"},
+	}
+	for i := 0; i < steps; i++ {
+		msgs = append(msgs,
+			chat.Message{Role: chat.RoleAssistant, ToolCalls: []chat.ToolCall{{ID: "call", Name: "read_file", Arguments: json.RawMessage(`{"path":"api.log"}`)}}},
+			chat.Message{Role: chat.RoleTool, Name: "read_file", Content: strings.Repeat("x", outputSize)},
+		)
+	}
+	return msgs
+}
+
+func TestFit_UnderLimitUnchanged(t *testing.T) {
+	b := NewBudgeter(EstimatorFor("openai", "gpt-4o"), 100_000, 1_000)
+	msgs := conversation(3, 100)
+	out, report := b.Fit(msgs, nil)
+	if report.Trimmed() || len(out) != len(msgs) {
+		t.Errorf("expected no trimming, got %s", report)
+	}
+}
+
+func TestFit_TruncatesOldOutputsFirst(t *testing.T) {
+	est := EstimatorFor("openai", "gpt-4o")
+	b := NewBudgeter(est, 8_000, 1_000)
+	msgs := conversation(3, 12_000) // ~3k tokens per tool output
+
+	out, report := b.Fit(msgs, nil)
+	if report.After > report.Limit {
+		t.Fatalf("still over the limit: %s", report)
+	}
+	if report.Dropped != 0 || report.Truncated == 0 {
+		t.Errorf("expected truncation without dropping turns, got %s", report)
+	}
+	if !strings.Contains(out[3].Content, "[truncated") {
+		t.Errorf("expected the oldest tool output to be truncated")
+	}
+	if out[len(out)-1].Content != msgs[len(msgs)-1].Content {
+		t.Errorf("expected the latest tool output to be kept intact")
+	}
+	if msgs[3].Content != strings.Repeat("x", 12_000) {
+		t.Errorf("Fit must not modify its input")
+	}
+}
+
+func TestFit_DropsOldestTurns(t *testing.T) {
+	b := NewBudgeter(EstimatorFor("ollama", "llama3"), 4_096, 512)
+	msgs := conversation(20, 1_000)
+
+	out, report := b.Fit(msgs, nil)
+	if report.After > report.Limit || report.Dropped == 0 {
+		t.Fatalf("expected old turns to be dropped, got %s", report)
+	}
+	if report.Dropped%2 != 0 {
+		t.Errorf("tool calls and results must be dropped together, dropped %d", report.Dropped)
+	}
+	if out[0].Role != chat.RoleSystem || !strings.Contains(out[1].Content, "earlier messages were omitted") {
+		t.Errorf("expected the task to carry an omission note, got %q", out[1].Content)
+	}
+	if out[2].Role != chat.RoleAssistant {
+		t.Errorf("history must resume at a tool call, got %s", out[2].Role)
+	}
+}
+
+func TestFit_TruncatesHugeContext(t *testing.T) {
+	b := NewBudgeter(EstimatorFor("ollama", "llama3"), 4_096, 512)
+	msgs := []chat.Message{
+		{Role: chat.RoleSystem, Content: "You are Vibe."},
+		{Role: chat.RoleUser, Content: "--- @logs ---\n" + strings.Repeat("ERROR timeout\n", 5_000) + "Task:\nexplain"},
+	}
+	out, report := b.Fit(msgs, nil)
+	if report.After > report.Limit {
+		t.Fatalf("still over the limit: %s", report)
+	}
+	if !strings.HasSuffix(out[1].Content, "Task:\nexplain") {
+		t.Errorf("expected the end of the task message to survive truncation")
+	}
+}
+
+func TestContextWindow(t *testing.T) {
+	cases := []struct {
+		provider, model string
+		override, want  int
+	}{
+		{"openai", "gpt-4o-mini", 0, 128_000},
+		{"openai", "gpt-4", 0, 8_192},
+		{"gemini", "models/gemini-1.5-flash", 0, 1_000_000},
+		{"ollama", "llama3.1", 0, 4_096},
+		{"ollama", "llama3.1", 32_768, 32_768},
+		{"openai", "my-finetune", 0, 8_192},
+	}
+	for _, c := range cases {
+		if got := ContextWindow(c.provider, c.model, c.override); got != c.want {
+			t.Errorf("ContextWindow(%s, %s, %d) = %d, want %d", c.provider, c.model, c.override, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Package tokens estimates prompt sizes and fits agent conversations into a model's context window.
+package tokens
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+	"github.com/phamdaiminhquan/vibe-devops/pkg/config"
+)
+
+// messageOverhead approximates the per-message tokens added by chat templates.
+const messageOverhead = 4
+
+// Estimator approximates token counts from text length. It errs on the high
+// side: counting bytes overestimates non-ASCII text, which is the safe direction.
+type Estimator struct {
+	bytesPerToken float64
+}
+
+// EstimatorFor returns the estimator for a provider's model. OpenAI and Gemini
+// tokenizers average about 4 bytes per token on code and logs; the Llama-style
+// tokenizers of most local models are less efficient.
+func EstimatorFor(provider, model string) Estimator {
+	model = strings.ToLower(strings.TrimPrefix(model, "models/"))
+	for _, prefix := range []string{"gpt-", "o1", "o3", "o4", "gemini"} {
+		if strings.HasPrefix(model, prefix) {
+			return Estimator{bytesPerToken: 4}
+		}
+	}
+	if provider == config.ProviderGemini {
+		return Estimator{bytesPerToken: 4}
+	}
+	return Estimator{bytesPerToken: 3.3}
+}
+
+// Count estimates the tokens of text.
+func (e Estimator) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	return int(math.Ceil(float64(len(text)) / e.bytesPerToken))
+}
+
+// bytes converts a token count back into an approximate text length.
+func (e Estimator) bytes(tokens int) int {
+	return int(float64(tokens) * e.bytesPerToken)
+}
+
+// Message estimates one chat message, including tool calls.
+func (e Estimator) Message(m chat.Message) int {
+	n := messageOverhead + e.Count(m.Content)
+	for _, c := range m.ToolCalls {
+		n += e.Count(c.Name) + e.Count(string(c.Arguments))
+	}
+	return n
+}
+
+// Messages estimates a whole conversation.
+func (e Estimator) Messages(msgs []chat.Message) int {
+	n := 0
+	for _, m := range msgs {
+		n += e.Message(m)
+	}
+	return n
+}
+
+// Tools estimates native tool declarations sent alongside the messages.
+func (e Estimator) Tools(specs []ports.ToolSpec) int {
+	if len(specs) == 0 {
+		return 0
+	}
+	data, _ := json.Marshal(specs)
+	return e.Count(string(data))
+}
+
+// defaultWindows are context windows in tokens, keyed by model name prefix.
+var defaultWindows = map[string]int{
+	// OpenAI
+	"gpt-4o":        128_000,
+	"gpt-4.1":       1_000_000,
+	"gpt-4-turbo":   128_000,
+	"gpt-4":         8_192,
+	"gpt-3.5-turbo": 16_385,
+	"o1":            200_000,
+	"o3":            200_000,
+	"o4":            200_000,
+	// Gemini
+	"gemini-pro":     32_768,
+	"gemini-1.0-pro": 32_768,
+	"gemini-1.5":     1_000_000,
+	"gemini-2":       1_000_000,
+	// Common local models (when served through an OpenAI-compatible endpoint)
+	"llama3.1":  128_000,
+	"llama3.2":  128_000,
+	"llama3":    8_192,
+	"qwen2.5":   32_768,
+	"mistral":   32_768,
+	"phi3":      4_096,
+	"codellama": 16_384,
+}
+
+// defaultWindow is used for unknown models.
+const defaultWindow = 8_192
+
+// ollamaDefaultWindow is Ollama's default num_ctx: whatever the model supports,
+// the server truncates prompts to this unless told otherwise.
+const ollamaDefaultWindow = 4_096
+
+// ContextWindow returns the usable window of a provider's model. override, the
+// configured `contextWindow`, wins when set.
+func ContextWindow(provider, model string, override int) int {
+	if override > 0 {
+		return override
+	}
+	if provider == config.ProviderOllama {
+		return ollamaDefaultWindow
+	}
+	if window := KnownWindow(model); window > 0 {
+		return window
+	}
+	return defaultWindow
+}
+
+// KnownWindow returns the context window of a well-known model family, or 0.
+func KnownWindow(model string) int {
+	model = strings.ToLower(strings.TrimPrefix(model, "models/"))
+	prefixes := make([]string, 0, len(defaultWindows))
+	for k := range defaultWindows {
+		prefixes = append(prefixes, k)
+	}
+	// Longest first so "gpt-4o" wins over "gpt-4".
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(model, prefix) {
+			return defaultWindows[prefix]
+		}
+	}
+	return 0
+}
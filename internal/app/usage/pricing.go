@@ -0,0 +1,74 @@
+package usage
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+	"github.com/phamdaiminhquan/vibe-devops/pkg/config"
+)
+
+// defaultPrices are list prices in USD per million tokens, keyed by model name prefix.
+// They drift over time; override them with `usage.prices` in .vibe.yaml.
+var defaultPrices = map[string]config.ModelPrice{
+	// OpenAI
+	"gpt-4o":        {Input: 2.50, Output: 10.00},
+	"gpt-4o-mini":   {Input: 0.15, Output: 0.60},
+	"gpt-4.1":       {Input: 2.00, Output: 8.00},
+	"gpt-4.1-mini":  {Input: 0.40, Output: 1.60},
+	"gpt-4.1-nano":  {Input: 0.10, Output: 0.40},
+	"gpt-4-turbo":   {Input: 10.00, Output: 30.00},
+	"gpt-3.5-turbo": {Input: 0.50, Output: 1.50},
+	"o3-mini":       {Input: 1.10, Output: 4.40},
+	"o4-mini":       {Input: 1.10, Output: 4.40},
+	// Gemini
+	"gemini-pro":       {Input: 0.50, Output: 1.50},
+	"gemini-1.0-pro":   {Input: 0.50, Output: 1.50},
+	"gemini-1.5-pro":   {Input: 1.25, Output: 5.00},
+	"gemini-1.5-flash": {Input: 0.075, Output: 0.30},
+	"gemini-2.0-flash": {Input: 0.10, Output: 0.40},
+	"gemini-2.5-pro":   {Input: 1.25, Output: 10.00},
+	"gemini-2.5-flash": {Input: 0.30, Output: 2.50},
+}
+
+// Pricing resolves the price of a model by longest matching name prefix.
+type Pricing struct {
+	prices   map[string]config.ModelPrice
+	prefixes []string
+}
+
+// NewPricing merges overrides on top of the built-in table.
+func NewPricing(overrides map[string]config.ModelPrice) *Pricing {
+	prices := make(map[string]config.ModelPrice, len(defaultPrices)+len(overrides))
+	for k, v := range defaultPrices {
+		prices[k] = v
+	}
+	for k, v := range overrides {
+		prices[strings.ToLower(strings.TrimSpace(k))] = v
+	}
+
+	prefixes := make([]string, 0, len(prices))
+	for k := range prices {
+		prefixes = append(prefixes, k)
+	}
+	// Longest first so "gpt-4o-mini" wins over "gpt-4o".
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	return &Pricing{prices: prices, prefixes: prefixes}
+}
+
+// Cost returns the USD cost of u and whether the model's price is known.
+// Local Ollama models are free.
+func (p *Pricing) Cost(u ports.Usage) (float64, bool) {
+	if u.Provider == config.ProviderOllama {
+		return 0, true
+	}
+	model := strings.ToLower(strings.TrimPrefix(u.Model, "models/"))
+	for _, prefix := range p.prefixes {
+		if strings.HasPrefix(model, prefix) {
+			price := p.prices[prefix]
+			return (float64(u.PromptTokens)*price.Input + float64(u.CompletionTokens)*price.Output) / 1e6, true
+		}
+	}
+	return 0, false
+}
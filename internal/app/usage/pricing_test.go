@@ -0,0 +1,36 @@
+package usage
+
+import (
+	"math"
+	"testing"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+	"github.com/phamdaiminhquan/vibe-devops/pkg/config"
+)
+
+func TestPricing_Cost(t *testing.T) {
+	p := NewPricing(map[string]config.ModelPrice{
+		" My-Model ": {Input: 1, Output: 2},
+	})
+	cases := []struct {
+		name   string
+		usage  ports.Usage
+		cost   float64
+		priced bool
+	}{
+		{"longest prefix wins", ports.Usage{Provider: "openai", Model: "gpt-4o-mini-2024-07-18", PromptTokens: 1_000_000, CompletionTokens: 1_000_000}, 0.75, true},
+		{"shorter prefix", ports.Usage{Provider: "openai", Model: "gpt-4o", PromptTokens: 1000, CompletionTokens: 500}, 0.0075, true},
+		{"gemini models/ prefix", ports.Usage{Provider: "gemini", Model: "models/gemini-2.5-flash", PromptTokens: 2_000_000}, 0.60, true},
+		{"override", ports.Usage{Provider: "openai", Model: "my-model", PromptTokens: 500_000, CompletionTokens: 250_000}, 1.0, true},
+		{"ollama is free", ports.Usage{Provider: config.ProviderOllama, Model: "llama3", PromptTokens: 1_000_000}, 0, true},
+		{"unknown model", ports.Usage{Provider: "openai", Model: "mystery", PromptTokens: 1000}, 0, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cost, priced := p.Cost(tc.usage)
+			if priced != tc.priced || math.Abs(cost-tc.cost) > 1e-9 {
+				t.Errorf("got (%v, %v), want (%v, %v)", cost, priced, tc.cost, tc.priced)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package usage
+
+import (
+	"sort"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+	"github.com/phamdaiminhquan/vibe-devops/pkg/config"
+)
+
+// GroupBy selects the report dimension.
+type GroupBy string
+
+const (
+	ByDay     GroupBy = "day"
+	BySession GroupBy = "session"
+	ByModel   GroupBy = "model"
+)
+
+// Row is one line of a usage report.
+type Row struct {
+	Key string
+	Totals
+}
+
+// Filter narrows the records included in a report. Empty fields match everything.
+type Filter struct {
+	Project string
+	Session string
+}
+
+func (f Filter) match(r ports.UsageRecord) bool {
+	return (f.Project == "" || r.Project == f.Project) && (f.Session == "" || r.Session == f.Session)
+}
+
+// Summarize groups records by the given dimension. Days are listed oldest first,
+// sessions and models by descending cost.
+func Summarize(records []ports.UsageRecord, by GroupBy, filter Filter) ([]Row, Totals) {
+	var total Totals
+	index := make(map[string]int)
+	var rows []Row
+
+	for _, r := range records {
+		if !filter.match(r) {
+			continue
+		}
+		key := groupKey(r, by)
+		i, ok := index[key]
+		if !ok {
+			i = len(rows)
+			index[key] = i
+			rows = append(rows, Row{Key: key})
+		}
+		priced := r.CostUSD > 0 || r.Provider == config.ProviderOllama
+		rows[i].add(r, priced)
+		total.add(r, priced)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if by == ByDay {
+			return rows[i].Key < rows[j].Key
+		}
+		if rows[i].CostUSD != rows[j].CostUSD {
+			return rows[i].CostUSD > rows[j].CostUSD
+		}
+		return rows[i].TotalTokens() > rows[j].TotalTokens()
+	})
+	return rows, total
+}
+
+func groupKey(r ports.UsageRecord, by GroupBy) string {
+	switch by {
+	case BySession:
+		if r.Session == "" {
+			return "-"
+		}
+		return r.Session
+	case ByModel:
+		return r.Provider + "/" + r.Model
+	default:
+		return r.Time.Local().Format("2006-01-02")
+	}
+}
@@ -0,0 +1,41 @@
+package usage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+func TestSummarize(t *testing.T) {
+	day := time.Date(2026, 3, 2, 12, 0, 0, 0, time.Local)
+	records := []ports.UsageRecord{
+		{Time: day, Project: "a", Session: "s1", Provider: "openai", Model: "gpt-4o", PromptTokens: 100, CompletionTokens: 10, CostUSD: 0.5},
+		{Time: day.AddDate(0, 0, -1), Project: "a", Provider: "ollama", Model: "llama3", PromptTokens: 1000},
+		{Time: day, Project: "a", Session: "s1", Provider: "openai", Model: "gpt-4o", PromptTokens: 100, CostUSD: 0.5},
+		{Time: day, Project: "b", Session: "s2", Provider: "gemini", Model: "mystery", PromptTokens: 5},
+	}
+
+	rows, total := Summarize(records, ByModel, Filter{Project: "a"})
+	if total.Calls != 3 || total.CostUSD != 1 || total.UnpricedCalls != 0 {
+		t.Errorf("unexpected totals %+v", total)
+	}
+	if len(rows) != 2 || rows[0].Key != "openai/gpt-4o" || rows[0].Calls != 2 || rows[1].Key != "ollama/llama3" {
+		t.Errorf("expected models by descending cost, got %+v", rows)
+	}
+
+	rows, _ = Summarize(records, ByDay, Filter{})
+	if len(rows) != 2 || rows[0].Key != "2026-03-01" || rows[1].Key != "2026-03-02" {
+		t.Errorf("expected days oldest first, got %+v", rows)
+	}
+
+	rows, total = Summarize(records, BySession, Filter{})
+	if len(rows) != 3 || rows[0].Key != "s1" || total.UnpricedCalls != 1 {
+		t.Errorf("unexpected sessions %+v (totals %+v)", rows, total)
+	}
+	for _, r := range rows {
+		if r.Key == "" {
+			t.Error("records without a session should be grouped as '-'")
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Package usage accounts for tokens and cost of model calls and enforces per-run budgets.
+package usage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+)
+
+// Budget caps a single run. Zero fields are unlimited.
+type Budget struct {
+	MaxCostUSD float64
+	MaxTokens  int
+}
+
+// Totals aggregates usage.
+type Totals struct {
+	Calls            int
+	PromptTokens     int
+	CompletionTokens int
+	CostUSD          float64
+	// UnpricedCalls counts calls whose model is missing from the price table.
+	UnpricedCalls int
+}
+
+func (t Totals) TotalTokens() int { return t.PromptTokens + t.CompletionTokens }
+
+func (t *Totals) add(r ports.UsageRecord, priced bool) {
+	t.Calls++
+	t.PromptTokens += r.PromptTokens
+	t.CompletionTokens += r.CompletionTokens
+	t.CostUSD += r.CostUSD
+	if !priced {
+		t.UnpricedCalls++
+	}
+}
+
+// Tracker records the usage of one process run and persists it to a store.
+type Tracker struct {
+	store   ports.UsageStore
+	pricing *Pricing
+	logger  *slog.Logger
+	project string
+
+	mu      sync.Mutex
+	session string
+	budget  Budget
+	totals  Totals
+}
+
+// NewTracker creates a tracker; store may be nil to keep usage in memory only.
+func NewTracker(store ports.UsageStore, pricing *Pricing, project string, logger *slog.Logger) *Tracker {
+	if pricing == nil {
+		pricing = NewPricing(nil)
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Tracker{store: store, pricing: pricing, project: project, logger: logger}
+}
+
+// SetSession tags subsequent records with a session name.
+func (t *Tracker) SetSession(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.session = name
+}
+
+// StartRun begins a new run tagged with session: the totals the budget is
+// checked against start again from zero. A process serving several runs, such
+// as `vibe serve`, calls it before each one.
+func (t *Tracker) StartRun(session string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.session = session
+	t.totals = Totals{}
+}
+
+// SetBudget replaces the per-run budget.
+func (t *Tracker) SetBudget(b Budget) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.budget = b
+}
+
+// Totals returns usage so far in this run.
+func (t *Tracker) Totals() Totals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.totals
+}
+
+// Record accounts for one call. Persisting is best-effort: a failed write never fails the call.
+func (t *Tracker) Record(u ports.Usage) {
+	if u.PromptTokens == 0 && u.CompletionTokens == 0 {
+		return
+	}
+	cost, priced := t.pricing.Cost(u)
+
+	t.mu.Lock()
+	r := ports.UsageRecord{
+		Time:             time.Now(),
+		Project:          t.project,
+		Session:          t.session,
+		Provider:         u.Provider,
+		Model:            u.Model,
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		CostUSD:          cost,
+	}
+	t.totals.add(r, priced)
+	t.mu.Unlock()
+
+	if t.store != nil {
+		if err := t.store.Append(r); err != nil {
+			t.logger.Warn("failed to persist usage", "error", err)
+		}
+	}
+}
+
+// Check returns ports.ErrBudgetExceeded once the run has reached its budget.
+func (t *Tracker) Check() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.budget.MaxTokens > 0 && t.totals.TotalTokens() >= t.budget.MaxTokens {
+		return fmt.Errorf("%w: used %d of %d tokens", ports.ErrBudgetExceeded, t.totals.TotalTokens(), t.budget.MaxTokens)
+	}
+	if t.budget.MaxCostUSD > 0 && t.totals.CostUSD >= t.budget.MaxCostUSD {
+		return fmt.Errorf("%w: spent $%.4f of $%.4f", ports.ErrBudgetExceeded, t.totals.CostUSD, t.budget.MaxCostUSD)
+	}
+	return nil
+}
+
+// Provider decorates a ports.Provider so every call is metered and budget-checked.
+type Provider struct {
+	ports.Provider
+	tracker *Tracker
+}
+
+// Wrap meters provider through tracker.
+func Wrap(provider ports.Provider, tracker *Tracker) *Provider {
+	return &Provider{Provider: provider, tracker: tracker}
+}
+
+func (p *Provider) SupportsToolCalling() bool { return ports.SupportsToolCalling(p.Provider) }
+
+func (p *Provider) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
+	if err := p.tracker.Check(); err != nil {
+		return ports.GenerateResponse{}, err
+	}
+	resp, err := p.Provider.Generate(ctx, req)
+	if err == nil {
+		p.tracker.Record(resp.Usage)
+	}
+	return resp, err
+}
+
+func (p *Provider) StreamGenerate(ctx context.Context, req ports.GenerateRequest) (<-chan ports.StreamChunk, error) {
+	if err := p.tracker.Check(); err != nil {
+		return nil, err
+	}
+	in, err := p.Provider.StreamGenerate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan ports.StreamChunk)
+	go func() {
+		defer close(out)
+		for chunk := range in {
+			if chunk.Usage != nil {
+				p.tracker.Record(*chunk.Usage)
+			}
+			out <- chunk
+		}
+	}()
+	return out, nil
+}
+
+var _ ports.Provider = (*Provider)(nil)
@@ -0,0 +1,146 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/phamdaiminhquan/vibe-devops/internal/ports"
+	"github.com/phamdaiminhquan/vibe-devops/pkg/config"
+)
+
+type memoryStore struct {
+	records []ports.UsageRecord
+	err     error
+}
+
+func (m *memoryStore) Append(r ports.UsageRecord) error {
+	m.records = append(m.records, r)
+	return m.err
+}
+
+func (m *memoryStore) List(since time.Time) ([]ports.UsageRecord, error) { return m.records, nil }
+
+// stubProvider answers every call with fixed usage.
+type stubProvider struct {
+	usage ports.Usage
+	calls int
+}
+
+func (s *stubProvider) Name() string                       { return "stub" }
+func (s *stubProvider) IsConfigured(context.Context) error { return nil }
+func (s *stubProvider) Close() error                       { return nil }
+
+func (s *stubProvider) Generate(ctx context.Context, req ports.GenerateRequest) (ports.GenerateResponse, error) {
+	s.calls++
+	return ports.GenerateResponse{Text: "ok", Usage: s.usage}, nil
+}
+
+func (s *stubProvider) StreamGenerate(ctx context.Context, req ports.GenerateRequest) (<-chan ports.StreamChunk, error) {
+	s.calls++
+	ch := make(chan ports.StreamChunk, 2)
+	usage := s.usage
+	ch <- ports.StreamChunk{Content: "ok"}
+	ch <- ports.StreamChunk{Usage: &usage}
+	close(ch)
+	return ch, nil
+}
+
+func newTestTracker(store ports.UsageStore) *Tracker {
+	pricing := NewPricing(map[string]config.ModelPrice{"test-model": {Input: 1, Output: 2}})
+	return NewTracker(store, pricing, "/work/app", slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestTracker_RecordsTotals(t *testing.T) {
+	store := &memoryStore{}
+	tracker := newTestTracker(store)
+	tracker.SetSession("deploy")
+
+	tracker.Record(ports.Usage{Provider: "openai", Model: "test-model", PromptTokens: 1_000_000, CompletionTokens: 500_000})
+	tracker.Record(ports.Usage{Provider: "openai", Model: "unknown", PromptTokens: 10, CompletionTokens: 5})
+	tracker.Record(ports.Usage{Provider: "openai", Model: "test-model"}) // no tokens: skipped
+
+	totals := tracker.Totals()
+	if totals.Calls != 2 || totals.PromptTokens != 1_000_010 || totals.CompletionTokens != 500_005 {
+		t.Errorf("unexpected totals %+v", totals)
+	}
+	if totals.CostUSD != 2 || totals.UnpricedCalls != 1 {
+		t.Errorf("expected $2 and one unpriced call, got %+v", totals)
+	}
+	if len(store.records) != 2 {
+		t.Fatalf("expected 2 persisted records, got %d", len(store.records))
+	}
+	if r := store.records[0]; r.Session != "deploy" || r.Project != "/work/app" || r.CostUSD != 2 {
+		t.Errorf("unexpected record %+v", r)
+	}
+}
+
+func TestTracker_StoreFailureDoesNotFailRecord(t *testing.T) {
+	tracker := newTestTracker(&memoryStore{err: errors.New("disk full")})
+	tracker.Record(ports.Usage{Model: "test-model", PromptTokens: 1})
+	if tracker.Totals().Calls != 1 {
+		t.Error("expected the call to be counted even though persisting failed")
+	}
+}
+
+func TestTracker_Check(t *testing.T) {
+	tracker := newTestTracker(nil)
+	if err := tracker.Check(); err != nil {
+		t.Fatalf("an empty budget is unlimited, got %v", err)
+	}
+
+	tracker.SetBudget(Budget{MaxTokens: 100})
+	tracker.Record(ports.Usage{Model: "test-model", PromptTokens: 60, CompletionTokens: 39})
+	if err := tracker.Check(); err != nil {
+		t.Errorf("99 of 100 tokens is within budget, got %v", err)
+	}
+	tracker.Record(ports.Usage{Model: "test-model", PromptTokens: 1})
+	if err := tracker.Check(); !errors.Is(err, ports.ErrBudgetExceeded) {
+		t.Errorf("expected ErrBudgetExceeded at the token limit, got %v", err)
+	}
+	tracker.StartRun("next")
+	if err := tracker.Check(); err != nil {
+		t.Errorf("a new run starts a new budget window, got %v", err)
+	}
+
+	tracker = newTestTracker(nil)
+	tracker.SetBudget(Budget{MaxCostUSD: 1})
+	tracker.Record(ports.Usage{Model: "test-model", PromptTokens: 1_000_000})
+	if err := tracker.Check(); !errors.Is(err, ports.ErrBudgetExceeded) {
+		t.Errorf("expected ErrBudgetExceeded at the cost limit, got %v", err)
+	}
+}
+
+func TestProvider_StopsCallsOverBudget(t *testing.T) {
+	tracker := newTestTracker(nil)
+	tracker.SetBudget(Budget{MaxTokens: 150})
+	stub := &stubProvider{usage: ports.Usage{Provider: "openai", Model: "test-model", PromptTokens: 80, CompletionTokens: 20}}
+	p := Wrap(stub, tracker)
+	ctx := context.Background()
+
+	if _, err := p.Generate(ctx, ports.GenerateRequest{Prompt: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	ch, err := p.StreamGenerate(ctx, ports.GenerateRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range ch {
+	}
+	if got := tracker.Totals().TotalTokens(); got != 200 {
+		t.Errorf("expected streamed usage to be recorded, got %d tokens", got)
+	}
+
+	if _, err := p.Generate(ctx, ports.GenerateRequest{Prompt: "hi"}); !errors.Is(err, ports.ErrBudgetExceeded) {
+		t.Errorf("expected ErrBudgetExceeded, got %v", err)
+	}
+	if _, err := p.StreamGenerate(ctx, ports.GenerateRequest{Prompt: "hi"}); !errors.Is(err, ports.ErrBudgetExceeded) {
+		t.Errorf("expected ErrBudgetExceeded for streams, got %v", err)
+	}
+	if stub.calls != 2 {
+		t.Errorf("calls over budget must not reach the provider, got %d calls", stub.calls)
+	}
+}
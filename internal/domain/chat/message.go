@@ -1,5 +1,7 @@
 package chat
 
+import "encoding/json"
+
 // Role represents the author of a message in a conversation.
 // This is kept small and stable to support future chat-mode.
 type Role string
@@ -13,8 +15,22 @@ const (
 
 // Message is a single conversational turn.
 type Message struct {
-	Role    Role
-	Content string
+	Role    Role   `json:"role"`
+	Content string `json:"content"`
+	// ToolCalls are the calls an assistant turn requested.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID and Name identify the call a RoleTool message answers.
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	Name       string `json:"name,omitempty"`
+}
+
+// ToolCall is a structured function call requested by the model.
+type ToolCall struct {
+	// ID correlates the call with its result (synthesised when the provider has none).
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Arguments is the JSON object the model passed to the tool.
+	Arguments json.RawMessage `json:"arguments"`
 }
 
 // History is an ordered list of messages (oldest -> newest).
@@ -0,0 +1,35 @@
+package ports
+
+import "context"
+
+// ModelInfo describes a model offered by a provider. Capability flags are
+// best-effort hints: some APIs report them, others are inferred from the name.
+type ModelInfo struct {
+	Name string
+	// ContextWindow is the input token limit; 0 when the provider does not report it.
+	ContextWindow int
+	ToolCalling   bool
+	JSONMode      bool
+	Vision        bool
+	// Size is the on-disk size in bytes, for local models.
+	Size int64
+	// Details is a short free-form description (family, parameter count, ...).
+	Details string
+}
+
+// ModelLister is implemented by providers that can list the models available to them.
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
+
+// PullProgress reports the progress of a model download.
+type PullProgress struct {
+	Status    string
+	Total     int64
+	Completed int64
+}
+
+// ModelPuller is implemented by providers that can download models locally.
+type ModelPuller interface {
+	PullModel(ctx context.Context, name string, progress func(PullProgress)) error
+}
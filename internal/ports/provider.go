@@ -2,17 +2,92 @@ package ports
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/phamdaiminhquan/vibe-devops/internal/domain/chat"
 )
 
+// ErrToolsUnsupported is returned by providers when the selected model rejects
+// native tool definitions. Callers can fall back to the JSON text protocol.
+var ErrToolsUnsupported = errors.New("model does not support native tool calling")
+
+// ErrBudgetExceeded is returned once a run has spent its token or cost budget.
+var ErrBudgetExceeded = errors.New("usage budget exceeded")
+
+// ProviderError carries the transport details callers need to decide whether a
+// failure is worth retrying or failing over. Adapters wrap upstream errors in it.
+type ProviderError struct {
+	Provider string
+	// StatusCode is the HTTP status of the failed call; 0 for network errors.
+	StatusCode int
+	// RetryAfter is the server-requested delay (Retry-After header or equivalent), if any.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ProviderError) Error() string { return e.Err.Error() }
+
+func (e *ProviderError) Unwrap() error { return e.Err }
+
+// Retryable reports whether the same call may succeed if repeated later:
+// rate limits, server errors and network failures.
+func (e *ProviderError) Retryable() bool {
+	switch {
+	case e.StatusCode == 0:
+		return !errors.Is(e.Err, context.Canceled) && !errors.Is(e.Err, context.DeadlineExceeded)
+	case e.StatusCode == 408, e.StatusCode == 429:
+		return true
+	default:
+		return e.StatusCode >= 500
+	}
+}
+
+// ParseRetryAfter parses a Retry-After header value (delay-seconds or HTTP-date).
+// It returns 0 when the value is empty, invalid or in the past.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
 // StreamChunk represents a piece of streamed content from the AI provider
 type StreamChunk struct {
 	Content string
 	IsLast  bool
 	Error   error
+	// ToolCalls is set once, on the final chunk, when the model requested tools.
+	ToolCalls []chat.ToolCall
+	// Usage is set on the final chunk when the provider reports token counts.
+	Usage *Usage
+}
+
+// Usage reports the tokens consumed by one model call.
+type Usage struct {
+	Provider         string `json:"provider,omitempty"`
+	Model            string `json:"model,omitempty"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
 }
 
+// TotalTokens returns prompt plus completion tokens.
+func (u Usage) TotalTokens() int { return u.PromptTokens + u.CompletionTokens }
+
 // Provider is the outbound port for an AI model provider.
 // It is chat-first, but still supports the current single-prompt workflow.
 type Provider interface {
@@ -23,18 +98,94 @@ type Provider interface {
 	Close() error
 }
 
+// ToolCallingProvider is implemented by providers that can pass GenerateRequest.Tools
+// to the model natively and return structured tool calls.
+type ToolCallingProvider interface {
+	SupportsToolCalling() bool
+}
+
+// SupportsToolCalling reports whether p advertises native tool calling.
+func SupportsToolCalling(p Provider) bool {
+	tc, ok := p.(ToolCallingProvider)
+	return ok && tc.SupportsToolCalling()
+}
+
+// ToolSpec describes a tool the model may call through native function calling.
+type ToolSpec struct {
+	Name        string
+	Description string
+	// Parameters is the JSON Schema of the tool input (ToolDefinition.InputSchema).
+	Parameters json.RawMessage
+}
+
 type GenerateRequest struct {
 	// Prompt is the raw text prompt for providers that are prompt-based.
 	// For chat-based usage, Messages can be used instead.
 	Prompt   string
 	Messages []chat.Message
-	
+
 	// Model allows overriding the default model
 	Model string
-	// Temperature allows overriding the default temperature
-	Temperature *float32
+	// Sampling overrides the provider's sampling defaults; zero fields are left to the provider.
+	Sampling
+
+	// Tools are offered to the model for native function calling.
+	// Providers that don't implement ToolCallingProvider ignore them.
+	Tools []ToolSpec
+
+	// Output, when set, switches the provider to its JSON / structured-output mode.
+	Output *OutputFormat
+}
+
+// Sampling holds the generation parameters shared by all providers.
+// Nil pointers and zero values mean "use the default".
+type Sampling struct {
+	Temperature *float32 `json:"temperature,omitempty"`
+	TopP        *float32 `json:"top_p,omitempty"`
+	// MaxTokens caps the number of generated tokens.
+	MaxTokens int      `json:"max_tokens,omitempty"`
+	Stop      []string `json:"stop,omitempty"`
+	// Seed makes sampling reproducible where the provider supports it.
+	Seed *int `json:"seed,omitempty"`
+}
+
+// WithDefaults returns s with every unset field taken from d.
+func (s Sampling) WithDefaults(d Sampling) Sampling {
+	if s.Temperature == nil {
+		s.Temperature = d.Temperature
+	}
+	if s.TopP == nil {
+		s.TopP = d.TopP
+	}
+	if s.MaxTokens == 0 {
+		s.MaxTokens = d.MaxTokens
+	}
+	if len(s.Stop) == 0 {
+		s.Stop = d.Stop
+	}
+	if s.Seed == nil {
+		s.Seed = d.Seed
+	}
+	return s
+}
+
+// IsZero reports whether no parameter is set.
+func (s Sampling) IsZero() bool {
+	return s.Temperature == nil && s.TopP == nil && s.MaxTokens == 0 && len(s.Stop) == 0 && s.Seed == nil
+}
+
+// OutputFormat constrains the model's answer to a JSON value.
+type OutputFormat struct {
+	// Name identifies the schema; some APIs require one.
+	Name string
+	// Schema is the JSON Schema of the answer. Empty asks for any JSON object.
+	Schema json.RawMessage
 }
 
 type GenerateResponse struct {
 	Text string
+	// ToolCalls holds the functions the model asked to call, in order.
+	ToolCalls []chat.ToolCall
+	// Usage is zero when the provider did not report token counts.
+	Usage Usage
 }
@@ -7,6 +7,8 @@ import "time"
 type SessionStore interface {
 	Load(sessionName string) (*SessionState, error)
 	Save(sessionName string, state *SessionState) error
+	// List describes the saved sessions, most recently updated first.
+	List() ([]SessionInfo, error)
 }
 
 // SessionState is intentionally compact: a rolling summary plus a small tail of recent lines.
@@ -20,3 +22,11 @@ type SessionState struct {
 	CreatedAt time.Time         `json:"created_at"`
 	UpdatedAt time.Time         `json:"updated_at"`
 }
+
+// SessionInfo describes a saved session without its transcript.
+type SessionInfo struct {
+	Name        string    `json:"name"`
+	Summary     string    `json:"summary,omitempty"`
+	RecentLines int       `json:"recent_lines"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -3,6 +3,8 @@ package ports
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
 )
 
 // ToolPolicy defines the permission level for tool execution
@@ -17,6 +19,16 @@ const (
 	PolicyDenied ToolPolicy = "denied"
 )
 
+// ParseToolPolicy parses a policy name as written in configuration.
+func ParseToolPolicy(s string) (ToolPolicy, error) {
+	switch p := ToolPolicy(strings.TrimSpace(s)); p {
+	case PolicyAllowed, PolicyWithPermission, PolicyDenied:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid tool policy '%s' (use %s, %s or %s)", s, PolicyAllowed, PolicyWithPermission, PolicyDenied)
+	}
+}
+
 // ToolDefinition contains metadata about a tool
 type ToolDefinition struct {
 	// Name is the unique identifier for the tool (used in AI function calls)
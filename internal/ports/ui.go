@@ -0,0 +1,61 @@
+package ports
+
+import "io"
+
+// UI is the port through which the application prompts the user and shows
+// progress and results. The terminal implementation reads stdin and writes to
+// the console; the scripted one answers from a list, for tests.
+type UI interface {
+	// Confirm asks a yes/no question. Only an explicit yes counts.
+	Confirm(question string) bool
+	// Choose asks the user to pick one of choices and returns its key, or ""
+	// when nothing valid was picked.
+	Choose(question string, choices []Choice) string
+	// Input asks for a line of free text and returns it trimmed.
+	Input(question string) (string, error)
+
+	// Progress shows a transient status line that the next output replaces.
+	Progress(message string)
+	// Token streams a piece of the model's explanation.
+	Token(text string)
+	// Explain shows the model's explanation, or just ends the line if it was
+	// already streamed with Token.
+	Explain(text string)
+	// Message shows a line of information.
+	Message(level MessageLevel, text string)
+
+	// Command shows a command under a label, e.g. "Vibe wants to run".
+	Command(label, cmd string)
+	// Result shows how a command ended.
+	Result(outcome CommandOutcome)
+	// Output receives the live output of running commands.
+	Output() io.Writer
+}
+
+// MessageLevel is the kind of a UI message.
+type MessageLevel int
+
+const (
+	MessageInfo MessageLevel = iota
+	MessageSuccess
+	MessageWarning
+	MessageError
+)
+
+// Choice is one option of UI.Choose.
+type Choice struct {
+	// Key is what the user types to pick the option, e.g. "a".
+	Key   string `json:"key"`
+	Label string `json:"label"`
+}
+
+// CommandOutcome is how a command ended, for UI.Result.
+type CommandOutcome struct {
+	Command  string
+	ExitCode int
+	// Stderr is the captured error output, shown when the command failed.
+	Stderr string
+}
+
+// Succeeded reports whether the command exited with code 0.
+func (o CommandOutcome) Succeeded() bool { return o.ExitCode == 0 }
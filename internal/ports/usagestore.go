@@ -0,0 +1,23 @@
+package ports
+
+import "time"
+
+// UsageStore persists token usage so costs can be reported across runs.
+type UsageStore interface {
+	Append(record UsageRecord) error
+	// List returns records at or after since, oldest first.
+	List(since time.Time) ([]UsageRecord, error)
+}
+
+// UsageRecord is one model call as persisted by a UsageStore.
+type UsageRecord struct {
+	Time             time.Time `json:"time"`
+	Project          string    `json:"project,omitempty"`
+	Session          string    `json:"session,omitempty"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	// CostUSD is computed at record time from the price table; 0 when unknown.
+	CostUSD float64 `json:"cost_usd"`
+}
@@ -6,7 +6,6 @@ import (
 
 	"github.com/google/generative-ai-go/genai"
 	"github.com/phamdaiminhquan/vibe-devops/pkg/config"
-	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
@@ -66,36 +65,3 @@ func (p *GeminiProvider) GetCompletion(prompt string) (string, error) {
 func (p *GeminiProvider) IsConfigured() bool {
 	return p.cfg.APIKey != "" && p.cfg.APIKey != "YOUR_GEMINI_API_KEY_HERE"
 }
-
-// GetGeminiModels returns a list of available Gemini models for the given API key.
-func GetGeminiModels(apiKey string) ([]string, error) {
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create genai client: %w", err)
-	}
-	defer client.Close()
-
-	iter := client.ListModels(ctx)
-	var models []string
-	for {
-		m, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return nil, fmt.Errorf("failed to list models: %w", err)
-		}
-
-		// Check if the model supports generateContent
-		if m.SupportedGenerationMethods != nil {
-			for _, method := range m.SupportedGenerationMethods {
-				if method == "generateContent" {
-					models = append(models, m.Name)
-					break
-				}
-			}
-		}
-	}
-	return models, nil
-}
@@ -3,28 +3,240 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 const (
-	ConfigFileName           = ".vibe.yaml"
-	DefaultAPIKeyPlaceholder = "YOUR_GEMINI_API_KEY_HERE"
+	ConfigFileName                 = ".vibe.yaml"
+	DefaultAPIKeyPlaceholder       = "YOUR_GEMINI_API_KEY_HERE"
+	DefaultOpenAIAPIKeyPlaceholder = "YOUR_OPENAI_API_KEY_HERE"
+	DefaultOllamaHost              = "http://localhost:11434"
 )
 
+// Supported provider names for `ai.provider`.
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+	ProviderOllama = "ollama"
+)
+
+// SupportedProviders lists every provider name accepted in `ai.provider`.
+var SupportedProviders = []string{ProviderGemini, ProviderOpenAI, ProviderOllama}
+
+// SamplingConfig sets generation parameters. Unset fields keep the provider's defaults.
+type SamplingConfig struct {
+	Temperature *float32 `yaml:"temperature,omitempty"`
+	TopP        *float32 `yaml:"topP,omitempty"`
+	MaxTokens   int      `yaml:"maxTokens,omitempty"`
+	Stop        []string `yaml:"stop,omitempty"`
+	// Seed is ignored by Gemini.
+	Seed *int `yaml:"seed,omitempty"`
+}
+
 type GeminiConfig struct {
+	APIKey   string         `yaml:"apiKey"`
+	Model    string         `yaml:"model"`
+	Sampling SamplingConfig `yaml:"sampling,omitempty"`
+	// ContextWindow overrides the built-in window size (in tokens) of the model.
+	ContextWindow int `yaml:"contextWindow,omitempty"`
+}
+
+type OpenAIConfig struct {
 	APIKey string `yaml:"apiKey"`
 	Model  string `yaml:"model"`
+	// BaseURL points the client at any OpenAI-compatible server
+	// (vLLM, LM Studio, LiteLLM, Azure-style gateways). Empty means api.openai.com.
+	BaseURL      string `yaml:"baseURL,omitempty"`
+	Organization string `yaml:"organization,omitempty"`
+	// APIVersion switches to Azure-style routing (deployments + api-version query).
+	APIVersion string            `yaml:"apiVersion,omitempty"`
+	Headers    map[string]string `yaml:"headers,omitempty"`
+	Sampling   SamplingConfig    `yaml:"sampling,omitempty"`
+	// ContextWindow overrides the built-in window size (in tokens) of the model.
+	ContextWindow int `yaml:"contextWindow,omitempty"`
+}
+
+type OllamaConfig struct {
+	Host     string         `yaml:"host"`
+	Model    string         `yaml:"model"`
+	Sampling SamplingConfig `yaml:"sampling,omitempty"`
+	// ContextWindow is sent to Ollama as num_ctx; without it the server's default (4096) applies.
+	ContextWindow int `yaml:"contextWindow,omitempty"`
+}
+
+// RetryConfig tunes retries of a provider before failing over to the next one.
+// Zero values use the built-in defaults (3 attempts, 1s initial, 30s max backoff).
+type RetryConfig struct {
+	MaxAttempts    int           `yaml:"maxAttempts,omitempty"`
+	InitialBackoff time.Duration `yaml:"initialBackoff,omitempty"`
+	MaxBackoff     time.Duration `yaml:"maxBackoff,omitempty"`
+}
+
+// CassetteConfig records provider traffic to a cassette file, or replays it
+// from one instead of calling a provider. VIBE_CASSETTE and VIBE_CASSETTE_MODE
+// override it.
+type CassetteConfig struct {
+	Path string `yaml:"path,omitempty"`
+	// Mode is "record" or "replay" (default).
+	Mode string `yaml:"mode,omitempty"`
+	// Strict only replays interactions whose request matches exactly.
+	Strict bool `yaml:"strict,omitempty"`
+}
+
+// HTTPConfig tunes the HTTP client used by every provider adapter. Zero values
+// keep Go's defaults, including HTTPS_PROXY / NO_PROXY from the environment.
+type HTTPConfig struct {
+	Proxy string `yaml:"proxy,omitempty"`
+	// NoProxy lists hosts, domains (".corp.example") and CIDRs that bypass Proxy.
+	NoProxy []string `yaml:"noProxy,omitempty"`
+	// CABundle is a PEM file of private CAs, trusted in addition to the system pool.
+	CABundle string `yaml:"caBundle,omitempty"`
+	// ClientCert and ClientKey are PEM files for mutual TLS.
+	ClientCert     string        `yaml:"clientCert,omitempty"`
+	ClientKey      string        `yaml:"clientKey,omitempty"`
+	ConnectTimeout time.Duration `yaml:"connectTimeout,omitempty"`
+	// ReadTimeout fails a call when the server sends nothing for this long.
+	ReadTimeout time.Duration `yaml:"readTimeout,omitempty"`
+}
+
+// RoleConfig routes a model role to a provider and model. Empty fields fall back
+// to the active provider and the model configured for that provider.
+type RoleConfig struct {
+	Provider string `yaml:"provider,omitempty"`
+	Model    string `yaml:"model,omitempty"`
 }
 
 type AIConfig struct {
-	Provider string       `yaml:"provider"`
-	Gemini   GeminiConfig `yaml:"gemini"`
+	Provider string `yaml:"provider"`
+	// Fallbacks are tried in order when the primary provider keeps failing.
+	Fallbacks []string       `yaml:"fallbacks,omitempty"`
+	Retry     RetryConfig    `yaml:"retry,omitempty"`
+	Cassette  CassetteConfig `yaml:"cassette,omitempty"`
+	HTTP      HTTPConfig     `yaml:"http,omitempty"`
+	// UseCases override sampling per model role (agent, summarizer, analyzer),
+	// on top of the provider's own sampling defaults.
+	UseCases map[string]SamplingConfig `yaml:"useCases,omitempty"`
+	// Roles route model roles (agent, summarizer, analyzer) to their own
+	// provider and model, e.g. a cheap local model for summaries.
+	Roles  map[string]RoleConfig `yaml:"roles,omitempty"`
+	Gemini GeminiConfig          `yaml:"gemini"`
+	OpenAI OpenAIConfig          `yaml:"openai"`
+	Ollama OllamaConfig          `yaml:"ollama"`
+}
+
+// ModelPrice is a price in USD per million tokens.
+type ModelPrice struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+}
+
+// BudgetConfig caps what a single run may spend. Zero means unlimited.
+type BudgetConfig struct {
+	MaxCostUSD float64 `yaml:"maxCostUSD,omitempty"`
+	MaxTokens  int     `yaml:"maxTokens,omitempty"`
+}
+
+type UsageConfig struct {
+	// Prices override or extend the built-in price table, keyed by model name prefix.
+	Prices map[string]ModelPrice `yaml:"prices,omitempty"`
+	Budget BudgetConfig          `yaml:"budget,omitempty"`
+}
+
+// CacheConfig controls the on-disk response cache used by analysis commands.
+// Zero values use the defaults (enabled, 24h TTL, 100 MB).
+type CacheConfig struct {
+	Disabled  bool          `yaml:"disabled,omitempty"`
+	TTL       time.Duration `yaml:"ttl,omitempty"`
+	MaxSizeMB int           `yaml:"maxSizeMB,omitempty"`
+}
+
+// ToolsConfig controls the tools the agent may run.
+type ToolsConfig struct {
+	// Policies override a tool's policy by name: allowed, allowedWithPermission or denied.
+	Policies map[string]string `yaml:"policies,omitempty"`
+	// MCPServers are Model Context Protocol servers whose tools are offered to
+	// the agent, keyed by a short server name.
+	MCPServers map[string]MCPServerConfig `yaml:"mcpServers,omitempty"`
+}
+
+// MCPServerConfig declares one MCP server: set Command to start a stdio server,
+// or URL to connect to a Streamable HTTP server (which may answer over SSE).
+// Values in Env and Headers may reference environment variables as ${NAME}.
+type MCPServerConfig struct {
+	Command string            `yaml:"command,omitempty"`
+	Args    []string          `yaml:"args,omitempty"`
+	Env     map[string]string `yaml:"env,omitempty"`
+	URL     string            `yaml:"url,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Timeout bounds connecting to the server and listing its tools (default 30s).
+	Timeout  time.Duration `yaml:"timeout,omitempty"`
+	Disabled bool          `yaml:"disabled,omitempty"`
 }
 
 // Config holds the application's configuration.
 type Config struct {
-	AI AIConfig `yaml:"ai"`
+	AI    AIConfig    `yaml:"ai"`
+	Usage UsageConfig `yaml:"usage,omitempty"`
+	Cache CacheConfig `yaml:"cache,omitempty"`
+	Tools ToolsConfig `yaml:"tools,omitempty"`
+}
+
+// IsSupportedProvider reports whether name is a known provider.
+func IsSupportedProvider(name string) bool {
+	for _, p := range SupportedProviders {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ContextWindow returns the configured `contextWindow` of the named provider, or 0.
+func (c *Config) ContextWindow(provider string) int {
+	switch provider {
+	case ProviderOpenAI:
+		return c.AI.OpenAI.ContextWindow
+	case ProviderOllama:
+		return c.AI.Ollama.ContextWindow
+	case ProviderGemini:
+		return c.AI.Gemini.ContextWindow
+	default:
+		return 0
+	}
+}
+
+// ActiveModel returns the model configured for the active provider.
+func (c *Config) ActiveModel() string {
+	return c.ModelFor(c.AI.Provider)
+}
+
+// ModelFor returns the model configured for the named provider.
+func (c *Config) ModelFor(provider string) string {
+	switch provider {
+	case ProviderOpenAI:
+		return c.AI.OpenAI.Model
+	case ProviderOllama:
+		return c.AI.Ollama.Model
+	default:
+		return c.AI.Gemini.Model
+	}
+}
+
+// RoleTarget returns the provider and model serving the named role.
+func (c *Config) RoleTarget(role string) (provider, model string) {
+	r := c.AI.Roles[role]
+	provider = strings.ToLower(strings.TrimSpace(r.Provider))
+	if provider == "" {
+		provider = strings.ToLower(strings.TrimSpace(c.AI.Provider))
+	}
+	model = strings.TrimSpace(r.Model)
+	if model == "" {
+		model = c.ModelFor(provider)
+	}
+	return provider, model
 }
 
 // Load loads the configuration from the .vibe.yaml file in the specified directory.
@@ -59,8 +271,12 @@ func Write(dir string, cfg *Config) error {
 // GetDefaultConfig returns a default configuration object.
 func GetDefaultConfig() *Config {
 	var cfg Config
-	cfg.AI.Provider = "gemini"
+	cfg.AI.Provider = ProviderGemini
 	cfg.AI.Gemini.APIKey = DefaultAPIKeyPlaceholder
 	cfg.AI.Gemini.Model = "gemini-pro"
+	cfg.AI.OpenAI.APIKey = DefaultOpenAIAPIKeyPlaceholder
+	cfg.AI.OpenAI.Model = "gpt-4o"
+	cfg.AI.Ollama.Host = DefaultOllamaHost
+	cfg.AI.Ollama.Model = "llama3"
 	return &cfg
 }